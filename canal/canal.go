@@ -61,6 +61,10 @@ type Canal struct {
 var UnknownTableRetryPeriod = time.Second * time.Duration(10)
 var ErrExcludedTable = errors.New("excluded table meta")
 
+// ErrSchemaMismatch is returned when a rows event does not match the cached table schema,
+// see Config.CheckSchemaMismatch.
+var ErrSchemaMismatch = errors.New("rows event does not match table schema")
+
 func NewCanal(cfg *Config) (*Canal, error) {
 	c := new(Canal)
 	if cfg.Logger == nil {
@@ -144,7 +148,7 @@ func (c *Canal) prepareDumper() error {
 		return nil
 	}
 
-	if c.dumper, err = dump.NewDumper(dumpPath,
+	if c.dumper, err = dump.NewDumperWithArgs(dumpPath, c.cfg.Dump.ExecutionArgs,
 		c.cfg.Addr, c.cfg.User, c.cfg.Password); err != nil {
 		return errors.Trace(err)
 	}
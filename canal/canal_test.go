@@ -1,10 +1,15 @@
 package canal
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tidb/pkg/parser"
 	"github.com/siddontang/go-log/log"
 	"github.com/stretchr/testify/require"
@@ -12,6 +17,8 @@ import (
 
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-mysql-org/go-mysql/schema"
+	"github.com/go-mysql-org/go-mysql/server"
 	"github.com/go-mysql-org/go-mysql/test_util"
 )
 
@@ -480,3 +487,118 @@ func TestIncludeExcludeTableRegex(t *testing.T) {
 	require.False(t, c.checkTableMatch("test.canal_test_inner"))
 	require.False(t, c.checkTableMatch("mysql.canal_test_inner"))
 }
+
+// schemaHandler serves the schema of the table test.t, whose columns are nil once dropped.
+type schemaHandler struct {
+	server.EmptyHandler
+
+	mu      sync.Mutex
+	columns []string
+}
+
+func (h *schemaHandler) HandleQuery(query string) (*mysql.Result, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var names []string
+	var values [][]interface{}
+	switch q := strings.ToLower(query); {
+	case strings.HasPrefix(q, "show full columns from `test`.`t`"):
+		if h.columns == nil {
+			return nil, mysql.NewDefaultError(mysql.ER_NO_SUCH_TABLE, "test", "t")
+		}
+		names = []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"}
+		for _, column := range h.columns {
+			values = append(values, []interface{}{column, "int", nil, "YES", "", nil, "", "select", ""})
+		}
+	case strings.HasPrefix(q, "show index from `test`.`t`"):
+		names = []string{"Table", "Non_unique", "Key_name", "Seq_in_index", "Column_name", "Collation", "Cardinality"}
+	case strings.Contains(q, "information_schema.tables"):
+		names = []string{"TABLE_NAME"}
+		if h.columns != nil {
+			values = [][]interface{}{{"t"}}
+		}
+	default:
+		return nil, nil
+	}
+
+	r, err := mysql.BuildSimpleTextResultset(names, values)
+	if err != nil {
+		return nil, err
+	}
+	return &mysql.Result{Resultset: r}, nil
+}
+
+type rowsEventHandler struct {
+	DummyEventHandler
+	rows []*RowsEvent
+}
+
+func (h *rowsEventHandler) OnRow(e *RowsEvent) error {
+	h.rows = append(h.rows, e)
+	return nil
+}
+
+func TestCheckSchemaMismatch(t *testing.T) {
+	h := &schemaHandler{}
+	logHandler, _ := log.NewNullHandler()
+	cfg := NewDefaultConfig()
+	cfg.User = "root"
+	cfg.CheckSchemaMismatch = true
+	cfg.Logger = log.NewDefault(logHandler)
+	cfg.Dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		serverSide, clientSide := net.Pipe()
+		go func() {
+			conn, err := server.NewConn(serverSide, "root", "", h)
+			if err != nil {
+				return
+			}
+			for conn.HandleCommand() == nil {
+			}
+		}()
+		return clientSide, nil
+	}
+
+	rowsEvent := &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2},
+		Event: &replication.RowsEvent{
+			Table:       &replication.TableMapEvent{Schema: []byte("test"), Table: []byte("t")},
+			ColumnCount: 2,
+			Rows:        [][]interface{}{{int32(1), int32(2)}},
+		},
+	}
+	newCanal := func() (*Canal, *rowsEventHandler) {
+		eh := &rowsEventHandler{}
+		c := &Canal{cfg: cfg, ctx: context.Background(), eventHandler: eh, tables: map[string]*schema.Table{}}
+		// the cached schema misses the column added by a DDL canal didn't see
+		c.tables["test.t"] = &schema.Table{Schema: "test", Name: "t"}
+		c.tables["test.t"].AddColumn("a", "int", "", "")
+		return c, eh
+	}
+
+	// the refreshed schema matches the rows
+	h.columns = []string{"a", "b"}
+	c, eh := newCanal()
+	require.NoError(t, c.handleRowsEvent(rowsEvent))
+	require.Len(t, eh.rows, 1)
+	require.Len(t, eh.rows[0].Table.Columns, 2)
+	require.Equal(t, "b", eh.rows[0].Table.Columns[1].Name)
+	c.conn.Close()
+
+	// the refreshed schema doesn't match either
+	h.columns = []string{"a"}
+	c, eh = newCanal()
+	err := c.handleRowsEvent(rowsEvent)
+	require.Equal(t, ErrSchemaMismatch, errors.Cause(err))
+	require.Empty(t, eh.rows)
+	c.conn.Close()
+
+	// the table was dropped since, its rows are skipped
+	h.columns = nil
+	c, eh = newCanal()
+	require.NoError(t, c.handleRowsEvent(rowsEvent))
+	require.Empty(t, eh.rows)
+	_, ok := c.tables["test.t"]
+	require.False(t, ok)
+	c.conn.Close()
+}
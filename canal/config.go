@@ -22,6 +22,10 @@ type DumpConfig struct {
 	// If not set, ignore using mysqldump.
 	ExecutionPath string `toml:"mysqldump"`
 
+	// Arguments passed to the execution before the mysqldump arguments, like
+	// ["exec", "mysql", "mysqldump"] when ExecutionPath is docker.
+	ExecutionArgs []string `toml:"mysqldump_args"`
+
 	// Will override Databases, tables is in database table_db
 	Tables  []string `toml:"tables"`
 	TableDB string   `toml:"table_db"`
@@ -75,6 +79,13 @@ type Config struct {
 	// discard row event without table meta
 	DiscardNoMetaRowEvent bool `toml:"discard_no_meta_row_event"`
 
+	// CheckSchemaMismatch compares the column count of every rows event with the cached
+	// table schema. On a mismatch (e.g. the schema is stale after a missed DDL) the schema
+	// is refreshed and checked again, if it still does not match ErrSchemaMismatch is
+	// returned instead of delivering rows with misaligned columns. The rows of a table
+	// dropped or excluded since are skipped, like without the check.
+	CheckSchemaMismatch bool `toml:"check_schema_mismatch"`
+
 	Dump DumpConfig `toml:"dump"`
 
 	UseDecimal bool `toml:"use_decimal"`
@@ -50,12 +50,12 @@ func (c *Canal) runSyncBinlog() error {
 
 		switch e := ev.Event.(type) {
 		case *replication.RotateEvent:
-			// If the timestamp equals zero, the received rotate event is a fake rotate event
-			// and contains only the name of the next binlog file. Its log position should be
-			// ignored.
+			// If the event is flagged artificial or its timestamp equals zero, the received
+			// rotate event is a fake rotate event and contains only the name of the next binlog
+			// file. Its log position should be ignored.
 			// See https://github.com/mysql/mysql-server/blob/8e797a5d6eb3a87f16498edcb7261a75897babae/sql/rpl_binlog_sender.h#L235
 			// and https://github.com/mysql/mysql-server/blob/8cc757da3d87bf4a1f07dcfb2d3c96fed3806870/sql/rpl_binlog_sender.cc#L899
-			if ev.Header.Timestamp == 0 {
+			if ev.Header.IsArtificial() || ev.Header.Timestamp == 0 {
 				fakeRotateLogName := string(e.NextLogName)
 				c.cfg.Logger.Infof("received fake rotate event, next log name is %s", e.NextLogName)
 
@@ -270,14 +270,22 @@ func (c *Canal) handleRowsEvent(e *replication.BinlogEvent) error {
 
 	t, err := c.GetTable(schemaName, tableName)
 	if err != nil {
-		e := errors.Cause(err)
-		// ignore errors below
-		if e == ErrExcludedTable || e == schema.ErrTableNotExist || e == schema.ErrMissingTableMeta {
+		if isSkippedTableError(err) {
 			err = nil
 		}
 
 		return err
 	}
+
+	if c.cfg.CheckSchemaMismatch && int(ev.ColumnCount) != len(t.Columns) {
+		if t, err = c.refreshMismatchedTable(t, ev); err != nil {
+			if isSkippedTableError(err) {
+				return nil
+			}
+			return errors.Trace(err)
+		}
+	}
+
 	var action string
 	switch e.Header.EventType {
 	case replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2, replication.MARIADB_WRITE_ROWS_COMPRESSED_EVENT_V1:
@@ -293,6 +301,33 @@ func (c *Canal) handleRowsEvent(e *replication.BinlogEvent) error {
 	return c.eventHandler.OnRow(events)
 }
 
+// isSkippedTableError tells whether the rows events of a table are skipped when GetTable fails
+// with err, e.g. the table was dropped since.
+func isSkippedTableError(err error) bool {
+	e := errors.Cause(err)
+	return e == ErrExcludedTable || e == schema.ErrTableNotExist || e == schema.ErrMissingTableMeta
+}
+
+// refreshMismatchedTable reloads the schema of a table whose cached columns do not
+// match the rows event, returning ErrSchemaMismatch if the new schema does not match either.
+func (c *Canal) refreshMismatchedTable(t *schema.Table, ev *replication.RowsEvent) (*schema.Table, error) {
+	c.cfg.Logger.Warnf("rows event of %s.%s has %d columns but table schema has %d, refresh table schema",
+		t.Schema, t.Name, ev.ColumnCount, len(t.Columns))
+
+	c.ClearTableCache(ev.Table.Schema, ev.Table.Table)
+	t, err := c.GetTable(t.Schema, t.Name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if int(ev.ColumnCount) != len(t.Columns) {
+		return nil, errors.Annotatef(ErrSchemaMismatch, "table %s.%s has %d columns, but rows event has %d",
+			t.Schema, t.Name, len(t.Columns), ev.ColumnCount)
+	}
+
+	return t, nil
+}
+
 func (c *Canal) FlushBinlog() error {
 	_, err := c.Execute("FLUSH BINARY LOGS")
 	return errors.Trace(err)
@@ -51,6 +51,7 @@ func (c *Conn) readInitialHandshake() error {
 		}
 		return errors.Errorf("invalid protocol version %d, expected 10", data[0])
 	}
+	c.protocolVersion = data[0]
 	pos := 1
 
 	// skip mysql version
@@ -79,7 +80,14 @@ func (c *Conn) readInitialHandshake() error {
 		return errors.New("the MySQL server can not support protocol 41 and above required by the client")
 	}
 	if c.capability&CLIENT_SSL == 0 && c.tlsConfig != nil {
-		return errors.New("the MySQL Server does not support TLS required by the client")
+		if !c.tlsPreferred {
+			return errors.New("the MySQL Server does not support TLS required by the client")
+		}
+		// go on in plaintext, with the buffered connection the TLS one would have been replaced by
+		c.tlsConfig = nil
+		seq := c.Sequence
+		c.Conn = packet.NewConnWithTimeout(c.Conn.Conn, c.ReadTimeout, c.WriteTimeout, c.BufferSize)
+		c.Sequence = seq
 	}
 	pos += 2
 
@@ -141,6 +149,11 @@ func (c *Conn) readInitialHandshake() error {
 		c.authPluginName = defaultAuthPluginName
 	}
 
+	// answer with the preferred auth plugin, if the server does not accept it, it will ask to switch
+	if c.preferredAuthPlugin != "" && c.capability&CLIENT_PLUGIN_AUTH != 0 {
+		c.authPluginName = c.preferredAuthPlugin
+	}
+
 	return nil
 }
 
@@ -167,7 +180,14 @@ func (c *Conn) genAuthResponse(authData []byte) ([]byte, bool, error) {
 			// write cleartext auth packet
 			// see: https://dev.mysql.com/doc/refman/8.0/en/sha256-pluggable-authentication.html
 			return []byte(c.password), true, nil
+		} else if c.serverPubKey != nil {
+			// encrypt the password with the known public key, saving a round trip
+			enc, err := EncryptPassword(c.password, c.salt, c.serverPubKey)
+			return enc, false, errors.Trace(err)
 		} else {
+			if err := c.checkPublicKeyRetrieval(); err != nil {
+				return nil, false, err
+			}
 			// request public key from server
 			// see: https://dev.mysql.com/doc/internals/en/public-key-retrieval.html
 			return []byte{1}, false, nil
@@ -178,6 +198,16 @@ func (c *Conn) genAuthResponse(authData []byte) ([]byte, bool, error) {
 	}
 }
 
+// checkPublicKeyRetrieval returns an error if the server RSA public key can't be requested from
+// the server, see SetAllowPublicKeyRetrieval.
+func (c *Conn) checkPublicKeyRetrieval() error {
+	if c.noPublicKeyRetrieval {
+		return errors.Errorf("'%s' authentication without TLS needs the server public key, "+
+			"set it with SetServerPubKey or allow retrieving it from the server", c.authPluginName)
+	}
+	return nil
+}
+
 // generate connection attributes data
 func (c *Conn) genAttributes() []byte {
 	if len(c.attributes) == 0 {
@@ -209,8 +239,14 @@ func (c *Conn) writeAuthHandshake() error {
 	capability |= c.ccaps&CLIENT_FOUND_ROWS | c.ccaps&CLIENT_IGNORE_SPACE |
 		c.ccaps&CLIENT_MULTI_STATEMENTS | c.ccaps&CLIENT_MULTI_RESULTS |
 		c.ccaps&CLIENT_PS_MULTI_RESULTS | c.ccaps&CLIENT_CONNECT_ATTRS |
-		c.ccaps&CLIENT_COMPRESS | c.ccaps&CLIENT_ZSTD_COMPRESSION_ALGORITHM |
-		c.ccaps&CLIENT_LOCAL_FILES
+		c.ccaps&CLIENT_LOCAL_FILES | c.ccaps&CLIENT_SESSION_TRACK
+	// The compression is only used if the server supports it, the connection is uncompressed
+	// otherwise. zlib is preferred if both are requested and supported.
+	if compression := c.ccaps & c.capability & CLIENT_COMPRESS; compression > 0 {
+		capability |= compression
+	} else {
+		capability |= c.ccaps & c.capability & CLIENT_ZSTD_COMPRESSION_ALGORITHM
+	}
 
 	// To enable TLS / SSL
 	if c.tlsConfig != nil {
@@ -256,10 +292,12 @@ func (c *Conn) writeAuthHandshake() error {
 		capability |= CLIENT_CONNECT_ATTRS
 		length += len(attrData)
 	}
-	if c.ccaps&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
+	if capability&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
 		length++
 	}
 
+	c.clientCapability = capability
+
 	data := make([]byte, length+4)
 
 	// capability [32 bit]
@@ -346,7 +384,7 @@ func (c *Conn) writeAuthHandshake() error {
 		pos += copy(data[pos:], attrData)
 	}
 
-	if c.ccaps&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
+	if capability&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
 		// zstd_compression_level
 		data[pos] = 0x03
 	}
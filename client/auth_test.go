@@ -1,6 +1,9 @@
 package client
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
 	"net"
 	"testing"
 
@@ -39,6 +42,40 @@ func TestConnGenAttributes(t *testing.T) {
 	}
 }
 
+func TestConnGenAuthResponseServerPubKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	c := &Conn{
+		password:       "secret",
+		salt:           []byte("01234567890123456789"),
+		authPluginName: mysql.AUTH_SHA256_PASSWORD,
+	}
+
+	// without a known public key it must be requested from the server
+	auth, addNull, err := c.genAuthResponse(c.salt)
+	require.NoError(t, err)
+	require.False(t, addNull)
+	require.Equal(t, []byte{1}, auth)
+
+	// unless it can't be retrieved
+	c.SetAllowPublicKeyRetrieval(false)
+	_, _, err = c.genAuthResponse(c.salt)
+	require.ErrorContains(t, err, "needs the server public key")
+
+	c.SetServerPubKey(&key.PublicKey)
+	auth, addNull, err = c.genAuthResponse(c.salt)
+	require.NoError(t, err)
+	require.False(t, addNull)
+
+	plain, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, auth, nil)
+	require.NoError(t, err)
+	for i := range plain {
+		plain[i] ^= c.salt[i%len(c.salt)]
+	}
+	require.Equal(t, "secret\x00", string(plain))
+}
+
 func TestConnCollation(t *testing.T) {
 	collations := []string{
 		"big5_chinese_ci",
@@ -110,3 +147,73 @@ func sendAuthResponse(t *testing.T, collation string) net.Conn {
 	}()
 	return server
 }
+
+func TestConnPreferredAuthPlugin(t *testing.T) {
+	caps := mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SECURE_CONNECTION | mysql.CLIENT_PLUGIN_AUTH
+
+	handshake := []byte{0, 0, 0, 0, mysql.ClassicProtocolVersion}
+	handshake = append(handshake, "8.0.35\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, "12345678\x00"...)
+	handshake = append(handshake, byte(caps), byte(caps>>8), mysql.DEFAULT_COLLATION_ID, 0, 0, byte(caps>>16), byte(caps>>24), 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "123456789012\x00"...)
+	handshake = append(handshake, mysql.AUTH_CACHING_SHA2_PASSWORD+"\x00"...)
+
+	for _, preferred := range []string{"", mysql.AUTH_NATIVE_PASSWORD} {
+		server, client := net.Pipe()
+		go func() {
+			_ = packet.NewConn(server).WritePacket(handshake)
+		}()
+
+		c := &Conn{Conn: packet.NewConn(client)}
+		if preferred != "" {
+			require.NoError(t, c.SetPreferredAuthPlugin(preferred))
+		}
+		require.NoError(t, c.readInitialHandshake())
+		if preferred == "" {
+			require.Equal(t, mysql.AUTH_CACHING_SHA2_PASSWORD, c.authPluginName)
+		} else {
+			require.Equal(t, preferred, c.authPluginName)
+		}
+		client.Close()
+		server.Close()
+	}
+
+	require.Error(t, (&Conn{}).SetPreferredAuthPlugin("unknown_plugin"))
+}
+
+func TestConnHandshakeInfo(t *testing.T) {
+	caps := mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_DEPRECATE_EOF | mysql.CLIENT_SSL | mysql.CLIENT_SECURE_CONNECTION | mysql.CLIENT_PLUGIN_AUTH
+
+	handshake := []byte{0, 0, 0, 0, mysql.ClassicProtocolVersion}
+	handshake = append(handshake, "8.0.36\x00"...)
+	handshake = append(handshake, 7, 0, 0, 0)
+	handshake = append(handshake, "12345678\x00"...)
+	handshake = append(handshake, byte(caps), byte(caps>>8), mysql.DEFAULT_COLLATION_ID, 0, 0, byte(caps>>16), byte(caps>>24), 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "123456789012\x00"...)
+	handshake = append(handshake, mysql.AUTH_CACHING_SHA2_PASSWORD+"\x00"...)
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go func() {
+		_ = packet.NewConn(server).WritePacket(handshake)
+	}()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	require.Zero(t, c.HandshakeInfo().ProtocolVersion)
+	require.NoError(t, c.readInitialHandshake())
+	c.clientCapability = mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_LONG_PASSWORD
+
+	info := c.HandshakeInfo()
+	require.Equal(t, mysql.ClassicProtocolVersion, info.ProtocolVersion)
+	require.Equal(t, "8.0.36", info.ServerVersion)
+	require.Equal(t, uint32(7), info.ConnectionID)
+	require.Equal(t, []string{"CLIENT_PROTOCOL_41"}, info.Capabilities)
+	require.Equal(t, mysql.AUTH_CACHING_SHA2_PASSWORD, info.AuthPluginName)
+	require.Nil(t, info.TLS)
+
+	require.Equal(t, "CLIENT_PROTOCOL_41|CLIENT_SSL|CLIENT_SECURE_CONNECTION|CLIENT_PLUGIN_AUTH|CLIENT_DEPRECATE_EOF", c.CapabilityString())
+}
@@ -0,0 +1,297 @@
+package client
+
+import (
+	"encoding/binary"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// SelectPerChunkCallback is called by ExecuteSelectStreaming, when set as Conn.FieldChunkCallback,
+// with the consecutive chunks of a BLOB, TEXT or JSON value of the row being read, last is true
+// for the final chunk of the value. It is not called for NULL values.
+// You must not save chunk after this callback is done. Copy it if you need.
+type SelectPerChunkCallback func(column int, chunk []byte, last bool) error
+
+// isChunkedField reports whether the values of f are passed to Conn.FieldChunkCallback.
+func isChunkedField(f *Field) bool {
+	switch f.Type {
+	case MYSQL_TYPE_TINY_BLOB, MYSQL_TYPE_MEDIUM_BLOB, MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BLOB,
+		MYSQL_TYPE_JSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// binaryFieldSize returns the size of the values of f in a binary row, 0 if they are length encoded.
+func binaryFieldSize(f *Field) int {
+	switch f.Type {
+	case MYSQL_TYPE_TINY:
+		return 1
+	case MYSQL_TYPE_SHORT, MYSQL_TYPE_YEAR:
+		return 2
+	case MYSQL_TYPE_INT24, MYSQL_TYPE_LONG, MYSQL_TYPE_FLOAT:
+		return 4
+	case MYSQL_TYPE_LONGLONG, MYSQL_TYPE_DOUBLE:
+		return 8
+	default:
+		return 0
+	}
+}
+
+const (
+	chunkStateHeader = iota
+	chunkStateLength
+	chunkStateCopy
+	chunkStateStream
+	chunkStateDone
+	chunkStateRaw
+)
+
+// chunkedRowWriter receives the payload of a row packet as it is read off the connection.
+// It passes the values of the chunked fields to cb, if set, and keeps the rest of the packet
+// in row, with these values replaced by empty strings, so the row can still be parsed.
+type chunkedRowWriter struct {
+	fields []*Field
+	binary bool
+	cb     SelectPerChunkCallback
+	// the values kept in row larger than maxFieldSize, if set, are dropped and fail the row
+	// with ErrFieldTooLarge
+	maxFieldSize int
+	// err is the first error of cb or of a value too large, the chunks after it are dropped
+	err error
+
+	row   []byte
+	state int
+	col   int
+	// the size of the header and NULL bitmap of binary rows
+	header int
+	// the start in row of the current value length
+	start int
+	// the bytes left of the current value
+	left uint64
+}
+
+func (w *chunkedRowWriter) reset() {
+	w.row = w.row[:0]
+	w.state = chunkStateHeader
+	w.col = 0
+	w.start = 0
+	w.left = 0
+	if w.binary {
+		// the NULL bitmap of binary rows starts at bit 2
+		w.header = 1 + ((len(w.fields) + 7 + 2) >> 3)
+	}
+}
+
+// complete reports whether a whole row was written.
+func (w *chunkedRowWriter) complete() bool {
+	return w.state == chunkStateDone
+}
+
+func (w *chunkedRowWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		switch w.state {
+		case chunkStateHeader:
+			// EOF and ERR packets are kept as they are
+			if len(w.row) == 0 && (p[0] == ERR_HEADER || (w.binary && p[0] != OK_HEADER)) {
+				w.state = chunkStateRaw
+				continue
+			}
+			if !w.binary {
+				w.nextValue()
+				continue
+			}
+			m := min(w.header-len(w.row), len(p))
+			w.row = append(w.row, p[:m]...)
+			p = p[m:]
+			if len(w.row) == w.header {
+				w.nextValue()
+			}
+		case chunkStateLength:
+			w.row = append(w.row, p[0])
+			p = p[1:]
+			w.readLength()
+		case chunkStateCopy:
+			m := int(min(w.left, uint64(len(p))))
+			w.row = append(w.row, p[:m]...)
+			p = p[m:]
+			w.left -= uint64(m)
+			if w.left == 0 {
+				w.col++
+				w.nextValue()
+			}
+		case chunkStateStream:
+			m := int(min(w.left, uint64(len(p))))
+			w.left -= uint64(m)
+			w.chunk(p[:m], w.left == 0)
+			p = p[m:]
+			if w.left == 0 {
+				w.col++
+				w.nextValue()
+			}
+		default:
+			// trailing bytes are kept for the row parsing to fail
+			w.row = append(w.row, p...)
+			p = nil
+		}
+	}
+	return n, nil
+}
+
+// nextValue sets the state for reading the value of column w.col.
+func (w *chunkedRowWriter) nextValue() {
+	for ; w.col < len(w.fields); w.col++ {
+		f := w.fields[w.col]
+		if w.binary {
+			nullBitmap := w.row[1:w.header]
+			if nullBitmap[(w.col+2)/8]&(1<<(uint(w.col+2)%8)) > 0 || f.Type == MYSQL_TYPE_NULL {
+				continue
+			}
+			if size := binaryFieldSize(f); size > 0 {
+				w.state = chunkStateCopy
+				w.left = uint64(size)
+				return
+			}
+		}
+		w.state = chunkStateLength
+		w.start = len(w.row)
+		return
+	}
+	w.state = chunkStateDone
+}
+
+// readLength handles the bytes of a length encoded value length read so far.
+func (w *chunkedRowWriter) readLength() {
+	b := w.row[w.start:]
+	size := 1
+	switch b[0] {
+	case 0xfb:
+		// NULL
+		w.col++
+		w.nextValue()
+		return
+	case 0xfc:
+		size = 3
+	case 0xfd:
+		size = 4
+	case 0xfe:
+		size = 9
+	case 0xff:
+		w.state = chunkStateRaw
+		return
+	}
+	if len(b) < size {
+		return
+	}
+
+	length, _, _ := LengthEncodedInt(b)
+	f := w.fields[w.col]
+	if w.cb == nil || !isChunkedField(f) {
+		if w.maxFieldSize > 0 && length > uint64(w.maxFieldSize) {
+			if w.err == nil {
+				w.err = errors.Annotatef(ErrFieldTooLarge, "field %s is %d bytes, more than MaxFieldSize %d",
+					f.Name, length, w.maxFieldSize)
+			}
+			// the value is dropped like the chunks after an error
+			w.row = append(w.row[:w.start], 0)
+			w.state = chunkStateStream
+			w.left = length
+			return
+		}
+		w.state = chunkStateCopy
+		w.left = length
+		if length == 0 {
+			w.col++
+			w.nextValue()
+		}
+		return
+	}
+
+	// the value is replaced by an empty string
+	w.row = append(w.row[:w.start], 0)
+	if length == 0 {
+		w.chunk(nil, true)
+		w.col++
+		w.nextValue()
+		return
+	}
+	w.state = chunkStateStream
+	w.left = length
+}
+
+func (w *chunkedRowWriter) chunk(chunk []byte, last bool) {
+	if w.err == nil {
+		w.err = w.cb(w.col, chunk, last)
+	}
+}
+
+// readResultRowsChunked is readResultRowsStreaming passing the values of the chunked fields
+// to FieldChunkCallback as they are read.
+func (c *Conn) readResultRowsChunked(result *Result, isBinary bool, perRowCb SelectPerRowCallback) (err error) {
+	var row []FieldValue
+	w := &chunkedRowWriter{
+		fields:       result.Fields,
+		binary:       isBinary,
+		cb:           c.FieldChunkCallback,
+		maxFieldSize: c.MaxFieldSize,
+	}
+
+	t, err := c.newTranscoder(result.Fields)
+	if err != nil {
+		return errors.Trace(c.discardResultRows(err))
+	}
+
+	for {
+		w.reset()
+		if err = c.ReadPacketTo(w); err != nil {
+			return errors.Trace(err)
+		}
+		data := w.row
+		if len(data) == 0 {
+			return ErrMalformPacket
+		}
+
+		// EOF Packet
+		if !w.complete() && c.isEOFPacket(data) {
+			if c.capability&CLIENT_PROTOCOL_41 > 0 {
+				result.Warnings = binary.LittleEndian.Uint16(data[1:])
+				result.Status = binary.LittleEndian.Uint16(data[3:])
+				c.status = result.Status
+			}
+
+			break
+		}
+
+		if !w.complete() && data[0] == ERR_HEADER {
+			return c.handleErrorPacket(data)
+		}
+
+		if !w.complete() {
+			return ErrMalformPacket
+		}
+
+		if w.err != nil {
+			// skip the remaining rows so the connection can still be used
+			return errors.Trace(c.discardResultRows(w.err))
+		}
+
+		row, err = RowData(data).Parse(result.Fields, isBinary, row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		if err = t.transcode(row); err != nil {
+			return errors.Trace(c.discardResultRows(err))
+		}
+
+		if err = perRowCb(row); err != nil {
+			return errors.Trace(c.discardResultRows(err))
+		}
+	}
+
+	return nil
+}
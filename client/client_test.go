@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/stretchr/testify/require"
@@ -91,14 +92,21 @@ func (s *clientTestSuite) TestConn_Ping() {
 
 func (s *clientTestSuite) TestConn_Compress() {
 	addr := fmt.Sprintf("%s:%s", *test_util.MysqlHost, s.port)
-	conn, err := Connect(addr, *testUser, *testPassword, "", func(conn *Conn) error {
-		conn.SetCapability(mysql.CLIENT_COMPRESS)
-		return nil
-	})
-	require.NoError(s.T(), err)
-
-	_, err = conn.Execute("SELECT VERSION()")
-	require.NoError(s.T(), err)
+	for _, compression := range []uint32{mysql.CLIENT_COMPRESS, mysql.CLIENT_ZSTD_COMPRESSION_ALGORITHM} {
+		conn, err := Connect(addr, *testUser, *testPassword, "", func(conn *Conn) error {
+			conn.SetCapability(compression)
+			return nil
+		})
+		require.NoError(s.T(), err)
+
+		// zstd is supported since MySQL 8.0.18, the connection is uncompressed before
+		r, err := conn.Execute("SELECT REPEAT('a', 100000)")
+		require.NoError(s.T(), err)
+		v, err := r.GetString(0, 0)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), strings.Repeat("a", 100000), v)
+		conn.Close()
+	}
 }
 
 func (s *clientTestSuite) TestConn_SetCapability() {
@@ -432,6 +440,27 @@ func (s *clientTestSuite) TestStmt_Signed() {
 	require.NoError(s.T(), err)
 }
 
+func (s *clientTestSuite) TestStmt_DateTimeFractional() {
+	_, err := s.c.Execute(`CREATE TEMPORARY TABLE mixer_test_datetime6 (id INT PRIMARY KEY, dt DATETIME(6), t TIME(6))`)
+	require.NoError(s.T(), err)
+
+	dt := time.Date(2024, 2, 29, 13, 14, 15, 123456000, time.UTC)
+	d := -(25*time.Hour + 2*time.Minute + 3*time.Second + 654321*time.Microsecond)
+	_, err = s.c.Execute(`INSERT INTO mixer_test_datetime6 (id, dt, t) VALUES (?, ?, ?)`, 1, dt, d)
+	require.NoError(s.T(), err)
+
+	result, err := s.c.Execute(`SELECT dt, t FROM mixer_test_datetime6 WHERE id = ?`, 1)
+	require.NoError(s.T(), err)
+
+	str, err := result.GetString(0, 0)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "2024-02-29 13:14:15.123456", str)
+
+	str, err = result.GetString(0, 1)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "-25:02:03.654321", str)
+}
+
 func (s *clientTestSuite) TestStmt_Trans() {
 	_, err := s.c.Execute(`insert into mixer_test_stmt (id, str) values (1002, "abc")`)
 	require.NoError(s.T(), err)
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/pkg/parser/charset"
+)
+
+var (
+	collationNamesOnce sync.Once
+	// the names of all the collations known to the parser
+	collationNames []string
+)
+
+// maxCollationSuggestions is the number of close matches listed for an unknown collation.
+const maxCollationSuggestions = 5
+
+// unknownCollationError returns the error of the unknown collation name, listing the known
+// collations with a close name.
+func unknownCollationError(name string) error {
+	collationNamesOnce.Do(func() {
+		// the collation ids are 2 bytes
+		for id := 1; id <= 0xffff; id++ {
+			if collation, err := charset.GetCollationByID(id); err == nil {
+				collationNames = append(collationNames, collation.Name)
+			}
+		}
+	})
+
+	name = strings.ToLower(name)
+	// names up to a third of their length apart, at least 2, are close
+	maxDistance := max(len(name)/3, 2)
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, known := range collationNames {
+		if d := editDistance(name, known); d <= maxDistance {
+			matches = append(matches, match{known, d})
+		}
+	}
+	if len(matches) == 0 {
+		return errors.Errorf("unknown collation %s", name)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	names := make([]string, 0, maxCollationSuggestions)
+	for i := 0; i < len(matches) && i < maxCollationSuggestions; i++ {
+		names = append(names, matches[i].name)
+	}
+	return errors.Errorf("unknown collation %s, did you mean %s?", name, strings.Join(names, ", "))
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
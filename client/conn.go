@@ -3,11 +3,14 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"runtime"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -29,7 +32,23 @@ type Conn struct {
 	password  string
 	db        string
 	tlsConfig *tls.Config
-	proto     string
+	// the connection is in plaintext, instead of failing, if the server doesn't support TLS
+	tlsPreferred bool
+	proto        string
+
+	// what the connection was opened with, for Clone
+	addr    string
+	dialer  Dialer
+	options []Option
+
+	// server RSA public key used by sha256_password and caching_sha2_password full authentication,
+	// if nil it is requested from the server
+	serverPubKey *rsa.PublicKey
+	// the server RSA public key is not requested from the server, see SetAllowPublicKeyRetrieval
+	noPublicKeyRetrieval bool
+
+	// auth plugin used in the handshake response instead of the one advertised by the server
+	preferredAuthPlugin string
 
 	// Connection read and write timeouts to set on the connection
 	ReadTimeout  time.Duration
@@ -38,16 +57,72 @@ type Conn struct {
 	// The buffer size to use in the packet connection
 	BufferSize int
 
-	serverVersion string
+	// ColumnNameFunc, if set, maps the column names of resultsets to the names the name-based
+	// getters, like GetIntByName, look up. Field.Name is kept as sent by the server.
+	// See NormalizeColumnName.
+	ColumnNameFunc func(name string) string
+
+	// The maximum size of a single field value read in a resultset, 0 means no limit.
+	// A larger value fails the query with ErrFieldTooLarge, it is checked as the row is read
+	// and isn't kept in memory, the rest of the resultset is skipped.
+	MaxFieldSize int
+
+	// FieldChunkCallback, if set, is passed the values of the BLOB, TEXT and JSON columns of the
+	// rows read by ExecuteSelectStreaming in chunks, as they are read off the connection, instead
+	// of them being read into memory. In the rows passed to the SelectPerRowCallback, these values
+	// are empty strings, or NULL. MaxFieldSize doesn't apply to them.
+	FieldChunkCallback SelectPerChunkCallback
+
+	// TranscodeResults, if set, converts the values of the string columns of resultsets whose
+	// charset isn't the one of the connection, e.g. latin1 columns, to the connection charset.
+	// The binary strings and the chunks passed to FieldChunkCallback are kept as they are.
+	// The server converts the values itself unless character_set_results is NULL or binary,
+	// so the connection sets it to NULL after SET NAMES: TranscodeResults must be set before
+	// connecting, e.g. in an Option.
+	TranscodeResults bool
+
+	// MetricsCallback, if set, is called after each command with its metrics. It must be set
+	// before connecting, e.g. in an Option, for the bytes of the connection to be counted.
+	MetricsCallback func(CommandMetrics)
+	counter         *countingConn
+
+	// QueryLogFunc, if set, logs each query and prepared statement execution with its duration
+	// and error. The text of the query and its arguments is the one of QueryLogRedactor, by
+	// default RedactQuery which doesn't log the values.
+	QueryLogFunc     func(format string, args ...interface{})
+	QueryLogRedactor QueryRedactor
+
+	// OnDisconnect, if set, enables the detection of the server closing the connection while
+	// it is idle, like when wait_timeout passes: a background reader runs between commands,
+	// and when the connection is closed, it closes the socket and calls OnDisconnect from its
+	// goroutine with an error wrapping ErrBadConn. Only the commands of Conn stop the reader,
+	// the connection must not be used through the packet.Conn methods directly.
+	OnDisconnect func(err error)
+	watch        *idleWatch
+	// disables the detection, once the connection is closed or streams binlog events
+	watchOff bool
+
+	// set by the first Close, the later calls do nothing
+	closed atomic.Bool
+
+	// protocol version of the initial handshake
+	protocolVersion byte
+	serverVersion   string
 	// server capabilities
 	capability uint32
 	// client-set capabilities only
 	ccaps uint32
+	// capabilities sent in the handshake response
+	clientCapability uint32
 
 	attributes map[string]string
 
 	status uint16
 
+	// tracked with CLIENT_SESSION_TRACK and session_track_transaction_info, empty otherwise
+	trxState           string
+	trxCharacteristics string
+
 	charset string
 	// sets the collation to be set on the auth handshake, this does not issue a 'set names' command
 	collation string
@@ -56,6 +131,19 @@ type Conn struct {
 	authPluginName string
 
 	connectionID uint32
+
+	// autocommit mode of the session when the connection was opened
+	defaultAutoCommit bool
+
+	// session variables read on the first call of ServerTimeZone and SQLMode, nil until then
+	serverTimeZone *time.Location
+	sqlMode        *string
+}
+
+// NormalizeColumnName lowercases name and strips its backticks and surrounding spaces, to be
+// used as ColumnNameFunc. The names given to the name-based getters must be normalized too.
+func NormalizeColumnName(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.ReplaceAll(name, "`", "")))
 }
 
 // This function will be called for every row in resultset from ExecuteSelectStreaming.
@@ -83,7 +171,7 @@ func Connect(addr, user, password, dbName string, options ...Option) (*Conn, err
 
 // ConnectWithTimeout to a MySQL address using a timeout.
 func ConnectWithTimeout(addr, user, password, dbName string, timeout time.Duration, options ...Option) (*Conn, error) {
-	return ConnectWithContext(context.Background(), addr, user, password, dbName, time.Second*10, options...)
+	return ConnectWithContext(context.Background(), addr, user, password, dbName, timeout, options...)
 }
 
 // ConnectWithContext to a MySQL addr using the provided context.
@@ -92,6 +180,29 @@ func ConnectWithContext(ctx context.Context, addr, user, password, dbName string
 	return ConnectWithDialer(ctx, "", addr, user, password, dbName, dialer.DialContext, options...)
 }
 
+// ConnectAny connects to the first reachable MySQL server of addrs, a comma-separated list of
+// addresses accepted by Connect, trying them in order. It returns the address connected to.
+func ConnectAny(addrs, user, password, dbName string, options ...Option) (*Conn, string, error) {
+	var errs []string
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		c, err := Connect(addr, user, password, dbName, options...)
+		if err == nil {
+			return c, addr, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", addr, err))
+	}
+
+	if len(errs) == 0 {
+		return nil, "", errors.New("no address to connect to")
+	}
+	return nil, "", errors.Errorf("failed to connect to any address: %s", strings.Join(errs, "; "))
+}
+
 // Dialer connects to the address on the named network using the provided context.
 type Dialer func(ctx context.Context, network, address string) (net.Conn, error)
 
@@ -122,6 +233,9 @@ func ConnectWithDialer(ctx context.Context, network, addr, user, password, dbNam
 	c.password = password
 	c.db = dbName
 	c.proto = network
+	c.addr = addr
+	c.dialer = dialer
+	c.options = options
 
 	// use default charset here, utf-8
 	c.charset = DEFAULT_CHARSET
@@ -135,6 +249,11 @@ func ConnectWithDialer(ctx context.Context, network, addr, user, password, dbNam
 		}
 	}
 
+	if c.MetricsCallback != nil {
+		c.counter = &countingConn{Conn: conn}
+		conn = c.counter
+	}
+
 	c.Conn = packet.NewConnWithTimeout(conn, c.ReadTimeout, c.WriteTimeout, c.BufferSize)
 	if c.tlsConfig != nil {
 		seq := c.Conn.Sequence
@@ -142,35 +261,112 @@ func ConnectWithDialer(ctx context.Context, network, addr, user, password, dbNam
 		c.Conn.Sequence = seq
 	}
 
-	if err = c.handshake(); err != nil {
-		// in the event of an error c.handshake() will close the connection
+	// the handshake is interrupted by closing the connection once ctx is done
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	err = c.setup()
+	if !stop() {
+		c.Close()
+		return nil, errors.Trace(ctx.Err())
+	}
+	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	if c.ccaps&CLIENT_COMPRESS > 0 {
+	c.defaultAutoCommit = c.IsAutoCommit()
+	c.startWatch()
+
+	return c, nil
+}
+
+// setup does the handshake of the connection and sets its collation or charset.
+func (c *Conn) setup() error {
+	if err := c.handshake(); err != nil {
+		// in the event of an error c.handshake() will close the connection
+		return errors.Trace(err)
+	}
+
+	// the compression negotiated in the handshake
+	if c.clientCapability&CLIENT_COMPRESS > 0 {
 		c.Conn.Compression = MYSQL_COMPRESS_ZLIB
-	} else if c.ccaps&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
+	} else if c.clientCapability&CLIENT_ZSTD_COMPRESSION_ALGORITHM > 0 {
 		c.Conn.Compression = MYSQL_COMPRESS_ZSTD
 	}
 
-	// if a collation was set with a ID of > 255, then we need to call SET NAMES ...
-	// since the auth handshake response only support collations with 1-byte ids
 	if len(c.collation) != 0 {
 		collation, err := charset.GetCollationByName(c.collation)
 		if err != nil {
 			c.Close()
-			return nil, errors.Trace(fmt.Errorf("invalid collation name %s", c.collation))
+			return errors.Trace(fmt.Errorf("invalid collation name %s", c.collation))
 		}
 
-		if collation.ID > 255 {
-			if _, err := c.exec(fmt.Sprintf("SET NAMES %s COLLATE %s", c.charset, c.collation)); err != nil {
-				c.Close()
-				return nil, errors.Trace(err)
+		// the collation wins over the charset
+		c.charset = collation.CharsetName
+	}
+	if err := c.setNames(); err != nil {
+		c.Close()
+		return errors.Trace(err)
+	}
+	if err := c.setResultsCharset(); err != nil {
+		c.Close()
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// setNames sets the charset and collation of the session with SET NAMES if the ones of the
+// handshake differ: the handshake only supports the collations with 1-byte ids, and is done
+// with the default collation if only a charset is set.
+func (c *Conn) setNames() error {
+	if len(c.collation) != 0 {
+		collation, err := charset.GetCollationByName(c.collation)
+		if err != nil {
+			return errors.Trace(fmt.Errorf("invalid collation name %s", c.collation))
+		}
+		if collation.CharsetName == c.charset {
+			if collation.ID > 255 {
+				if _, err := c.exec(fmt.Sprintf("SET NAMES %s COLLATE %s", c.charset, c.collation)); err != nil {
+					return errors.Trace(err)
+				}
 			}
+			return nil
 		}
+	} else if c.charset == DEFAULT_CHARSET {
+		return nil
 	}
 
-	return c, nil
+	// a charset set by SetCharset after the handshake
+	if _, err := c.exec(fmt.Sprintf("SET NAMES %s", c.charset)); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// setResultsCharset has the server return the values in the charset of their column, for
+// TranscodeResults to convert them: SET NAMES and the handshake set character_set_results
+// to the connection charset.
+func (c *Conn) setResultsCharset() error {
+	if !c.TranscodeResults {
+		return nil
+	}
+	if _, err := c.exec("SET character_set_results = NULL"); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// Clone opens a new connection to the same server, with the same credentials, current
+// database and options, like for a side connection killing or monitoring the queries of c.
+// The options are applied again to the new connection.
+func (c *Conn) Clone() (*Conn, error) {
+	return c.clone(context.Background())
+}
+
+// clone opens the new connection of Clone, applying options after the ones of c.
+func (c *Conn) clone(ctx context.Context, options ...Option) (*Conn, error) {
+	if c.dialer == nil {
+		return nil, errors.New("the connection was not opened by Connect")
+	}
+	return ConnectWithDialer(ctx, c.proto, c.addr, c.user, c.password, c.db, c.dialer, append(slices.Clip(c.options), options...)...)
 }
 
 func (c *Conn) handshake() error {
@@ -194,18 +390,32 @@ func (c *Conn) handshake() error {
 	return nil
 }
 
+// Close closes the connection. It can be called more than once, the calls after the first
+// return nil.
 func (c *Conn) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	c.stopWatch()
+	c.watchOff = true
 	return c.Conn.Close()
 }
 
-func (c *Conn) Quit() error {
+func (c *Conn) Quit() (err error) {
+	if c.closed.Load() {
+		return nil
+	}
+	defer c.observe(COM_QUIT)(&err)
+
 	if err := c.writeCommand(COM_QUIT); err != nil {
 		return err
 	}
 	return c.Close()
 }
 
-func (c *Conn) Ping() error {
+func (c *Conn) Ping() (err error) {
+	defer c.observe(COM_PING)(&err)
+
 	if err := c.writeCommand(COM_PING); err != nil {
 		return errors.Trace(err)
 	}
@@ -217,6 +427,26 @@ func (c *Conn) Ping() error {
 	return nil
 }
 
+// WriteBinlogDump sends a COM_BINLOG_DUMP command with the given flags. The binlog events
+// must then be read with ReadPacket, with BINLOG_DUMP_NON_BLOCK the server sends an EOF
+// packet when it has no more events to send.
+func (c *Conn) WriteBinlogDump(serverID uint32, flags uint16, p Position) error {
+	c.stopWatch()
+	c.watchOff = true
+	c.ResetSequence()
+
+	return c.WritePacket(AppendBinlogDumpCommand(make([]byte, 4, 4+11+len(p.Name)), serverID, flags, p))
+}
+
+// WriteBinlogDumpGTID sends a COM_BINLOG_DUMP_GTID command with the given flags, see WriteBinlogDump.
+func (c *Conn) WriteBinlogDumpGTID(serverID uint32, flags uint16, p Position, gset GTIDSet) error {
+	c.stopWatch()
+	c.watchOff = true
+	c.ResetSequence()
+
+	return c.WritePacket(AppendBinlogDumpGTIDCommand(make([]byte, 4), serverID, flags, p, gset))
+}
+
 // SetCapability enables the use of a specific capability
 func (c *Conn) SetCapability(cap uint32) {
 	c.ccaps |= cap
@@ -236,19 +466,59 @@ func (c *Conn) HasCapability(cap uint32) bool {
 // pass to options when connect
 func (c *Conn) UseSSL(insecureSkipVerify bool) {
 	c.tlsConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	c.tlsPreferred = false
 }
 
 // SetTLSConfig: use user-specified TLS config
 // pass to options when connect
 func (c *Conn) SetTLSConfig(config *tls.Config) {
 	c.tlsConfig = config
+	c.tlsPreferred = false
+}
+
+// PreferTLS: use the TLS config if the server supports TLS, and a plaintext connection otherwise
+// pass to options when connect
+func (c *Conn) PreferTLS(config *tls.Config) {
+	c.tlsConfig = config
+	c.tlsPreferred = true
+}
+
+// SetServerPubKey: use the given server RSA public key to encrypt the password for
+// 'sha256_password' and 'caching_sha2_password' authentication over a non-TLS connection,
+// instead of requesting it from the server.
+// pass to options when connect
+func (c *Conn) SetServerPubKey(pub *rsa.PublicKey) {
+	c.serverPubKey = pub
+}
+
+// SetAllowPublicKeyRetrieval: whether the server RSA public key may be requested from the server
+// for 'sha256_password' and 'caching_sha2_password' full authentication over a non-TLS connection,
+// it is allowed by default. A key sent by a server impersonating the real one would let it read the
+// password, so without TLS the authentication fails instead unless the key is set with SetServerPubKey.
+// pass to options when connect
+func (c *Conn) SetAllowPublicKeyRetrieval(allow bool) {
+	c.noPublicKeyRetrieval = !allow
+}
+
+// SetPreferredAuthPlugin: authenticate with the given auth plugin rather than the server default one,
+// e.g. 'mysql_native_password' to avoid the RSA key exchange of 'caching_sha2_password' without TLS.
+// The server may still ask to switch to another plugin.
+// pass to options when connect
+func (c *Conn) SetPreferredAuthPlugin(name string) error {
+	if !authPluginAllowed(name) {
+		return errors.Errorf("unsupported auth plugin '%s'", name)
+	}
+	c.preferredAuthPlugin = name
+	return nil
 }
 
-func (c *Conn) UseDB(dbName string) error {
+func (c *Conn) UseDB(dbName string) (err error) {
 	if c.db == dbName {
 		return nil
 	}
 
+	defer c.observe(COM_INIT_DB)(&err)
+
 	if err := c.writeCommandStr(COM_INIT_DB, dbName); err != nil {
 		return errors.Trace(err)
 	}
@@ -273,6 +543,126 @@ func (c *Conn) CompareServerVersion(v string) (int, error) {
 	return CompareServerVersions(c.serverVersion, v)
 }
 
+// ServerTimeZone returns the time zone of the session, it is used to interpret
+// TIMESTAMP values. When @@time_zone is SYSTEM, @@system_time_zone is used instead;
+// an abbreviation like CST, which isn't a known location, is the offset of the
+// server from UTC when it is queried.
+//
+// The value is queried on the first call, which can't be made while a resultset is
+// being read, and kept until ResetConnection: a later `SET time_zone` on this
+// connection is not reflected. The error of the query is returned, a server like a
+// proxy may not support it, and the next call queries it again.
+func (c *Conn) ServerTimeZone() (*time.Location, error) {
+	if c.serverTimeZone == nil {
+		loc, err := c.loadServerTimeZone()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.serverTimeZone = loc
+	}
+	return c.serverTimeZone, nil
+}
+
+// SQLMode returns the @@sql_mode of the session. Like ServerTimeZone, it is
+// queried on the first call.
+func (c *Conn) SQLMode() (string, error) {
+	if c.sqlMode == nil {
+		sqlMode, err := c.loadSQLMode()
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		c.sqlMode = &sqlMode
+	}
+	return *c.sqlMode, nil
+}
+
+func (c *Conn) loadSQLMode() (string, error) {
+	values, err := c.sessionValues("SELECT @@sql_mode", 1)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return values[0], nil
+}
+
+func (c *Conn) loadServerTimeZone() (*time.Location, error) {
+	values, err := c.sessionValues("SELECT @@time_zone, @@system_time_zone, TIMEDIFF(NOW(), UTC_TIMESTAMP())", 3)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	loc, err := parseServerTimeZone(values[0], values[1], values[2])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return loc, nil
+}
+
+// sessionValues returns the n values of the row of query as strings.
+func (c *Conn) sessionValues(query string, n int) ([]string, error) {
+	r, err := c.exec(query)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Close()
+
+	if r.Resultset == nil || r.RowNumber() != 1 || r.ColumnNumber() != n {
+		return nil, errors.Errorf("unexpected result of %s", query)
+	}
+	values := make([]string, n)
+	for i := range values {
+		v, err := r.GetString(0, i)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// the resultset memory is reused once it is closed
+		values[i] = strings.Clone(v)
+	}
+	return values, nil
+}
+
+// parseServerTimeZone converts the values of @@time_zone and @@system_time_zone
+// into a *time.Location. MySQL accepts either a named zone or an offset like '+08:00'.
+// A name that isn't a known location is the zone of utcOffset, the result of
+// TIMEDIFF(NOW(), UTC_TIMESTAMP()).
+func parseServerTimeZone(timeZone, systemTimeZone, utcOffset string) (*time.Location, error) {
+	if strings.EqualFold(timeZone, "SYSTEM") {
+		timeZone = systemTimeZone
+	}
+
+	if len(timeZone) > 0 && (timeZone[0] == '+' || timeZone[0] == '-') {
+		offset, err := parseTimeZoneOffset(timeZone)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return time.FixedZone(timeZone, offset), nil
+	}
+
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		offset, offsetErr := parseTimeZoneOffset(utcOffset)
+		if offsetErr != nil {
+			return nil, errors.Errorf("unknown server time zone %q: %v", timeZone, err)
+		}
+		return time.FixedZone(timeZone, offset), nil
+	}
+	return loc, nil
+}
+
+// parseTimeZoneOffset returns the seconds of an offset from UTC like '+08:00' or '-05:00:00'.
+func parseTimeZoneOffset(s string) (int, error) {
+	sign, v := 1, s
+	if len(v) > 0 && (v[0] == '+' || v[0] == '-') {
+		if v[0] == '-' {
+			sign = -1
+		}
+		v = v[1:]
+	}
+	var hours, minutes int
+	if _, err := fmt.Sscanf(v, "%d:%d", &hours, &minutes); err != nil {
+		return 0, errors.Errorf("invalid time zone offset %q", s)
+	}
+	return sign * (hours*3600 + minutes*60), nil
+}
+
 func (c *Conn) Execute(command string, args ...interface{}) (*Result, error) {
 	if len(args) == 0 {
 		return c.exec(command)
@@ -288,12 +678,80 @@ func (c *Conn) Execute(command string, args ...interface{}) (*Result, error) {
 	}
 }
 
+// QueryScalar executes the query and returns the single value of its resultset,
+// which must have exactly one row and one column. NULL is returned as nil.
+func (c *Conn) QueryScalar(query string, args ...interface{}) (interface{}, error) {
+	r, err := c.queryScalar(query, args...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Close()
+
+	v, err := r.GetValue(0, 0)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// the resultset memory is reused once it is closed
+	if b, ok := v.([]byte); ok {
+		v = bytes.Clone(b)
+	}
+	return v, nil
+}
+
+// QueryInt is like QueryScalar but converts the value to an int64, NULL being 0.
+func (c *Conn) QueryInt(query string, args ...interface{}) (int64, error) {
+	r, err := c.queryScalar(query, args...)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer r.Close()
+
+	return r.GetInt(0, 0)
+}
+
+// QueryString is like QueryScalar but converts the value to a string, NULL being "".
+func (c *Conn) QueryString(query string, args ...interface{}) (string, error) {
+	r, err := c.queryScalar(query, args...)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer r.Close()
+
+	v, err := r.GetString(0, 0)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return strings.Clone(v), nil
+}
+
+func (c *Conn) queryScalar(query string, args ...interface{}) (*Result, error) {
+	r, err := c.Execute(query, args...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	if r.Resultset == nil || r.RowNumber() != 1 || r.ColumnNumber() != 1 {
+		rows, columns := 0, 0
+		if r.Resultset != nil {
+			rows, columns = r.RowNumber(), r.ColumnNumber()
+		}
+		r.Close()
+		return nil, errors.Errorf("expected a single value, got %d rows of %d columns", rows, columns)
+	}
+
+	return r, nil
+}
+
 // ExecuteMultiple will call perResultCallback for every result of the multiple queries
-// that are executed.
+// that are executed, as each of them is read, in a single round trip.
 //
-// When ExecuteMultiple is used, the connection should have the SERVER_MORE_RESULTS_EXISTS
-// flag set to signal the server multiple queries are executed. Handling the responses
-// is up to the implementation of perResultCallback.
+// When ExecuteMultiple is used, the connection should have the CLIENT_MULTI_STATEMENTS
+// capability set to run more than one statement, see SetCapability. Handling the responses
+// is up to the implementation of perResultCallback. When a statement fails the server
+// doesn't run the next ones, its error, annotated with the number of the statement from 1,
+// is passed to perResultCallback and is the last call. The error wraps the *mysql.MyError
+// of the server, use errors.As to get it. The resultsets of a CALL and its final OK are
+// counted as one statement.
 //
 // Example:
 //
@@ -301,13 +759,15 @@ func (c *Conn) Execute(command string, args ...interface{}) (*Result, error) {
 // conn.ExecuteMultiple(queries, func(result *mysql.Result, err error) {
 // // Use the result as you want
 // })
-func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCallback) (*Result, error) {
+func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCallback) (_ *Result, err error) {
+	defer c.observeQuery(COM_QUERY, query, nil)(&err)
+
 	if err := c.writeCommandStr(COM_QUERY, query); err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	var err error
 	var result *Result
+	statements := newStatementCounter(query)
 
 	bs := utils.ByteSliceGet(16)
 	defer utils.ByteSlicePut(bs)
@@ -315,7 +775,7 @@ func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCall
 	for {
 		bs.B, err = c.ReadPacketReuseMem(bs.B[:0])
 		if err != nil {
-			return nil, errors.Trace(err)
+			return nil, errors.Annotatef(err, "statement %d", statements.current())
 		}
 
 		switch bs.B[0] {
@@ -330,6 +790,11 @@ func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCall
 		default:
 			result, err = c.readResultset(bs.B, false)
 		}
+		if err != nil {
+			err = errors.Annotatef(err, "statement %d", statements.current())
+		} else {
+			statements.add(result)
+		}
 		// call user-defined callback
 		perResultCallback(result, err)
 
@@ -349,11 +814,74 @@ func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCall
 	}}, nil
 }
 
+// ExecuteAll executes query, which may hold several statements, and returns the results of all
+// of them. The statements after the first need the CLIENT_MULTI_STATEMENTS capability, see
+// SetCapability. When a statement fails the server doesn't run the next ones, the results of
+// the previous ones are released and its error, annotated with the number of the statement
+// from 1, is returned. Use errors.As to get the *mysql.MyError of the server.
+func (c *Conn) ExecuteAll(query string) (_ []*Result, err error) {
+	defer c.observeQuery(COM_QUERY, query, nil)(&err)
+
+	if err := c.writeCommandStr(COM_QUERY, query); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var results []*Result
+	statements := newStatementCounter(query)
+	for {
+		r, err := c.readResult(false)
+		if err != nil {
+			for _, r := range results {
+				r.Close()
+			}
+			return nil, errors.Annotatef(err, "statement %d", statements.current())
+		}
+		results = append(results, r)
+		statements.add(r)
+
+		if r.Status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			return results, nil
+		}
+	}
+}
+
+// CallProcedure calls the stored procedure name, used in the CALL statement as is, with args
+// bound to its parameters, and returns all the results the server sends: one for each resultset
+// of the procedure, then the final status result.
+//
+// CALL is executed as a prepared statement, so the values of the OUT and INOUT parameters, whose
+// args are usually nil, are sent in a resultset of their own just before the status result. Its
+// Status has SERVER_PS_OUT_PARAMS set.
+//
+// It needs the CLIENT_PS_MULTI_RESULTS capability, see SetCapability, and returns an error
+// before sending anything without it: the server can't send the resultsets and OUT parameters.
+func (c *Conn) CallProcedure(name string, args ...interface{}) ([]*Result, error) {
+	if c.clientCapability&CLIENT_PS_MULTI_RESULTS == 0 {
+		return nil, errors.Errorf("CallProcedure needs the CLIENT_PS_MULTI_RESULTS capability")
+	}
+
+	query := fmt.Sprintf("CALL %s(%s)", name, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+	s, err := c.Prepare(query)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	results, err := s.executeAll(args...)
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results, nil
+}
+
 // ExecuteSelectStreaming will call perRowCallback for every row in resultset
 // WITHOUT saving any row data to Result.{Values/RawPkg/RowDatas} fields.
 // When given, perResultCallback will be called once per result
 //
 // ExecuteSelectStreaming should be used only for SELECT queries with a large response resultset for memory preserving.
+// When args are given, the query is executed as a prepared statement.
 //
 // Example:
 //
@@ -363,7 +891,21 @@ func (c *Conn) ExecuteMultiple(query string, perResultCallback ExecPerResultCall
 // // You must not save FieldValue.AsString() value after this callback is done. Copy it if you need.
 // return nil
 // }, nil)
-func (c *Conn) ExecuteSelectStreaming(command string, result *Result, perRowCallback SelectPerRowCallback, perResultCallback SelectPerResultCallback) error {
+func (c *Conn) ExecuteSelectStreaming(command string, result *Result, perRowCallback SelectPerRowCallback, perResultCallback SelectPerResultCallback, args ...interface{}) (err error) {
+	if len(args) > 0 {
+		s, err := c.Prepare(command)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		err = s.ExecuteSelectStreaming(result, perRowCallback, perResultCallback, args...)
+		if cerr := s.Close(); cerr != nil && err == nil {
+			err = errors.Trace(cerr)
+		}
+		return err
+	}
+
+	defer c.observeQuery(COM_QUERY, command, nil)(&err)
+
 	if err := c.writeCommandStr(COM_QUERY, command); err != nil {
 		return errors.Trace(err)
 	}
@@ -386,30 +928,80 @@ func (c *Conn) Rollback() error {
 	return errors.Trace(err)
 }
 
+var (
+	// ErrNoSuchConnection is returned by KillConnection and KillQuery when the id is unknown to the server
+	ErrNoSuchConnection = errors.New("no such connection")
+	// ErrKillDenied is returned by KillConnection and KillQuery when the user may not kill the connection
+	ErrKillDenied = errors.New("not allowed to kill the connection")
+)
+
+// KillConnection terminates the connection with the id, as returned by GetConnectionID.
+func (c *Conn) KillConnection(id uint32) error {
+	return c.kill("CONNECTION", id)
+}
+
+// KillQuery terminates the statement the connection with the id is executing, leaving the connection open.
+func (c *Conn) KillQuery(id uint32) error {
+	return c.kill("QUERY", id)
+}
+
+func (c *Conn) kill(kind string, id uint32) error {
+	_, err := c.exec(fmt.Sprintf("KILL %s %d", kind, id))
+	if err == nil {
+		return nil
+	}
+
+	if myErr, ok := errors.Cause(err).(*MyError); ok {
+		switch myErr.Code {
+		case ER_NO_SUCH_THREAD:
+			return fmt.Errorf("%w: %w", ErrNoSuchConnection, myErr)
+		case ER_KILL_DENIED_ERROR:
+			return fmt.Errorf("%w: %w", ErrKillDenied, myErr)
+		}
+	}
+	return errors.Trace(err)
+}
+
 func (c *Conn) SetAttributes(attributes map[string]string) {
 	for k, v := range attributes {
 		c.attributes[k] = v
 	}
 }
 
-func (c *Conn) SetCharset(charset string) error {
-	if c.charset == charset {
+// SetCharset sets the charset of the connection. Before the connection is established,
+// e.g. in an Option, it is only checked and then set once connected, unless a collation
+// was set too.
+func (c *Conn) SetCharset(name string) error {
+	if c.charset == name {
 		return nil
 	}
 
-	if _, err := c.exec(fmt.Sprintf("SET NAMES %s", charset)); err != nil {
-		return errors.Trace(err)
-	} else {
-		c.charset = charset
+	if len(c.serverVersion) == 0 {
+		// the charsets TiDB doesn't support are returned with an error too
+		if cs, _ := charset.GetCharsetInfo(name); cs == nil {
+			return errors.Errorf("unknown charset %s", name)
+		}
+		c.charset = name
 		return nil
 	}
+
+	if _, err := c.exec(fmt.Sprintf("SET NAMES %s", name)); err != nil {
+		return errors.Trace(err)
+	}
+	c.charset = name
+	return errors.Trace(c.setResultsCharset())
 }
 
+// SetCollation sets the collation sent in the auth handshake, the ones with an id over 255 are set
+// with SET NAMES once connected. An unknown collation is an error listing the close known ones.
 func (c *Conn) SetCollation(collation string) error {
 	if len(c.serverVersion) != 0 {
 		return errors.Trace(errors.Errorf("cannot set collation after connection is established"))
 	}
 
+	if _, err := charset.GetCollationByName(collation); err != nil {
+		return unknownCollationError(collation)
+	}
 	c.collation = collation
 	return nil
 }
@@ -418,7 +1010,9 @@ func (c *Conn) GetCollation() string {
 	return c.collation
 }
 
-func (c *Conn) FieldList(table string, wildcard string) ([]*Field, error) {
+func (c *Conn) FieldList(table string, wildcard string) (_ []*Field, err error) {
+	defer c.observe(COM_FIELD_LIST)(&err)
+
 	if err := c.writeCommandStrStr(COM_FIELD_LIST, table, wildcard); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -448,15 +1042,75 @@ func (c *Conn) FieldList(table string, wildcard string) ([]*Field, error) {
 	}
 }
 
-func (c *Conn) SetAutoCommit() error {
-	if !c.IsAutoCommit() {
-		if _, err := c.exec("SET AUTOCOMMIT = 1"); err != nil {
+// SetAutoCommit turns the autocommit mode of the session on or off, if it isn't already.
+// IsAutoCommit returns the current mode.
+func (c *Conn) SetAutoCommit(autoCommit bool) error {
+	if c.IsAutoCommit() == autoCommit {
+		return nil
+	}
+
+	query := "SET AUTOCOMMIT = 0"
+	if autoCommit {
+		query = "SET AUTOCOMMIT = 1"
+	}
+	if _, err := c.exec(query); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ResetSession rolls back the open transaction, if any, and restores the autocommit mode the
+// session had when the connection was opened, so the next user of a pooled connection doesn't
+// inherit them. It doesn't send anything if the session is clean.
+func (c *Conn) ResetSession() error {
+	if c.InTransaction() {
+		if err := c.Rollback(); err != nil {
 			return errors.Trace(err)
 		}
 	}
+	return errors.Trace(c.SetAutoCommit(c.defaultAutoCommit))
+}
+
+// ResetConnection resets the session with COM_RESET_CONNECTION, like a new connection but
+// without authenticating again: the transaction is rolled back, the session variables are
+// restored and the user variables, temporary tables and prepared statements are dropped.
+// It needs MySQL 5.7.3+ or MariaDB 10.2.4+. The session is back on the charset and collation
+// of the handshake, the ones of the connection are set again with SET NAMES if they differ,
+// and character_set_results again to NULL with TranscodeResults.
+func (c *Conn) ResetConnection() error {
+	if err := c.resetConnection(); err != nil {
+		return err
+	}
+	if err := c.setNames(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.setResultsCharset())
+}
+
+func (c *Conn) resetConnection() (err error) {
+	defer c.observe(COM_RESET_CONNECTION)(&err)
+
+	if err := c.writeCommand(COM_RESET_CONNECTION); err != nil {
+		return errors.Trace(err)
+	}
+
+	// the tracking may be turned off by the reset, its OK packet reports the new state
+	c.trxState, c.trxCharacteristics = "", ""
+	// the session variables are back to the global values
+	c.serverTimeZone, c.sqlMode = nil, nil
+	if _, err := c.readOK(); err != nil {
+		return errors.Trace(err)
+	}
 	return nil
 }
 
+// NoBackslashEscapes reports whether the sql_mode of the session has NO_BACKSLASH_ESCAPES, in
+// which a backslash is a character of the string literals rather than an escape, as told by
+// the server in the status of the last response.
+func (c *Conn) NoBackslashEscapes() bool {
+	return c.status&SERVER_STATUS_NO_BACKSLASH_ESCAPED > 0
+}
+
 func (c *Conn) IsAutoCommit() bool {
 	return c.status&SERVER_STATUS_AUTOCOMMIT > 0
 }
@@ -465,6 +1119,22 @@ func (c *Conn) IsInTransaction() bool {
 	return c.status&SERVER_STATUS_IN_TRANS > 0
 }
 
+// InTransaction reports whether a transaction is open, explicit or implicit. It checks the
+// SERVER_STATUS_IN_TRANS status, like IsInTransaction, and the transaction state tracked by
+// the server when the connection has the CLIENT_SESSION_TRACK capability and
+// session_track_transaction_info is STATE or CHARACTERISTICS. The state is only sent in OK
+// packets, so it may be older than the status after a resultset ended by an EOF packet.
+func (c *Conn) InTransaction() bool {
+	return c.IsInTransaction() || (c.trxState != "" && c.trxState[0] != '_')
+}
+
+// TransactionCharacteristics returns the statements that would start a transaction like the
+// open one, e.g. "START TRANSACTION READ ONLY;", as tracked by the server when
+// session_track_transaction_info is CHARACTERISTICS. It is empty without tracking.
+func (c *Conn) TransactionCharacteristics() string {
+	return c.trxCharacteristics
+}
+
 func (c *Conn) GetCharset() string {
 	return c.charset
 }
@@ -486,7 +1156,9 @@ func (c *Conn) ReadOKPacket() (*Result, error) {
 	return c.readOK()
 }
 
-func (c *Conn) exec(query string) (*Result, error) {
+func (c *Conn) exec(query string) (_ *Result, err error) {
+	defer c.observeQuery(COM_QUERY, query, nil)(&err)
+
 	if err := c.writeCommandStr(COM_QUERY, query); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -494,9 +1166,53 @@ func (c *Conn) exec(query string) (*Result, error) {
 	return c.readResult(false)
 }
 
+// HandshakeInfo describes what was negotiated in the handshake of a connection.
+type HandshakeInfo struct {
+	ProtocolVersion byte
+	ServerVersion   string
+	ConnectionID    uint32
+
+	// ServerCapabilities are the capability flags advertised by the server, ClientCapabilities
+	// the ones sent back by the client. Only the flags in both are in effect.
+	ServerCapabilities uint32
+	ClientCapabilities uint32
+	// Capabilities are the names of the flags in effect, like CLIENT_DEPRECATE_EOF
+	Capabilities []string
+
+	AuthPluginName string
+
+	// TLS is nil if the connection is not encrypted
+	TLS *tls.ConnectionState
+}
+
+// HandshakeInfo returns what was negotiated with the server in the handshake, for debugging.
+func (c *Conn) HandshakeInfo() HandshakeInfo {
+	info := HandshakeInfo{
+		ProtocolVersion:    c.protocolVersion,
+		ServerVersion:      c.serverVersion,
+		ConnectionID:       c.connectionID,
+		ServerCapabilities: c.capability,
+		ClientCapabilities: c.clientCapability,
+		Capabilities:       capabilityNames(c.capability & c.clientCapability),
+		AuthPluginName:     c.authPluginName,
+	}
+
+	if c.Conn != nil {
+		if tlsConn, ok := c.Conn.Conn.(*tls.Conn); ok {
+			state := tlsConn.ConnectionState()
+			info.TLS = &state
+		}
+	}
+
+	return info
+}
+
 func (c *Conn) CapabilityString() string {
+	return strings.Join(capabilityNames(c.capability), "|")
+}
+
+func capabilityNames(capability uint32) []string {
 	var caps []string
-	capability := c.capability
 	for i := 0; capability != 0; i++ {
 		field := uint32(1 << i)
 		if capability&field == 0 {
@@ -574,7 +1290,7 @@ func (c *Conn) CapabilityString() string {
 		}
 	}
 
-	return strings.Join(caps, "|")
+	return caps
 }
 
 func (c *Conn) StatusString() string {
@@ -1,14 +1,22 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/packet"
 	"github.com/go-mysql-org/go-mysql/test_util"
 )
 
@@ -189,8 +197,1559 @@ func (s *connTestSuite) TestExecuteSelectStreaming() {
 	require.Equal(s.T(), 1, perResultCallbackCalledTimes)
 }
 
+func (s *connTestSuite) TestExecuteSelectStreamingWithArgs() {
+	var (
+		ids    []int64
+		result mysql.Result
+	)
+
+	err := s.c.ExecuteSelectStreaming(`SELECT id FROM `+testExecuteSelectStreamingTablename+` WHERE id > ? ORDER BY id`,
+		&result,
+		func(row []mysql.FieldValue) error {
+			ids = append(ids, row[0].AsInt64())
+			return nil
+		}, nil, 2)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []int64{3, 4}, ids)
+
+	// stopping early must leave the connection usable
+	stop := errors.New("stop")
+	err = s.c.ExecuteSelectStreaming(`SELECT id FROM `+testExecuteSelectStreamingTablename,
+		&result,
+		func(row []mysql.FieldValue) error {
+			return stop
+		}, nil)
+	require.ErrorIs(s.T(), err, stop)
+
+	_, err = s.c.Execute("SELECT 1")
+	require.NoError(s.T(), err)
+}
+
+func (s *connTestSuite) TestQueryIter() {
+	it, err := s.c.QueryIter(`SELECT id, str FROM ` + testExecuteSelectStreamingTablename + ` ORDER BY id`)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), it.Fields(), 2)
+
+	var expectedRowId int64
+	for it.Next() {
+		var (
+			id  int64
+			str string
+		)
+		require.NoError(s.T(), it.Scan(&id, &str))
+		require.Equal(s.T(), expectedRowId, id)
+		require.Equal(s.T(), testExecuteSelectStreamingRows[id], str)
+		expectedRowId++
+	}
+	require.NoError(s.T(), it.Err())
+	require.NoError(s.T(), it.Close())
+	require.Equal(s.T(), int64(len(testExecuteSelectStreamingRows)), expectedRowId)
+
+	// closing before reading all rows must leave the connection usable
+	it, err = s.c.QueryIter(`SELECT id, str FROM `+testExecuteSelectStreamingTablename+` WHERE id > ? ORDER BY id`, 1)
+	require.NoError(s.T(), err)
+	require.True(s.T(), it.Next())
+	require.NoError(s.T(), it.Close())
+
+	// a statement is left open by its iterators
+	stmt, err := s.c.Prepare(`SELECT str FROM ` + testExecuteSelectStreamingTablename + ` WHERE id = ?`)
+	require.NoError(s.T(), err)
+	defer stmt.Close()
+	for id := range 2 {
+		it, err = stmt.QueryIter(id)
+		require.NoError(s.T(), err)
+		require.True(s.T(), it.Next())
+		require.Equal(s.T(), testExecuteSelectStreamingRows[id], string(it.Row()[0].AsString()))
+		require.NoError(s.T(), it.Close())
+	}
+
+	_, err = s.c.Execute("SELECT 1")
+	require.NoError(s.T(), err)
+}
+
+func (s *connTestSuite) TestConnectAny() {
+	// nothing listens on port 1, so the second address must be used
+	addr := fmt.Sprintf("%s:%s", *test_util.MysqlHost, s.port)
+	c, connected, err := ConnectAny("127.0.0.1:1, "+addr, *testUser, *testPassword, "")
+	require.NoError(s.T(), err)
+	defer c.Close()
+	require.Equal(s.T(), addr, connected)
+	require.NoError(s.T(), c.Ping())
+}
+
+func TestConnectAnyNoneReachable(t *testing.T) {
+	_, _, err := ConnectAny("127.0.0.1:1,127.0.0.1:2", "root", "", "")
+	require.ErrorContains(t, err, "127.0.0.1:1")
+	require.ErrorContains(t, err, "127.0.0.1:2")
+
+	_, _, err = ConnectAny(" , ", "root", "", "")
+	require.Error(t, err)
+}
+
+func (s *connTestSuite) TestQueryScalar() {
+	n, err := s.c.QueryInt(`SELECT COUNT(*) FROM ` + testExecuteSelectStreamingTablename)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(len(testExecuteSelectStreamingRows)), n)
+
+	str, err := s.c.QueryString(`SELECT str FROM `+testExecuteSelectStreamingTablename+` WHERE id = ?`, 1)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), testExecuteSelectStreamingRows[1], str)
+
+	v, err := s.c.QueryScalar(`SELECT NULL`)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), v)
+
+	_, err = s.c.QueryScalar(`SELECT 1, 2`)
+	require.Error(s.T(), err)
+
+	_, err = s.c.QueryScalar(`SELECT id FROM ` + testExecuteSelectStreamingTablename)
+	require.Error(s.T(), err)
+}
+
 func (s *connTestSuite) TestAttributes() {
 	// Test that both custom attributes and library set attributes are visible
 	require.Equal(s.T(), "go-mysql", s.c.attributes["_client_name"])
 	require.Equal(s.T(), "attrvalue", s.c.attributes["attrtest"])
 }
+
+func TestParseServerTimeZone(t *testing.T) {
+	loc, err := parseServerTimeZone("SYSTEM", "UTC", "00:00:00")
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, loc)
+
+	loc, err = parseServerTimeZone("+08:00", "UTC", "08:00:00")
+	require.NoError(t, err)
+	_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	require.Equal(t, 8*3600, offset)
+
+	loc, err = parseServerTimeZone("-05:30", "UTC", "-05:30:00")
+	require.NoError(t, err)
+	_, offset = time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	require.Equal(t, -(5*3600 + 30*60), offset)
+
+	// an abbreviation is the offset of the server
+	loc, err = parseServerTimeZone("SYSTEM", "CST", "-06:00:00")
+	require.NoError(t, err)
+	name, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	require.Equal(t, "CST", name)
+	require.Equal(t, -6*3600, offset)
+
+	_, err = parseServerTimeZone("+xx", "UTC", "00:00:00")
+	require.Error(t, err)
+
+	_, err = parseServerTimeZone("SYSTEM", "Not/AZone", "")
+	require.Error(t, err)
+}
+
+func TestLoadSessionVariables(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	defer c.Close()
+
+	var queries int
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			query, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			queries++
+			var values []string
+			switch {
+			case strings.Contains(string(query), "@@sql_mode"):
+				values = []string{"STRICT_TRANS_TABLES"}
+			case strings.Contains(string(query), "@@time_zone"):
+				values = []string{"SYSTEM", "CEST", "02:00:00"}
+			}
+			packets := [][]byte{{byte(len(values))}}
+			row := []byte{}
+			for _, v := range values {
+				packets = append(packets, (&mysql.Field{Name: []byte("v"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 33}).Dump())
+				row = append(row, byte(len(v)))
+				row = append(row, v...)
+			}
+			packets = append(packets, []byte{mysql.EOF_HEADER, 0, 0, 2, 0}, row, []byte{mysql.EOF_HEADER, 0, 0, 2, 0})
+			for _, p := range packets {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	sqlMode, err := c.SQLMode()
+	require.NoError(t, err)
+	require.Equal(t, "STRICT_TRANS_TABLES", sqlMode)
+	loc, err := c.ServerTimeZone()
+	require.NoError(t, err)
+	name, offset := time.Date(2024, 7, 1, 0, 0, 0, 0, loc).Zone()
+	require.Equal(t, "CEST", name)
+	require.Equal(t, 2*3600, offset)
+	require.Equal(t, 2, queries)
+
+	// the values are queried once
+	sqlMode, err = c.SQLMode()
+	require.NoError(t, err)
+	require.Equal(t, "STRICT_TRANS_TABLES", sqlMode)
+	_, err = c.ServerTimeZone()
+	require.NoError(t, err)
+	require.Equal(t, 2, queries)
+}
+
+func (s *connTestSuite) TestSessionVariables() {
+	loc, err := s.c.ServerTimeZone()
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), loc)
+
+	_, err = s.c.SQLMode()
+	require.NoError(s.T(), err)
+}
+
+func (s *connTestSuite) TestResetConnectionCharset() {
+	addr := fmt.Sprintf("%s:%s", *test_util.MysqlHost, s.port)
+	c, err := Connect(addr, *testUser, *testPassword, "", func(c *Conn) error {
+		return c.SetCharset("utf8mb4")
+	})
+	require.NoError(s.T(), err)
+	defer c.Close()
+
+	require.NoError(s.T(), c.ResetConnection())
+	cs, err := c.QueryString("SELECT @@character_set_client")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "utf8mb4", cs)
+}
+
+func TestResetConnectionSetNames(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	var commands []string
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			commands = append(commands, fmt.Sprintf("%d %s", data[0], data[1:]))
+			if err = sc.WritePacket([]byte{0, 0, 0, 0, mysql.OK_HEADER, 0, 0, 0, 0, 0, 0}); err != nil {
+				return
+			}
+		}
+	}()
+
+	tests := []struct {
+		charset, collation string
+		transcode          bool
+		setNames           string
+	}{
+		{charset: mysql.DEFAULT_CHARSET},
+		{charset: "utf8mb4", setNames: "SET NAMES utf8mb4"},
+		{charset: "latin1", collation: "latin1_swedish_ci"},
+		{charset: "utf8mb4", collation: "utf8mb4_0900_as_cs", setNames: "SET NAMES utf8mb4 COLLATE utf8mb4_0900_as_cs"},
+		// set by SetCharset once connected
+		{charset: "utf8mb4", collation: "latin1_swedish_ci", setNames: "SET NAMES utf8mb4"},
+		{charset: "utf8mb4", setNames: "SET NAMES utf8mb4", transcode: true},
+		{charset: mysql.DEFAULT_CHARSET, transcode: true},
+	}
+	for _, test := range tests {
+		commands = nil
+		c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41, charset: test.charset, collation: test.collation, TranscodeResults: test.transcode}
+		require.NoError(t, c.ResetConnection())
+		want := []string{fmt.Sprintf("%d ", mysql.COM_RESET_CONNECTION)}
+		if test.setNames != "" {
+			want = append(want, fmt.Sprintf("%d %s", mysql.COM_QUERY, test.setNames))
+		}
+		if test.transcode {
+			want = append(want, fmt.Sprintf("%d SET character_set_results = NULL", mysql.COM_QUERY))
+		}
+		require.Equal(t, want, commands, test)
+	}
+	client.Close()
+}
+
+func TestConnCheckFieldSize(t *testing.T) {
+	fields := []*mysql.Field{{Name: []byte("id")}, {Name: []byte("payload")}}
+	row := append(mysql.PutLengthEncodedString([]byte("1")), mysql.PutLengthEncodedString([]byte(strings.Repeat("x", 100)))...)
+
+	// the value is checked before it is read, it isn't kept
+	w := &chunkedRowWriter{fields: fields, maxFieldSize: 50}
+	w.reset()
+	_, err := w.Write(row)
+	require.NoError(t, err)
+	require.ErrorIs(t, w.err, mysql.ErrFieldTooLarge)
+	require.ErrorContains(t, w.err, "payload")
+	require.Less(t, len(w.row), 50)
+
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			if _, err := sc.ReadPacket(); err != nil {
+				return
+			}
+			for _, p := range [][]byte{
+				{2},
+				(&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 33}).Dump(),
+				(&mysql.Field{Name: []byte("payload"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 33}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+				row,
+				row,
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			} {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	r, err := c.Execute("SELECT id, payload FROM t")
+	require.NoError(t, err)
+	require.Equal(t, 2, r.RowNumber())
+
+	c.MaxFieldSize = 200
+	_, err = c.Execute("SELECT id, payload FROM t")
+	require.NoError(t, err)
+
+	// the remaining rows are skipped, the connection can still be used
+	c.MaxFieldSize = 50
+	_, err = c.Execute("SELECT id, payload FROM t")
+	require.ErrorIs(t, err, mysql.ErrFieldTooLarge)
+	require.ErrorContains(t, err, "payload")
+
+	var result mysql.Result
+	err = c.ExecuteSelectStreaming("SELECT id, payload FROM t", &result, func(row []mysql.FieldValue) error {
+		return nil
+	}, nil)
+	require.ErrorIs(t, err, mysql.ErrFieldTooLarge)
+
+	it, err := c.QueryIter("SELECT id, payload FROM t")
+	require.NoError(t, err)
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Err(), mysql.ErrFieldTooLarge)
+	require.ErrorIs(t, it.Close(), mysql.ErrFieldTooLarge)
+
+	c.MaxFieldSize = 0
+	r, err = c.Execute("SELECT id, payload FROM t")
+	require.NoError(t, err)
+	require.Equal(t, 2, r.RowNumber())
+}
+
+func (s *connTestSuite) TestWithContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.c.WithContext(ctx).Execute(`SELECT SLEEP(10)`)
+	require.ErrorIs(s.T(), err, context.DeadlineExceeded)
+	require.Less(s.T(), time.Since(start), 5*time.Second)
+
+	// the query was killed, the connection is still usable
+	require.NoError(s.T(), s.c.Ping())
+
+	r, err := s.c.WithContext(context.Background()).Execute(`SELECT 1`)
+	require.NoError(s.T(), err)
+	r.Close()
+}
+
+func TestWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &Conn{}
+	_, err := c.WithContext(ctx).Execute(`SELECT 1`)
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, c.WithContext(ctx).Begin(), context.Canceled)
+}
+
+func (s *connTestSuite) TestKill() {
+	err := s.c.KillQuery(1 << 31)
+	require.ErrorIs(s.T(), err, ErrNoSuchConnection)
+	var myErr *mysql.MyError
+	require.ErrorAs(s.T(), err, &myErr)
+	require.Equal(s.T(), uint16(mysql.ER_NO_SUCH_THREAD), myErr.Code)
+
+	c, err := Connect(*test_util.MysqlHost+":"+s.port, *testUser, *testPassword, "")
+	require.NoError(s.T(), err)
+	defer c.Close()
+
+	require.NoError(s.T(), s.c.KillConnection(c.GetConnectionID()))
+	require.Error(s.T(), c.Ping())
+}
+
+func (s *connTestSuite) TestClone() {
+	c, err := s.c.Clone()
+	require.NoError(s.T(), err)
+	defer c.Close()
+
+	require.NotEqual(s.T(), s.c.GetConnectionID(), c.GetConnectionID())
+	// the options of SetupSuite were applied again
+	require.Equal(s.T(), "attrvalue", c.attributes["attrtest"])
+}
+
+func TestConnClone(t *testing.T) {
+	_, err := (&Conn{}).Clone()
+	require.ErrorContains(t, err, "not opened by Connect")
+
+	var network, addr string
+	c := &Conn{
+		proto: "unix",
+		addr:  "/tmp/mysql.sock",
+		dialer: func(ctx context.Context, n, a string) (net.Conn, error) {
+			network, addr = n, a
+			return nil, errors.New("refused")
+		},
+	}
+	_, err = c.Clone()
+	require.ErrorContains(t, err, "refused")
+	require.Equal(t, "unix", network)
+	require.Equal(t, "/tmp/mysql.sock", addr)
+}
+
+// serveOK acts as a MySQL server on c accepting any user and answering each command with an
+// OK packet, the queries are sent to queries.
+func serveOK(c net.Conn, queries chan<- string) {
+	defer c.Close()
+	sc := packet.NewConn(c)
+	capability := mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SECURE_CONNECTION | mysql.CLIENT_PLUGIN_AUTH
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.0\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, "01234567\x00"...)
+	handshake = append(handshake, byte(capability), byte(capability>>8), mysql.DEFAULT_COLLATION_ID, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0)
+	handshake = append(handshake, byte(capability>>16), byte(capability>>24), 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "890123456789\x00"...)
+	handshake = append(handshake, mysql.AUTH_NATIVE_PASSWORD+"\x00"...)
+	if sc.WritePacket(append(make([]byte, 4), handshake...)) != nil {
+		return
+	}
+	for first := true; ; first = false {
+		data, err := sc.ReadPacket()
+		if err != nil {
+			return
+		}
+		if !first && data[0] == mysql.COM_QUERY {
+			queries <- string(data[1:])
+		}
+		if sc.WritePacket([]byte{0, 0, 0, 0, mysql.OK_HEADER, 0, 0, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0, 0, 0}) != nil {
+			return
+		}
+		sc.ResetSequence()
+	}
+}
+
+func TestKillQueryDialer(t *testing.T) {
+	queries := make(chan string, 10)
+	var dialed []string
+	var applied int
+	c := &Conn{
+		proto:        "tcp",
+		addr:         "mysql.internal:3306",
+		user:         "root",
+		connectionID: 42,
+		// e.g. a tunnel, the address can't be dialed directly
+		dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, network+" "+addr)
+			server, client := net.Pipe()
+			go serveOK(server, queries)
+			return client, nil
+		},
+		options: []Option{func(*Conn) error {
+			applied++
+			return nil
+		}},
+	}
+
+	// the statement is killed from a connection opened like c
+	require.NoError(t, c.killQuery())
+	require.Equal(t, []string{"tcp mysql.internal:3306"}, dialed)
+	require.Equal(t, 1, applied)
+	close(queries)
+	var kills []string
+	for q := range queries {
+		if strings.HasPrefix(q, "KILL") {
+			kills = append(kills, q)
+		}
+	}
+	require.Equal(t, []string{"KILL QUERY 42"}, kills)
+}
+
+func TestScanUnsignedOverflow(t *testing.T) {
+	fv := mysql.NewFieldValue(mysql.FieldValueTypeUnsigned, math.MaxUint64, nil)
+
+	var u uint64
+	require.NoError(t, scanFieldValue(&u, &fv))
+	require.Equal(t, uint64(math.MaxUint64), u)
+
+	var i int64
+	require.ErrorContains(t, scanFieldValue(&i, &fv), "overflows")
+
+	fv = mysql.NewFieldValue(mysql.FieldValueTypeUnsigned, math.MaxInt64, nil)
+	require.NoError(t, scanFieldValue(&i, &fv))
+	require.Equal(t, int64(math.MaxInt64), i)
+}
+
+func TestConnMetricsCallback(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	var metrics []CommandMetrics
+	c := &Conn{
+		MetricsCallback: func(m CommandMetrics) { metrics = append(metrics, m) },
+		counter:         &countingConn{Conn: client},
+	}
+	c.Conn = packet.NewConn(c.counter)
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			resp := []byte{0, 0, 0, 0, mysql.OK_HEADER, 0, 0, 0, 0}
+			if data[0] == mysql.COM_INIT_DB {
+				resp = []byte{0, 0, 0, 0, mysql.ERR_HEADER, 0x19, 0x04}
+				resp = append(resp, "unknown database"...)
+			}
+			if err = sc.WritePacket(resp); err != nil {
+				return
+			}
+		}
+	}()
+
+	require.NoError(t, c.Ping())
+	require.Error(t, c.UseDB("test"))
+
+	require.Len(t, metrics, 2)
+	require.Equal(t, byte(mysql.COM_PING), metrics[0].Command)
+	require.Equal(t, uint64(5), metrics[0].BytesWritten)
+	require.Equal(t, uint64(9), metrics[0].BytesRead)
+	require.NoError(t, metrics[0].Err)
+
+	require.Equal(t, byte(mysql.COM_INIT_DB), metrics[1].Command)
+	require.Equal(t, uint64(4+1+4), metrics[1].BytesWritten)
+	require.Equal(t, uint64(4+3+16), metrics[1].BytesRead)
+	require.Error(t, metrics[1].Err)
+}
+
+func TestConnOnDisconnect(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	disconnected := make(chan error, 1)
+	c := &Conn{
+		Conn:         packet.NewConn(client),
+		OnDisconnect: func(err error) { disconnected <- err },
+	}
+	c.startWatch()
+
+	sc := packet.NewConn(server)
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if _, err := sc.ReadPacket(); err == nil {
+			_ = sc.WritePacket([]byte{0, 0, 0, 0, mysql.OK_HEADER, 0, 0, 0, 0})
+		}
+	}()
+
+	// commands stop the background reader
+	require.NoError(t, c.Ping())
+	require.Empty(t, disconnected)
+	<-served
+
+	// like the server does when wait_timeout passes
+	sc.ResetSequence()
+	errPacket := append([]byte{0, 0, 0, 0, mysql.ERR_HEADER, 0xbf, 0x0f}, "disconnected by the server"...)
+	require.NoError(t, sc.WritePacket(errPacket))
+	require.NoError(t, server.Close())
+
+	select {
+	case err := <-disconnected:
+		require.ErrorIs(t, err, mysql.ErrBadConn)
+		require.ErrorContains(t, err, "disconnected by the server")
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnection not detected")
+	}
+
+	require.Error(t, c.Ping())
+	require.NoError(t, c.Close())
+}
+
+func TestStmtMetadata(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		if _, err := sc.ReadPacket(); err != nil {
+			return
+		}
+
+		// statement 1 with 2 columns and 1 parameter
+		packets := [][]byte{{mysql.OK_HEADER, 1, 0, 0, 0, 2, 0, 1, 0, 0, 0, 0}}
+		packets = append(packets, (&mysql.Field{Name: []byte("?"), Type: mysql.MYSQL_TYPE_VAR_STRING}).Dump())
+		packets = append(packets, []byte{mysql.EOF_HEADER, 0, 0, 2, 0})
+		packets = append(packets, (&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_LONGLONG, Flag: mysql.UNSIGNED_FLAG}).Dump())
+		packets = append(packets, (&mysql.Field{Name: []byte("name"), Type: mysql.MYSQL_TYPE_VARCHAR}).Dump())
+		packets = append(packets, []byte{mysql.EOF_HEADER, 0, 0, 2, 0})
+		for _, p := range packets {
+			if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	s, err := c.Prepare("SELECT id, name FROM t WHERE id = ?")
+	require.NoError(t, err)
+	require.Equal(t, 1, s.ParamNum())
+	require.Equal(t, 2, s.ColumnNum())
+
+	require.Len(t, s.Params(), 1)
+	require.Equal(t, mysql.MYSQL_TYPE_VAR_STRING, s.Params()[0].Type)
+
+	require.Len(t, s.Columns(), 2)
+	require.Equal(t, "id", string(s.Columns()[0].Name))
+	require.Equal(t, mysql.MYSQL_TYPE_LONGLONG, s.Columns()[0].Type)
+	require.NotZero(t, s.Columns()[0].Flag&mysql.UNSIGNED_FLAG)
+	require.Equal(t, "name", string(s.Columns()[1].Name))
+}
+
+func TestConnSetAutoCommit(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{
+		Conn:              packet.NewConn(client),
+		capability:        mysql.CLIENT_PROTOCOL_41,
+		status:            mysql.SERVER_STATUS_AUTOCOMMIT,
+		defaultAutoCommit: true,
+	}
+	defer c.Close()
+
+	var queries []string
+	go func() {
+		sc := packet.NewConn(server)
+		status := uint16(mysql.SERVER_STATUS_AUTOCOMMIT)
+		for {
+			sc.ResetSequence()
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			query := string(data[1:])
+			queries = append(queries, query)
+			switch query {
+			case "SET AUTOCOMMIT = 0":
+				status &^= mysql.SERVER_STATUS_AUTOCOMMIT
+			case "SET AUTOCOMMIT = 1":
+				status |= mysql.SERVER_STATUS_AUTOCOMMIT
+			case "ROLLBACK":
+				status &^= mysql.SERVER_STATUS_IN_TRANS
+			default:
+				status |= mysql.SERVER_STATUS_IN_TRANS
+			}
+			if err = sc.WritePacket([]byte{0, 0, 0, 0, mysql.OK_HEADER, 0, 0, byte(status), byte(status >> 8), 0, 0}); err != nil {
+				return
+			}
+		}
+	}()
+
+	// already on
+	require.NoError(t, c.SetAutoCommit(true))
+	require.NoError(t, c.SetAutoCommit(false))
+	require.False(t, c.IsAutoCommit())
+	require.NoError(t, c.SetAutoCommit(false))
+
+	_, err := c.Execute("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	require.True(t, c.IsInTransaction())
+
+	require.NoError(t, c.ResetSession())
+	require.True(t, c.IsAutoCommit())
+	require.False(t, c.IsInTransaction())
+
+	// nothing to reset
+	require.NoError(t, c.ResetSession())
+
+	require.Equal(t, []string{"SET AUTOCOMMIT = 0", "INSERT INTO t VALUES (1)", "ROLLBACK", "SET AUTOCOMMIT = 1"}, queries)
+}
+
+func TestHandleOKPacketAffectedRows(t *testing.T) {
+	c := &Conn{capability: mysql.CLIENT_PROTOCOL_41}
+
+	r, err := c.handleOKPacket([]byte{mysql.OK_HEADER, 0, 0, 2, 0, 0, 0})
+	require.NoError(t, err)
+	require.True(t, r.HasAffectedRows)
+	require.Zero(t, r.AffectedRows)
+
+	r, err = c.handleOKPacket([]byte{mysql.OK_HEADER, 3, 0, 2, 0, 0, 0})
+	require.NoError(t, err)
+	require.True(t, r.HasAffectedRows)
+	require.Equal(t, uint64(3), r.AffectedRows)
+
+	// -1
+	data := append([]byte{mysql.OK_HEADER}, mysql.PutLengthEncodedInt(math.MaxUint64)...)
+	r, err = c.handleOKPacket(append(data, 0, 2, 0, 0, 0))
+	require.NoError(t, err)
+	require.False(t, r.HasAffectedRows)
+	require.Zero(t, r.AffectedRows)
+
+	// the 8 bytes values of large bulk updates and BIGINT UNSIGNED ids
+	for _, n := range []uint64{1 << 32, math.MaxInt64 + 1, math.MaxUint64 - 1} {
+		data = append([]byte{mysql.OK_HEADER}, mysql.PutLengthEncodedInt(n)...)
+		data = append(data, mysql.PutLengthEncodedInt(n)...)
+		r, err = c.handleOKPacket(append(data, 2, 0, 0, 0))
+		require.NoError(t, err)
+		require.True(t, r.HasAffectedRows)
+		require.Equal(t, n, r.AffectedRows)
+		require.Equal(t, n, r.InsertId)
+	}
+}
+
+func TestHandleOKPacketSessionTrack(t *testing.T) {
+	c := &Conn{
+		capability:       mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SESSION_TRACK,
+		clientCapability: mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SESSION_TRACK,
+	}
+	ok := func(status uint16, changes ...[]byte) []byte {
+		data := []byte{mysql.OK_HEADER, 0, 0, byte(status), byte(status >> 8), 0, 0}
+		if len(changes) > 0 {
+			// empty info
+			data = append(data, 0)
+			data = append(data, mysql.PutLengthEncodedString(bytes.Join(changes, nil))...)
+		}
+		return data
+	}
+	change := func(tp byte, value string) []byte {
+		return append([]byte{tp}, mysql.PutLengthEncodedString(mysql.PutLengthEncodedString([]byte(value)))...)
+	}
+
+	// no tracking, the status is used
+	_, err := c.handleOKPacket(ok(mysql.SERVER_STATUS_IN_TRANS))
+	require.NoError(t, err)
+	require.True(t, c.InTransaction())
+
+	// START TRANSACTION READ ONLY, along with a schema change
+	_, err = c.handleOKPacket(ok(mysql.SERVER_SESSION_STATE_CHANGED,
+		append([]byte{mysql.SESSION_TRACK_SCHEMA}, mysql.PutLengthEncodedString(mysql.PutLengthEncodedString([]byte("test")))...),
+		change(mysql.SESSION_TRACK_TRANSACTION_STATE, "T_______"),
+		change(mysql.SESSION_TRACK_TRANSACTION_CHARACTERISTICS, "START TRANSACTION READ ONLY;")))
+	require.NoError(t, err)
+	require.True(t, c.InTransaction())
+	require.False(t, c.IsInTransaction())
+	require.Equal(t, "START TRANSACTION READ ONLY;", c.TransactionCharacteristics())
+
+	// COMMIT
+	_, err = c.handleOKPacket(ok(mysql.SERVER_SESSION_STATE_CHANGED,
+		change(mysql.SESSION_TRACK_TRANSACTION_STATE, "________"),
+		change(mysql.SESSION_TRACK_TRANSACTION_CHARACTERISTICS, "")))
+	require.NoError(t, err)
+	require.False(t, c.InTransaction())
+	require.Empty(t, c.TransactionCharacteristics())
+
+	// unchanged
+	_, err = c.handleOKPacket(ok(0))
+	require.NoError(t, err)
+	require.False(t, c.InTransaction())
+
+	// a SELECT opening an implicit transaction with autocommit=0, its resultset ends with an
+	// EOF packet setting the status but not the tracked state
+	c.status = mysql.SERVER_STATUS_IN_TRANS
+	require.True(t, c.InTransaction())
+	c.status = 0
+
+	_, err = c.handleOKPacket(ok(mysql.SERVER_SESSION_STATE_CHANGED, []byte{mysql.SESSION_TRACK_TRANSACTION_STATE, 9}))
+	require.Error(t, err)
+}
+
+func TestStmtNewParamsBound(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	defer c.Close()
+	s := &Stmt{conn: c, id: 1, params: 2}
+
+	packets := make(chan []byte, 1)
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			sc.ResetSequence()
+			packets <- data
+		}
+	}()
+
+	// command, id, flags, iteration count, NULL bitmap
+	header := []byte{mysql.COM_STMT_EXECUTE, 1, 0, 0, 0, 0, 1, 0, 0, 0}
+	types := []byte{mysql.MYSQL_TYPE_LONGLONG, 0, mysql.MYSQL_TYPE_STRING, 0}
+	for _, tt := range []struct {
+		args   []interface{}
+		packet [][]byte
+	}{
+		{[]interface{}{int64(1), "a"}, [][]byte{header, {0, 1}, types, {1, 0, 0, 0, 0, 0, 0, 0, 1, 'a'}}},
+		// same types
+		{[]interface{}{int64(2), "b"}, [][]byte{header, {0, 0}, {2, 0, 0, 0, 0, 0, 0, 0, 1, 'b'}}},
+		// NULL fits the bound type
+		{[]interface{}{nil, "c"}, [][]byte{header, {1, 0}, {1, 'c'}}},
+		// new types
+		{[]interface{}{int64(4), int64(5)}, [][]byte{header, {0, 1}, {mysql.MYSQL_TYPE_LONGLONG, 0, mysql.MYSQL_TYPE_LONGLONG, 0},
+			{4, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0, 0, 0, 0, 0, 0}}},
+	} {
+		require.NoError(t, s.write(tt.args...))
+		require.Equal(t, bytes.Join(tt.packet, nil), <-packets)
+	}
+}
+
+func TestWriteCommandNotSent(t *testing.T) {
+	for _, compression := range []uint8{mysql.MYSQL_COMPRESS_NONE, mysql.MYSQL_COMPRESS_ZLIB, mysql.MYSQL_COMPRESS_ZSTD} {
+		server, client := net.Pipe()
+		require.NoError(t, server.Close())
+
+		c := &Conn{Conn: packet.NewConn(client)}
+		c.Conn.Compression = compression
+		err := c.writeCommandStr(mysql.COM_QUERY, strings.Repeat("select 1;", 10))
+		require.ErrorIs(t, err, mysql.ErrNotSent, "compression %d", compression)
+		require.NoError(t, client.Close())
+	}
+}
+
+func TestConnCloseTwice(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	s := &Stmt{conn: c, id: 1}
+
+	packets := make(chan []byte, 2)
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			sc.ResetSequence()
+			packets <- data
+		}
+	}()
+
+	require.NoError(t, s.Close())
+	require.Equal(t, []byte{mysql.COM_STMT_CLOSE, 1, 0, 0, 0}, <-packets)
+	// the second call sends nothing
+	require.NoError(t, s.Close())
+
+	s2 := &Stmt{conn: c, id: 2}
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Quit())
+	// the statements are gone with the connection
+	require.NoError(t, s2.Close())
+	require.Empty(t, packets)
+}
+
+func TestCallProcedure(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41}
+	defer c.Close()
+
+	commands := make(chan []byte, 3)
+	go func() {
+		sc := packet.NewConn(server)
+		resultset := func(name string, value byte, status uint16) [][]byte {
+			eof := []byte{mysql.EOF_HEADER, 0, 0, byte(status), byte(status >> 8)}
+			return [][]byte{
+				{1},
+				(&mysql.Field{Name: []byte(name), Type: mysql.MYSQL_TYPE_LONGLONG}).Dump(),
+				eof,
+				{0, 0, value, 0, 0, 0, 0, 0, 0, 0},
+				eof,
+			}
+		}
+
+		responses := [][][]byte{
+			{
+				{mysql.OK_HEADER, 1, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0},
+				(&mysql.Field{Name: []byte("?")}).Dump(),
+				(&mysql.Field{Name: []byte("?")}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			},
+			append(append(
+				resultset("id", 7, mysql.SERVER_MORE_RESULTS_EXISTS),
+				resultset("@out", 9, mysql.SERVER_MORE_RESULTS_EXISTS|mysql.SERVER_PS_OUT_PARAMS)...),
+				[]byte{mysql.OK_HEADER, 1, 0, 0, 0, 0, 0}),
+			// COM_STMT_CLOSE has no response
+			nil,
+		}
+		for _, packets := range responses {
+			sc.ResetSequence()
+			data, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			commands <- data
+			for _, p := range packets {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// nothing is sent without CLIENT_PS_MULTI_RESULTS
+	_, err := c.CallProcedure("p", 1, nil)
+	require.ErrorContains(t, err, "CLIENT_PS_MULTI_RESULTS")
+	require.Empty(t, commands)
+
+	c.clientCapability = mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_PS_MULTI_RESULTS
+	results, err := c.CallProcedure("p", 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{mysql.COM_STMT_PREPARE}, "CALL p(?, ?)"...), <-commands)
+	require.Equal(t, byte(mysql.COM_STMT_EXECUTE), (<-commands)[0])
+	require.Equal(t, []byte{mysql.COM_STMT_CLOSE, 1, 0, 0, 0}, <-commands)
+
+	require.Len(t, results, 3)
+	id, err := results[0].GetInt(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id)
+
+	require.NotZero(t, results[1].Status&mysql.SERVER_PS_OUT_PARAMS)
+	out, err := results[1].GetIntByName(0, "@out")
+	require.NoError(t, err)
+	require.Equal(t, int64(9), out)
+
+	require.Nil(t, results[2].Resultset)
+	require.Equal(t, uint64(1), results[2].AffectedRows)
+}
+
+func TestExecuteAll(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		resultset := func(name string, value byte, status uint16) [][]byte {
+			eof := []byte{mysql.EOF_HEADER, 0, 0, byte(status), byte(status >> 8)}
+			return [][]byte{
+				{1},
+				(&mysql.Field{Name: []byte(name), Type: mysql.MYSQL_TYPE_LONGLONG}).Dump(),
+				eof,
+				{1, value},
+				eof,
+			}
+		}
+
+		more := mysql.SERVER_MORE_RESULTS_EXISTS
+		responses := [][][]byte{
+			append(append(
+				resultset("a", '1', more),
+				[]byte{mysql.OK_HEADER, 2, 0, byte(more), byte(more >> 8), 0, 0}),
+				resultset("b", '2', 0)...),
+			{
+				{mysql.OK_HEADER, 1, 0, byte(more), byte(more >> 8), 0, 0},
+				append([]byte{mysql.ERR_HEADER, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 't' doesn't exist"...),
+			},
+		}
+		for _, packets := range responses {
+			sc.ResetSequence()
+			if _, err := sc.ReadPacket(); err != nil {
+				return
+			}
+			for _, p := range packets {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	results, err := c.ExecuteAll("SELECT 1 AS a; DELETE FROM t; SELECT 2 AS b")
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	a, err := results[0].GetIntByName(0, "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), a)
+	require.Nil(t, results[1].Resultset)
+	require.Equal(t, uint64(2), results[1].AffectedRows)
+	b, err := results[2].GetIntByName(0, "b")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), b)
+
+	// the server stops at the failing statement
+	_, err = c.ExecuteAll("DELETE FROM u; DELETE FROM t; DELETE FROM v")
+	require.ErrorContains(t, err, "statement 2")
+	var myErr *mysql.MyError
+	require.ErrorAs(t, err, &myErr)
+	require.Equal(t, uint16(mysql.ER_NO_SUCH_TABLE), myErr.Code)
+}
+
+func TestExecuteMultipleError(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		if _, err := sc.ReadPacket(); err != nil {
+			return
+		}
+		// the resultset of the procedure, then its final OK
+		more := mysql.SERVER_MORE_RESULTS_EXISTS
+		for _, p := range [][]byte{
+			{1},
+			(&mysql.Field{Name: []byte("a"), Type: mysql.MYSQL_TYPE_LONGLONG}).Dump(),
+			{mysql.EOF_HEADER, 0, 0, byte(more), byte(more >> 8)},
+			{1, '1'},
+			{mysql.EOF_HEADER, 0, 0, byte(more), byte(more >> 8)},
+			{mysql.OK_HEADER, 0, 0, byte(more), byte(more >> 8), 0, 0},
+			append([]byte{mysql.ERR_HEADER, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 't' doesn't exist"...),
+		} {
+			if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	var errs []error
+	result, err := c.ExecuteMultiple("CALL p(); DELETE FROM t; DELETE FROM v", func(result *mysql.Result, err error) {
+		errs = append(errs, err)
+	})
+	require.NoError(t, err)
+	require.True(t, result.StreamingDone)
+
+	// the server stops at the failing statement
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.ErrorContains(t, errs[2], "statement 2")
+	var myErr *mysql.MyError
+	require.ErrorAs(t, errs[2], &myErr)
+	require.Equal(t, uint16(mysql.ER_NO_SUCH_TABLE), myErr.Code)
+}
+
+func TestCallStatements(t *testing.T) {
+	tests := []struct {
+		query string
+		calls []bool
+	}{
+		{"SELECT 1", []bool{false}},
+		{"CALL p(); SELECT 1;", []bool{true, false}},
+		{"call p ;; DELETE FROM t", []bool{true, false}},
+		{"SELECT ';CALL p()'; `x;call`; CALL p", []bool{false, false, true}},
+		{"SELECT 'it''s;' ; SELECT \"\\\";\"", []bool{false, false}},
+		{"/* ; */ CALL p(); -- ;\nCALLS; # CALL p\nCALL\tp", []bool{true, false, true}},
+		{"callp(); CALL", []bool{false, true}},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.calls, callStatements(test.query), test.query)
+	}
+}
+
+func TestColumnNameFunc(t *testing.T) {
+	require.Equal(t, "user_id", NormalizeColumnName(" `User_ID` "))
+
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), ColumnNameFunc: NormalizeColumnName}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		if _, err := sc.ReadPacket(); err != nil {
+			return
+		}
+		for _, p := range [][]byte{
+			{1},
+			(&mysql.Field{Name: []byte("UserID"), Type: mysql.MYSQL_TYPE_VAR_STRING}).Dump(),
+			{mysql.EOF_HEADER, 0, 0, 2, 0},
+			{1, '7'},
+			{mysql.EOF_HEADER, 0, 0, 2, 0},
+		} {
+			if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	r, err := c.Execute("SELECT id AS UserID FROM t")
+	require.NoError(t, err)
+	require.Equal(t, "UserID", string(r.Fields[0].Name))
+	id, err := r.GetIntByName(0, "userid")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id)
+	_, err = r.GetIntByName(0, "UserID")
+	require.Error(t, err)
+}
+
+func TestFieldChunkCallback(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	var chunks [][]byte
+	var done []int
+	var fail bool
+	c := &Conn{Conn: packet.NewConn(client), MaxFieldSize: 100}
+	c.FieldChunkCallback = func(column int, chunk []byte, last bool) error {
+		if fail {
+			return errors.New("stop")
+		}
+		chunks[column] = append(chunks[column], chunk...)
+		if last {
+			done = append(done, column)
+		}
+		return nil
+	}
+	defer c.Close()
+
+	blob := []byte(strings.Repeat("x", 70000))
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			if _, err := sc.ReadPacket(); err != nil {
+				return
+			}
+			for _, p := range [][]byte{
+				{3},
+				(&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_LONG}).Dump(),
+				(&mysql.Field{Name: []byte("data"), Type: mysql.MYSQL_TYPE_BLOB}).Dump(),
+				(&mysql.Field{Name: []byte("note"), Type: mysql.MYSQL_TYPE_BLOB}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+				append(append([]byte{1, '1'}, mysql.PutLengthEncodedString(blob)...), 0xfb),
+				{1, '2', 0, 1, 'y'},
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			} {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var rows [][]string
+	perRow := func(row []mysql.FieldValue) error {
+		var values []string
+		for _, v := range row {
+			values = append(values, v.String())
+		}
+		rows = append(rows, values)
+		return nil
+	}
+
+	chunks = make([][]byte, 3)
+	var result mysql.Result
+	require.NoError(t, c.ExecuteSelectStreaming("SELECT id, data, note FROM t", &result, perRow, nil))
+	require.Equal(t, [][]string{{"1", "''", "NULL"}, {"2", "''", "''"}}, rows)
+	require.Equal(t, blob, chunks[1])
+	require.Equal(t, []byte("y"), chunks[2])
+	require.Equal(t, []int{1, 1, 2}, done)
+
+	// an error of the callback skips the rest of the resultset
+	fail = true
+	err := c.ExecuteSelectStreaming("SELECT id, data, note FROM t", &result, perRow, nil)
+	require.ErrorContains(t, err, "stop")
+
+	c.FieldChunkCallback = nil
+	c.MaxFieldSize = 0
+	r, err := c.Execute("SELECT id, data, note FROM t")
+	require.NoError(t, err)
+	require.Equal(t, 2, r.RowNumber())
+
+	// a binary row written a byte at a time
+	fields := []*mysql.Field{{Type: mysql.MYSQL_TYPE_LONGLONG}, {Type: mysql.MYSQL_TYPE_NULL}, {Type: mysql.MYSQL_TYPE_LONG_BLOB}}
+	row := append([]byte{mysql.OK_HEADER, 1 << 3, 7, 0, 0, 0, 0, 0, 0, 0}, mysql.PutLengthEncodedString([]byte("abc"))...)
+	var data []byte
+	w := &chunkedRowWriter{fields: fields, binary: true, cb: func(column int, chunk []byte, last bool) error {
+		data = append(data, chunk...)
+		return nil
+	}}
+	w.reset()
+	for i := range row {
+		_, err = w.Write(row[i : i+1])
+		require.NoError(t, err)
+	}
+	require.True(t, w.complete())
+	require.Equal(t, []byte("abc"), data)
+	values, err := mysql.RowData(w.row).Parse(fields, true, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), values[0].AsInt64())
+	require.Nil(t, values[1].Value())
+	require.Empty(t, values[2].AsString())
+}
+
+func TestConnectContextHandshake(t *testing.T) {
+	// a server accepting connections but never sending its handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = ConnectWithContext(ctx, l.Addr().String(), "root", "", "", time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestTranscodeResults(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), charset: "utf8mb4"}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			query, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			// the charset of the name column is latin1, or swe7 which can't be transcoded
+			collation := uint16(8)
+			if strings.Contains(string(query), "swe7") {
+				collation = 10
+			}
+			for _, p := range [][]byte{
+				{3},
+				(&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_LONG, Charset: 63}).Dump(),
+				(&mysql.Field{Name: []byte("name"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: collation}).Dump(),
+				(&mysql.Field{Name: []byte("data"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 63}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+				{1, '1', 3, 0xe9, 't', 0xe9, 1, 0xe9},
+				{1, '2', 0xfb, 0},
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			} {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	r, err := c.Execute("SELECT id, name, data FROM t")
+	require.NoError(t, err)
+	name, err := r.GetString(0, 1)
+	require.NoError(t, err)
+	require.Equal(t, "\xe9t\xe9", name)
+
+	c.TranscodeResults = true
+	r, err = c.Execute("SELECT id, name, data FROM t")
+	require.NoError(t, err)
+	name, err = r.GetString(0, 1)
+	require.NoError(t, err)
+	require.Equal(t, "été", name)
+	data, err := r.GetString(0, 2)
+	require.NoError(t, err)
+	require.Equal(t, "\xe9", data)
+	isNull, err := r.IsNull(1, 1)
+	require.NoError(t, err)
+	require.True(t, isNull)
+
+	var names []string
+	var result mysql.Result
+	require.NoError(t, c.ExecuteSelectStreaming("SELECT id, name, data FROM t", &result, func(row []mysql.FieldValue) error {
+		names = append(names, row[1].String())
+		return nil
+	}, nil))
+	require.Equal(t, []string{"'été'", "NULL"}, names)
+
+	// a charset without encoding fails the query, the connection can still be used
+	_, err = c.Execute("SELECT id, name, data FROM swe7")
+	require.ErrorContains(t, err, "cannot transcode column name from charset swe7")
+	it, err := c.QueryIter("SELECT id, name, data FROM swe7")
+	require.ErrorContains(t, err, "cannot transcode column name from charset swe7")
+	require.Nil(t, it)
+
+	it, err = c.QueryIter("SELECT id, name, data FROM t")
+	require.NoError(t, err)
+	require.True(t, it.Next())
+	require.Equal(t, "été", string(it.Row()[1].AsString()))
+	require.True(t, it.Next())
+	require.False(t, it.Next())
+	require.NoError(t, it.Close())
+}
+
+func TestRowIterParseError(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			query, err := sc.ReadPacket()
+			if err != nil {
+				return
+			}
+			// the first row of the bad table is truncated
+			first := []byte{1, '1'}
+			if strings.Contains(string(query), "bad") {
+				first = []byte{5, '1'}
+			}
+			for _, p := range [][]byte{
+				{1},
+				(&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 33}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+				first,
+				{1, '2'},
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			} {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	it, err := c.QueryIter("SELECT id FROM bad")
+	require.NoError(t, err)
+	require.False(t, it.Next())
+	require.Error(t, it.Err())
+	require.Error(t, it.Close())
+
+	// the rest of the resultset was skipped, the connection can still be used
+	r, err := c.Execute("SELECT id FROM t")
+	require.NoError(t, err)
+	require.Equal(t, 2, r.RowNumber())
+	id, err := r.GetString(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, "1", id)
+}
+
+func TestQueryTyped(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Conn{Conn: packet.NewConn(client)}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for {
+			sc.ResetSequence()
+			if _, err := sc.ReadPacket(); err != nil {
+				return
+			}
+			for _, p := range [][]byte{
+				{5},
+				(&mysql.Field{Name: []byte("id"), Type: mysql.MYSQL_TYPE_LONGLONG}).Dump(),
+				(&mysql.Field{Name: []byte("name"), Type: mysql.MYSQL_TYPE_VAR_STRING}).Dump(),
+				(&mysql.Field{Name: []byte("created"), Type: mysql.MYSQL_TYPE_DATETIME}).Dump(),
+				(&mysql.Field{Name: []byte("score"), Type: mysql.MYSQL_TYPE_DOUBLE}).Dump(),
+				(&mysql.Field{Name: []byte("note"), Type: mysql.MYSQL_TYPE_VAR_STRING}).Dump(),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+				append(append(append(append([]byte{1, '1'},
+					mysql.PutLengthEncodedString([]byte("foo"))...),
+					mysql.PutLengthEncodedString([]byte("2024-02-29 13:14:15.5"))...),
+					mysql.PutLengthEncodedString([]byte("1.5"))...),
+					0xfb),
+				append(append(append(append([]byte{1, '2'},
+					mysql.PutLengthEncodedString([]byte("42"))...),
+					mysql.PutLengthEncodedString([]byte("0000-00-00 00:00:00"))...),
+					mysql.PutLengthEncodedString([]byte("2"))...),
+					mysql.PutLengthEncodedString([]byte("bar"))...),
+				{mysql.EOF_HEADER, 0, 0, 2, 0},
+			} {
+				if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	rows, err := c.QueryTyped("SELECT id, name, created, score, note FROM t")
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "created", "score", "note"}, rows.Columns())
+
+	var (
+		id      int
+		name    string
+		created time.Time
+		score   float32
+		note    sql.NullString
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &name, &created, &score, &note))
+	require.Equal(t, 1, id)
+	require.Equal(t, "foo", name)
+	require.Equal(t, time.Date(2024, 2, 29, 13, 14, 15, 500000000, time.UTC), created)
+	require.Equal(t, float32(1.5), score)
+	require.False(t, note.Valid)
+
+	var (
+		id64    sql.NullInt64
+		number  int8
+		zero    sql.NullTime
+		text    string
+		noteRaw []byte
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id64, &number, &zero, &text, &noteRaw))
+	require.Equal(t, sql.NullInt64{Int64: 2, Valid: true}, id64)
+	require.Equal(t, int8(42), number)
+	require.True(t, zero.Valid)
+	require.True(t, zero.Time.IsZero())
+	require.Equal(t, "2", text)
+	require.Equal(t, []byte("bar"), noteRaw)
+
+	var v interface{}
+	require.ErrorContains(t, rows.Scan(&v, &v, &id, &v, &v), `converting driver.Value type []uint8 ("0000-00-00 00:00:00") to a int`)
+	require.ErrorContains(t, rows.Scan(&v, &v, &v, &v), "expected 5 destination arguments")
+	require.ErrorContains(t, rows.Scan(&v, &v, &v, &v, &struct{}{}), "unsupported Scan destination")
+
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	// a NULL can't be scanned into a plain value
+	rows, err = c.QueryTyped("SELECT id, name, created, score, note FROM t")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.ErrorContains(t, rows.Scan(&v, &v, &v, &v, &name), "converting NULL to *string is unsupported")
+	require.NoError(t, rows.Close())
+}
+
+func TestRedactQuery(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT 1": "SELECT ?",
+		"SELECT * FROM t1 WHERE name = 'o''neil'":    "SELECT * FROM t1 WHERE name = ?",
+		`UPDATE t SET a = "x\"y", b = -1.5e+3`:       "UPDATE t SET a = ?, b = -?",
+		"SELECT `col 1`, `t2`.c3 FROM `t2` LIMIT 10": "SELECT `col 1`, `t2`.c3 FROM `t2` LIMIT ?",
+		"SELECT 'unterminated":                       "SELECT ?",
+	} {
+		require.Equal(t, expected, RedactQuery(query, nil), query)
+	}
+
+	require.Equal(t, "INSERT INTO t VALUES (?, ?, ?) [<int>, <string>, NULL]",
+		RedactQuery("INSERT INTO t VALUES (?, ?, ?)", []interface{}{1, "secret", nil}))
+}
+
+func TestQueryLogFunc(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	var logged []string
+	c := &Conn{Conn: packet.NewConn(client), capability: mysql.CLIENT_PROTOCOL_41}
+	c.QueryLogFunc = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	defer c.Close()
+
+	go func() {
+		sc := packet.NewConn(server)
+		for _, p := range [][]byte{
+			{mysql.OK_HEADER, 1, 0, 2, 0, 0, 0},
+			{mysql.ERR_HEADER, 0x26, 0x04, '#', '2', '3', '0', '0', '0', 'D', 'u', 'p'},
+		} {
+			sc.ResetSequence()
+			if _, err := sc.ReadPacket(); err != nil {
+				return
+			}
+			if err := sc.WritePacket(append(make([]byte, 4), p...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	_, err := c.Execute("UPDATE users SET email = 'a@b.c' WHERE id = 1")
+	require.NoError(t, err)
+
+	c.QueryLogRedactor = func(query string, args []interface{}) string {
+		return "redacted"
+	}
+	_, err = c.Execute("INSERT INTO users VALUES (1)")
+	require.ErrorIs(t, err, mysql.ErrDupEntry)
+
+	require.Len(t, logged, 2)
+	require.Regexp(t, `^query UPDATE users SET email = \? WHERE id = \? took `, logged[0])
+	require.Regexp(t, `^query redacted failed after .*: ERROR 1062 \(23000\): Dup$`, logged[1])
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	require.Equal(t, 10*time.Millisecond, p.backoff(1))
+	require.Equal(t, 20*time.Millisecond, p.backoff(2))
+	require.Equal(t, 40*time.Millisecond, p.backoff(3))
+	require.Equal(t, 50*time.Millisecond, p.backoff(4))
+	require.Equal(t, 50*time.Millisecond, p.backoff(100))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		require.GreaterOrEqual(t, d, 10*time.Millisecond)
+		require.LessOrEqual(t, d, 30*time.Millisecond)
+	}
+
+	require.Equal(t, 100*time.Millisecond, (&RetryPolicy{}).backoff(1))
+
+	require.Equal(t, 10*time.Second, (&RetryPolicy{}).dialTimeout())
+	require.Equal(t, time.Second, (&RetryPolicy{DialTimeout: time.Second}).dialTimeout())
+
+	require.True(t, p.retryable(errors.New("connection refused")))
+	require.False(t, p.retryable(fmt.Errorf("handleAuthResult: %w", mysql.NewDefaultError(mysql.ER_ACCESS_DENIED_ERROR, "root", "localhost", "YES"))))
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	var attempts int
+	policy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Retryable: func(error) bool {
+			attempts++
+			return true
+		},
+	}
+	_, err := ConnectWithRetry(context.Background(), "127.0.0.1:1", "root", "", "", policy)
+	require.ErrorContains(t, err, "after 3 attempts")
+	require.Equal(t, 3, attempts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	policy = RetryPolicy{InitialBackoff: 10 * time.Millisecond}
+	_, err = ConnectWithRetry(ctx, "127.0.0.1:1", "root", "", "", policy)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
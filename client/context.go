@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// killTimeout bounds the connection used to kill a canceled query.
+const killTimeout = 5 * time.Second
+
+// ContextConn runs the operations of a Conn under a context, see Conn.WithContext.
+type ContextConn struct {
+	c   *Conn
+	ctx context.Context
+}
+
+// WithContext returns a handle to run operations of the connection under ctx.
+// When ctx is canceled or its deadline passes while an operation is running, the query is
+// killed with KILL QUERY from another connection, opened like Clone with the address, dialer
+// and options of c, and the operation returns the context error. If the query can't be killed, the socket deadline is set to abort the operation instead,
+// which leaves the connection unusable.
+func (c *Conn) WithContext(ctx context.Context) *ContextConn {
+	return &ContextConn{c: c, ctx: ctx}
+}
+
+// Conn returns the underlying connection.
+func (cc *ContextConn) Conn() *Conn {
+	return cc.c
+}
+
+func (cc *ContextConn) Execute(command string, args ...interface{}) (*Result, error) {
+	var r *Result
+	err := cc.run(func() (err error) {
+		r, err = cc.c.Execute(command, args...)
+		return err
+	})
+	if err != nil && r != nil {
+		r.Close()
+		r = nil
+	}
+	return r, err
+}
+
+func (cc *ContextConn) Prepare(query string) (*Stmt, error) {
+	var s *Stmt
+	err := cc.run(func() (err error) {
+		s, err = cc.c.Prepare(query)
+		return err
+	})
+	if err != nil && s != nil {
+		s.Close()
+		s = nil
+	}
+	return s, err
+}
+
+func (cc *ContextConn) Begin() error {
+	return cc.run(cc.c.Begin)
+}
+
+func (cc *ContextConn) Commit() error {
+	return cc.run(cc.c.Commit)
+}
+
+func (cc *ContextConn) Rollback() error {
+	return cc.run(cc.c.Rollback)
+}
+
+func (cc *ContextConn) run(f func() error) error {
+	if err := cc.ctx.Err(); err != nil {
+		return errors.Trace(err)
+	}
+	if cc.ctx.Done() == nil {
+		return f()
+	}
+
+	finished := make(chan struct{})
+	watcherDone := make(chan struct{})
+	interrupted := false
+	go func() {
+		defer close(watcherDone)
+		select {
+		case <-finished:
+		case <-cc.ctx.Done():
+			interrupted = true
+			if err := cc.c.killQuery(); err != nil {
+				_ = cc.c.Conn.SetDeadline(time.Now())
+			}
+		}
+	}()
+
+	err := f()
+	close(finished)
+	// wait for the watcher, so a late KILL QUERY can't hit the next statement
+	<-watcherDone
+
+	// a killed statement may still succeed with a partial result, e.g. SLEEP returns 1
+	if interrupted {
+		if err != nil {
+			return errors.Annotatef(cc.ctx.Err(), "%v", err)
+		}
+		return errors.Trace(cc.ctx.Err())
+	}
+	return err
+}
+
+// killQuery kills the statement the connection is executing from a new connection, opened
+// like c with the same address, dialer and options.
+func (c *Conn) killQuery() error {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+
+	kc, err := c.clone(ctx, func(kc *Conn) error {
+		kc.ReadTimeout = killTimeout
+		kc.WriteTimeout = killTimeout
+		return nil
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer kc.Close()
+
+	return errors.Trace(kc.KillQuery(c.connectionID))
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	goErrors "errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// idleWatch is the background reader of an idle connection, see Conn.OnDisconnect.
+type idleWatch struct {
+	done chan struct{}
+	// set by the reader if it stopped for another reason than stopWatch
+	err error
+}
+
+// startWatch starts reading the idle connection in the background, to detect the server
+// closing it before the next command.
+func (c *Conn) startWatch() {
+	if c.OnDisconnect == nil || c.watch != nil || c.watchOff {
+		return
+	}
+
+	conn := c.Conn.Conn
+	// clear the deadline of the last read, the server may close the connection any time later
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	w := &idleWatch{done: make(chan struct{})}
+	c.watch = w
+	go func() {
+		defer close(w.done)
+
+		buf := make([]byte, 256)
+		n, err := conn.Read(buf)
+		if n == 0 {
+			var netErr net.Error
+			if goErrors.As(err, &netErr) && netErr.Timeout() {
+				// stopped by stopWatch
+				return
+			}
+			if err == io.EOF {
+				err = errors.Annotate(ErrBadConn, "connection closed by the server")
+			}
+		} else if n > 4 && buf[4] == ERR_HEADER {
+			// like ER_CLIENT_INTERACTION_TIMEOUT sent before the server closes the connection
+			err = errors.Annotatef(ErrBadConn, "connection closed by the server: %v", c.handleErrorPacket(buf[4:n]))
+		} else {
+			err = errors.Annotate(ErrBadConn, "unexpected data from the server on an idle connection")
+		}
+
+		w.err = err
+		_ = conn.Close()
+		c.OnDisconnect(err)
+	}()
+}
+
+// stopWatch stops the background reader started by startWatch, before the connection is used.
+func (c *Conn) stopWatch() {
+	w := c.watch
+	if w == nil {
+		return
+	}
+	c.watch = nil
+
+	conn := c.Conn.Conn
+	_ = conn.SetReadDeadline(time.Now())
+	<-w.done
+
+	if w.err != nil {
+		// the connection is closed, the command fails on it
+		c.watchOff = true
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+}
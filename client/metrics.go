@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net"
+	"time"
+)
+
+// CommandMetrics describes a command sent to the server, reported to Conn.MetricsCallback
+// once its response is read.
+type CommandMetrics struct {
+	// Command is the command sent, like COM_QUERY or COM_STMT_EXECUTE
+	Command byte
+
+	// BytesWritten and BytesRead are counted on the socket, including the packet headers
+	// and the TLS and compression overhead
+	BytesWritten uint64
+	BytesRead    uint64
+
+	Duration time.Duration
+	Err      error
+}
+
+// countingConn counts the bytes read from and written to a connection.
+type countingConn struct {
+	net.Conn
+
+	read    uint64
+	written uint64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read += uint64(n)
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written += uint64(n)
+	return n, err
+}
+
+func noopObserve(*error) {}
+
+// observe starts measuring the command cmd, and stops the disconnect detection while it runs.
+// The returned function reports it to the MetricsCallback with the error of the command, e.g.
+// defer c.observe(COM_PING)(&err).
+func (c *Conn) observe(cmd byte) func(*error) {
+	if c.OnDisconnect != nil {
+		c.stopWatch()
+	}
+	if c.MetricsCallback == nil || c.counter == nil {
+		if c.OnDisconnect != nil {
+			return c.restartWatch
+		}
+		return noopObserve
+	}
+
+	start := time.Now()
+	read, written := c.counter.read, c.counter.written
+	return func(err *error) {
+		c.MetricsCallback(CommandMetrics{
+			Command:      cmd,
+			BytesWritten: c.counter.written - written,
+			BytesRead:    c.counter.read - read,
+			Duration:     time.Since(start),
+			Err:          *err,
+		})
+		c.startWatch()
+	}
+}
+
+func (c *Conn) restartWatch(*error) {
+	c.startWatch()
+}
@@ -115,7 +115,7 @@ func NewPoolWithOptions(
 		maxIdle:  po.maxIdle,
 
 		idleCloseTimeout: Timestamp(math.Ceil(DefaultIdleTimeout.Seconds())),
-		idlePingTimeout:  Timestamp(math.Ceil(MaxIdleTimeoutWithoutPing.Seconds())),
+		idlePingTimeout:  Timestamp(math.Ceil(po.idlePingTimeout.Seconds())),
 
 		connect: func() (*Conn, error) {
 			return Connect(addr, user, password, dbName, po.connOptions...)
@@ -206,8 +206,8 @@ func (pool *Pool) GetConn(ctx context.Context) (*Conn, error) {
 			return nil, err
 		}
 
-		// For long time idle connections, we do a ping check
-		if delta := pool.nowTs() - connection.lastUseAt; delta > pool.idlePingTimeout {
+		// For long time idle connections, or all of them with a 0 idlePingTimeout, we do a ping check
+		if delta := pool.nowTs() - connection.lastUseAt; delta > pool.idlePingTimeout || pool.idlePingTimeout == 0 {
 			if err := pool.ping(connection.conn); err != nil {
 				pool.closeConn(connection.conn)
 				continue
@@ -218,8 +218,22 @@ func (pool *Pool) GetConn(ctx context.Context) (*Conn, error) {
 	}
 }
 
-// PutConn returns working connection back to pool
+// PutConn returns working connection back to pool, a closed connection is only
+// removed from the pool. A connection whose command failed with ErrBadConn should be
+// dropped with DropConn.
 func (pool *Pool) PutConn(conn *Conn) {
+	if conn.closed.Load() {
+		pool.closeConn(conn)
+		return
+	}
+
+	// don't leak an open transaction or the autocommit mode to the next user
+	if err := conn.ResetSession(); err != nil {
+		pool.logFunc(`Pool: reset session fail: %s`, err.Error())
+		pool.closeConn(conn)
+		return
+	}
+
 	pool.putConnection(Connection{
 		conn:      conn,
 		lastUseAt: pool.nowTs(),
@@ -402,8 +416,15 @@ func (pool *Pool) getOldIdleConnections(dst []Connection) []Connection {
 
 	synchro := &pool.synchro
 
+	// a 0 idlePingTimeout only applies to GetConn, pinging every idle connection in the
+	// background would keep the server busy
+	timeout := pool.idlePingTimeout
+	if timeout == 0 {
+		timeout = Timestamp(MaxIdleTimeoutWithoutPing.Seconds())
+	}
+
 	idleCnt := len(synchro.idleConnections)
-	checkBefore := pool.nowTs() - pool.idlePingTimeout
+	checkBefore := pool.nowTs() - timeout
 
 	for i := idleCnt - 1; i >= 0; i-- {
 		if synchro.idleConnections[i].lastUseAt > checkBefore {
@@ -609,5 +630,7 @@ func getDefaultPoolOptions() poolOptions {
 		minAlive: 1,
 		maxAlive: 10,
 		maxIdle:  2,
+
+		idlePingTimeout: MaxIdleTimeoutWithoutPing,
 	}
 }
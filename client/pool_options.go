@@ -20,6 +20,8 @@ type (
 		connOptions []Option
 
 		newPoolPingTimeout time.Duration
+
+		idlePingTimeout time.Duration
 	}
 )
 
@@ -58,3 +60,13 @@ func WithNewPoolPingTimeout(timeout time.Duration) PoolOption {
 		o.newPoolPingTimeout = timeout
 	}
 }
+
+// WithIdlePingTimeout sets how long a connection can be idle before it is pinged, when GetConn
+// returns it and while it is idle, MaxIdleTimeoutWithoutPing by default. With 0, GetConn pings
+// every connection it returns, so a connection broken while it was idle is closed and another
+// one is returned, the idle connections are still pinged after MaxIdleTimeoutWithoutPing.
+func WithIdlePingTimeout(timeout time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.idlePingTimeout = timeout
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/packet"
 	"github.com/go-mysql-org/go-mysql/test_util"
 	"github.com/siddontang/go-log/log"
 	"github.com/stretchr/testify/require"
@@ -77,3 +79,89 @@ func (s *poolTestSuite) TestPool_WrongAddr() {
 
 	require.Error(s.T(), err)
 }
+
+func TestPoolIdlePingTimeout(t *testing.T) {
+	// a server accepting any user and answering each command with an OK packet
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				sc := packet.NewConn(c)
+				capability := mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SECURE_CONNECTION | mysql.CLIENT_PLUGIN_AUTH
+				handshake := []byte{10}
+				handshake = append(handshake, "8.0.0\x00"...)
+				handshake = append(handshake, 1, 0, 0, 0)
+				handshake = append(handshake, "01234567\x00"...)
+				handshake = append(handshake, byte(capability), byte(capability>>8), mysql.DEFAULT_COLLATION_ID, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0)
+				handshake = append(handshake, byte(capability>>16), byte(capability>>24), 21)
+				handshake = append(handshake, make([]byte, 10)...)
+				handshake = append(handshake, "890123456789\x00"...)
+				handshake = append(handshake, mysql.AUTH_NATIVE_PASSWORD+"\x00"...)
+				ok := []byte{mysql.OK_HEADER, 0, 0, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0, 0, 0}
+				if sc.WritePacket(append(make([]byte, 4), handshake...)) != nil {
+					return
+				}
+				for {
+					if _, err := sc.ReadPacket(); err != nil {
+						return
+					}
+					if sc.WritePacket(append(make([]byte, 4), ok...)) != nil {
+						return
+					}
+					sc.ResetSequence()
+				}
+			}()
+		}
+	}()
+
+	pool, err := NewPoolWithOptions(l.Addr().String(), "root", "", "",
+		WithPoolLimits(0, 1, 1),
+		WithLogFunc(log.Debugf),
+		WithIdlePingTimeout(0),
+	)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// a closed connection put back is not returned again
+	conn, err := pool.GetConn(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	pool.PutConn(conn)
+
+	conn2, err := pool.GetConn(context.Background())
+	require.NoError(t, err)
+	require.NotSame(t, conn, conn2)
+	require.NoError(t, conn2.Ping())
+	pool.PutConn(conn2)
+
+	// a connection broken while it is idle fails the ping, another one is returned
+	require.NoError(t, conn2.Conn.Conn.Close())
+	conn3, err := pool.GetConn(context.Background())
+	require.NoError(t, err)
+	require.NotSame(t, conn2, conn3)
+	require.NoError(t, conn3.Ping())
+	pool.PutConn(conn3)
+}
+
+func TestPoolOldIdleConnections(t *testing.T) {
+	pool := &Pool{}
+	now := pool.nowTs()
+	recent := Connection{conn: &Conn{}, lastUseAt: now - 1}
+	old := Connection{conn: &Conn{}, lastUseAt: now - Timestamp(MaxIdleTimeoutWithoutPing.Seconds()) - 1}
+	pool.synchro.idleConnections = []Connection{recent, old}
+
+	// with a 0 idlePingTimeout, only the connections idle for MaxIdleTimeoutWithoutPing are pinged
+	// in the background
+	toPing := pool.getOldIdleConnections(nil)
+	require.Len(t, toPing, 1)
+	require.Same(t, old.conn, toPing[0].conn)
+	require.Len(t, pool.synchro.idleConnections, 1)
+	require.Same(t, recent.conn, pool.synchro.idleConnections[0].conn)
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QueryRedactor returns the text of a query and its arguments logged to Conn.QueryLogFunc,
+// args are the arguments of a prepared statement, nil for a plain query.
+type QueryRedactor func(query string, args []interface{}) string
+
+// observeQuery is observe for the commands running query, which is also logged to the
+// QueryLogFunc once it's done, e.g. defer c.observeQuery(COM_QUERY, query, nil)(&err).
+func (c *Conn) observeQuery(cmd byte, query string, args []interface{}) func(*error) {
+	observed := c.observe(cmd)
+	if c.QueryLogFunc == nil {
+		return observed
+	}
+
+	start := time.Now()
+	return func(err *error) {
+		observed(err)
+		c.logQuery(query, args, time.Since(start), *err)
+	}
+}
+
+func (c *Conn) logQuery(query string, args []interface{}, d time.Duration, err error) {
+	redact := c.QueryLogRedactor
+	if redact == nil {
+		redact = RedactQuery
+	}
+
+	text := redact(query, args)
+	if err != nil {
+		c.QueryLogFunc("query %s failed after %s: %v", text, d, err)
+	} else {
+		c.QueryLogFunc("query %s took %s", text, d)
+	}
+}
+
+// RedactQuery is the default QueryRedactor, it keeps the shape of the query but replaces its
+// string and number literals by ?, and the values of args by their type.
+func RedactQuery(query string, args []interface{}) string {
+	var b strings.Builder
+	b.WriteString(redactLiterals(query))
+	if len(args) > 0 {
+		b.WriteString(" [")
+		for i, arg := range args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if arg == nil {
+				b.WriteString("NULL")
+			} else {
+				fmt.Fprintf(&b, "<%T>", arg)
+			}
+		}
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+// redactLiterals replaces the quoted strings and the numbers of query by ?, the identifiers,
+// including the quoted ones, are kept.
+func redactLiterals(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			// skip to the closing quote, a quote is escaped by a backslash or doubled
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == '\\' {
+					j++
+				} else if query[j] == ch {
+					if j+1 < len(query) && query[j+1] == ch {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+			i = j + 1
+		case ch == '`':
+			end := len(query)
+			if j := strings.IndexByte(query[i+1:], '`'); j >= 0 {
+				end = i + j + 2
+			}
+			b.WriteString(query[i:end])
+			i = end
+		case isIdentByte(ch):
+			j := i
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			if ch >= '0' && ch <= '9' {
+				// a number, with its fraction or exponent
+				for j < len(query) && (isIdentByte(query[j]) || query[j] == '.' ||
+					((query[j] == '+' || query[j] == '-') && (query[j-1] == 'e' || query[j-1] == 'E'))) {
+					j++
+				}
+				b.WriteByte('?')
+			} else {
+				b.WriteString(query[i:j])
+			}
+			i = j
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 0x80 ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/x509"
 	"encoding/binary"
 	"encoding/pem"
+	goErrors "errors"
 	"fmt"
+	"math"
 
 	"github.com/pingcap/errors"
 
@@ -14,20 +16,24 @@ import (
 	"github.com/go-mysql-org/go-mysql/utils"
 )
 
-func (c *Conn) readUntilEOF() (err error) {
-	var data []byte
-
+// readFields reads column definitions until the EOF packet, n is the number expected.
+func (c *Conn) readFields(n int) ([]*Field, error) {
+	fields := make([]*Field, 0, n)
 	for {
-		data, err = c.ReadPacket()
-
+		data, err := c.ReadPacket()
 		if err != nil {
-			return
+			return nil, errors.Trace(err)
 		}
 
-		// EOF Packet
 		if c.isEOFPacket(data) {
-			return
+			return fields, nil
+		}
+
+		f, err := FieldData(data).Parse()
+		if err != nil {
+			return nil, errors.Trace(err)
 		}
+		fields = append(fields, f)
 	}
 }
 
@@ -43,6 +49,12 @@ func (c *Conn) handleOKPacket(data []byte) (*Result, error) {
 
 	r.AffectedRows, _, n = LengthEncodedInt(data[pos:])
 	pos += n
+	// -1 tells there is no count, like for some statements run by CALL
+	if r.AffectedRows == math.MaxUint64 {
+		r.AffectedRows = 0
+	} else {
+		r.HasAffectedRows = true
+	}
 	r.InsertId, _, n = LengthEncodedInt(data[pos:])
 	pos += n
 
@@ -53,19 +65,65 @@ func (c *Conn) handleOKPacket(data []byte) (*Result, error) {
 
 		//todo:strict_mode, check warnings as error
 		r.Warnings = binary.LittleEndian.Uint16(data[pos:])
-		// pos += 2
+		pos += 2
 	} else if c.capability&CLIENT_TRANSACTIONS > 0 {
 		r.Status = binary.LittleEndian.Uint16(data[pos:])
 		c.status = r.Status
 		// pos += 2
 	}
 
-	// new ok package will check CLIENT_SESSION_TRACK too, but I don't support it now.
+	// with CLIENT_SESSION_TRACK, the info is length encoded and followed by the session state
+	// changes, both are omitted when empty
+	if c.capability&c.clientCapability&CLIENT_SESSION_TRACK > 0 && pos < len(data) {
+		_, _, n, err := LengthEncodedString(data[pos:])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pos += n
+
+		if r.Status&SERVER_SESSION_STATE_CHANGED > 0 && pos < len(data) {
+			changes, _, _, err := LengthEncodedString(data[pos:])
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if err := c.handleSessionStateChanges(changes); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
 
 	// skip info
 	return r, nil
 }
 
+// handleSessionStateChanges keeps the transaction state and characteristics of the
+// session state changes of an OK packet, the other changes are skipped.
+func (c *Conn) handleSessionStateChanges(data []byte) error {
+	for pos := 0; pos < len(data); {
+		tp := data[pos]
+		pos++
+		change, _, n, err := LengthEncodedString(data[pos:])
+		if err != nil {
+			return errors.Trace(err)
+		}
+		pos += n
+
+		switch tp {
+		case SESSION_TRACK_TRANSACTION_STATE, SESSION_TRACK_TRANSACTION_CHARACTERISTICS:
+			value, _, _, err := LengthEncodedString(change)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if tp == SESSION_TRACK_TRANSACTION_STATE {
+				c.trxState = string(value)
+			} else {
+				c.trxCharacteristics = string(value)
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Conn) handleErrorPacket(data []byte) error {
 	e := new(MyError)
 
@@ -135,7 +193,14 @@ func (c *Conn) handleAuthResult() error {
 				if err = c.WriteClearAuthPacket(c.password); err != nil {
 					return err
 				}
+			} else if c.serverPubKey != nil {
+				if err = c.WriteEncryptedPassword(c.password, c.salt, c.serverPubKey); err != nil {
+					return err
+				}
 			} else {
+				if err = c.checkPublicKeyRetrieval(); err != nil {
+					return err
+				}
 				if err = c.WritePublicKeyAuthPacket(c.password, c.salt); err != nil {
 					return err
 				}
@@ -150,6 +215,9 @@ func (c *Conn) handleAuthResult() error {
 			return nil // auth already succeeded
 		}
 		block, _ := pem.Decode(data)
+		if block == nil {
+			return errors.Errorf("invalid server public key")
+		}
 		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 		if err != nil {
 			return err
@@ -258,6 +326,7 @@ func (c *Conn) readResultStreaming(binary bool, result *Result, perRowCb SelectP
 
 		result.Status = okResult.Status
 		result.AffectedRows = okResult.AffectedRows
+		result.HasAffectedRows = okResult.HasAffectedRows
 		result.InsertId = okResult.InsertId
 		result.Warnings = okResult.Warnings
 		if result.Resultset == nil {
@@ -287,6 +356,8 @@ func (c *Conn) readResultset(data []byte, binary bool) (*Result, error) {
 		Resultset: NewResultset(int(count)),
 	}
 
+	result.Binary = binary
+
 	if err := c.readResultColumns(result); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -314,6 +385,7 @@ func (c *Conn) readResultsetStreaming(data []byte, binary bool, result *Result,
 
 	// this is a streaming resultset
 	result.Resultset.Streaming = StreamingSelect
+	result.Binary = binary
 
 	if err := c.readResultColumns(result); err != nil {
 		return errors.Trace(err)
@@ -371,7 +443,11 @@ func (c *Conn) readResultColumns(result *Result) (err error) {
 			return err
 		}
 
-		result.FieldNames[utils.ByteSliceToString(result.Fields[i].Name)] = i
+		if c.ColumnNameFunc != nil {
+			result.FieldNames[c.ColumnNameFunc(string(result.Fields[i].Name))] = i
+		} else {
+			result.FieldNames[utils.ByteSliceToString(result.Fields[i].Name)] = i
+		}
 
 		i++
 	}
@@ -379,13 +455,15 @@ func (c *Conn) readResultColumns(result *Result) (err error) {
 
 func (c *Conn) readResultRows(result *Result, isBinary bool) (err error) {
 	var data []byte
+	w := c.fieldSizeWriter(result.Fields, isBinary)
 
 	for {
 		rawPkgLen := len(result.RawPkg)
-		result.RawPkg, err = c.ReadPacketReuseMem(result.RawPkg)
+		rawPkg, err := c.readRowPacket(w, result.RawPkg)
 		if err != nil {
-			return err
+			return c.rowPacketError(err)
 		}
+		result.RawPkg = rawPkg
 		data = result.RawPkg[rawPkgLen:]
 
 		// EOF Packet
@@ -413,27 +491,46 @@ func (c *Conn) readResultRows(result *Result, isBinary bool) (err error) {
 		result.Values = result.Values[:len(result.RowDatas)]
 	}
 
+	t, err := c.newTranscoder(result.Fields)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
 	for i := range result.Values {
 		result.Values[i], err = result.RowDatas[i].Parse(result.Fields, isBinary, result.Values[i])
 
 		if err != nil {
 			return errors.Trace(err)
 		}
+
+		if err = t.transcode(result.Values[i]); err != nil {
+			return errors.Trace(err)
+		}
 	}
 
 	return nil
 }
 
 func (c *Conn) readResultRowsStreaming(result *Result, isBinary bool, perRowCb SelectPerRowCallback) (err error) {
+	if c.FieldChunkCallback != nil {
+		return c.readResultRowsChunked(result, isBinary, perRowCb)
+	}
+
 	var (
 		data []byte
 		row  []FieldValue
 	)
 
+	t, err := c.newTranscoder(result.Fields)
+	if err != nil {
+		return errors.Trace(c.discardResultRows(err))
+	}
+	w := c.fieldSizeWriter(result.Fields, isBinary)
+
 	for {
-		data, err = c.ReadPacketReuseMem(data[:0])
+		data, err = c.readRowPacket(w, data[:0])
 		if err != nil {
-			return err
+			return c.rowPacketError(err)
 		}
 
 		// EOF Packet
@@ -458,12 +555,99 @@ func (c *Conn) readResultRowsStreaming(result *Result, isBinary bool, perRowCb S
 			return errors.Trace(err)
 		}
 
+		if err = t.transcode(row); err != nil {
+			return errors.Trace(c.discardResultRows(err))
+		}
+
 		// Send the row to "userland" code
 		err = perRowCb(row)
 		if err != nil {
-			return errors.Trace(err)
+			// skip the remaining rows so the connection can still be used
+			return errors.Trace(c.discardResultRows(err))
 		}
 	}
 
 	return nil
 }
+
+// fieldSizeWriter returns the writer checking the sizes of the values of the rows of fields
+// as they are read, nil without MaxFieldSize.
+func (c *Conn) fieldSizeWriter(fields []*Field, isBinary bool) *chunkedRowWriter {
+	if c.MaxFieldSize <= 0 {
+		return nil
+	}
+	return &chunkedRowWriter{fields: fields, binary: isBinary, maxFieldSize: c.MaxFieldSize}
+}
+
+// readRowPacket is ReadPacketReuseMem for the row packets of a resultset, their values are
+// checked by w as they are read if it is not nil. A value larger than MaxFieldSize isn't kept
+// in memory, ErrFieldTooLarge is returned once the whole packet is read.
+func (c *Conn) readRowPacket(w *chunkedRowWriter, dst []byte) ([]byte, error) {
+	if w == nil {
+		return c.ReadPacketReuseMem(dst)
+	}
+
+	w.reset()
+	if err := c.ReadPacketTo(w); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return append(dst, w.row...), nil
+}
+
+// rowPacketError returns the error of readRowPacket, the remaining rows are skipped for a
+// value too large so the connection can still be used.
+func (c *Conn) rowPacketError(err error) error {
+	if goErrors.Is(err, ErrFieldTooLarge) {
+		return errors.Trace(c.discardResultRows(err))
+	}
+	return err
+}
+
+// discardResultRows reads and drops the rows left in a resultset, returning cause unless reading fails.
+// Only the first bytes of the rows are kept, to find the EOF packet.
+func (c *Conn) discardResultRows(cause error) error {
+	var w packetHead
+	for {
+		w.reset()
+		if err := c.ReadPacketTo(&w); err != nil {
+			return errors.Trace(err)
+		}
+		data := w.head
+
+		if len(data) == 0 {
+			return ErrMalformPacket
+		}
+		if data[0] == EOF_HEADER && w.n <= 5 {
+			if c.capability&CLIENT_PROTOCOL_41 > 0 && len(data) == 5 {
+				c.status = binary.LittleEndian.Uint16(data[3:])
+			}
+			return cause
+		}
+		if data[0] == ERR_HEADER {
+			return cause
+		}
+	}
+}
+
+// packetHead keeps the first bytes of a packet, enough for an EOF packet, and drops the rest.
+type packetHead struct {
+	head []byte
+	// the size of the packet
+	n int
+}
+
+func (w *packetHead) reset() {
+	w.head = w.head[:0]
+	w.n = 0
+}
+
+func (w *packetHead) Write(p []byte) (int, error) {
+	if m := min(5-len(w.head), len(p)); m > 0 {
+		w.head = append(w.head, p[:m]...)
+	}
+	w.n += len(p)
+	return len(p), nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	goErrors "errors"
+	"math/rand"
+	"time"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// RetryPolicy configures how ConnectWithRetry retries a failed connection. The zero value
+// retries with the default backoff until the context is done.
+type RetryPolicy struct {
+	// MaxAttempts is the number of connection attempts, 0 means no limit
+	MaxAttempts int
+
+	// DialTimeout is the timeout of dialing the server on each attempt, 10s by default
+	DialTimeout time.Duration
+
+	// InitialBackoff is the wait after the first failed attempt, 100ms by default. The wait is
+	// multiplied by Multiplier, 2 by default, after each attempt, up to MaxBackoff, 10s by default.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter randomizes each wait by up to this fraction of it, between 0 and 1, so clients
+	// started together don't retry in lockstep
+	Jitter float64
+
+	// Retryable tells whether to retry after the error. By default all the errors are retried
+	// but the access denied errors of the server.
+	Retryable func(err error) bool
+}
+
+// backoff returns the wait after the failed attempt n, starting at 1.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	initial, maxBackoff, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	d := float64(initial)
+	for i := 1; i < n && d < float64(maxBackoff); i++ {
+		d *= multiplier
+	}
+	d = min(d, float64(maxBackoff))
+
+	if p.Jitter > 0 {
+		d += d * min(p.Jitter, 1) * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) dialTimeout() time.Duration {
+	if p.DialTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return p.DialTimeout
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return !goErrors.Is(err, ErrUserAccessDenied) && !goErrors.Is(err, ErrDBAccessDenied)
+}
+
+// ConnectWithRetry connects like ConnectWithContext, retrying with an exponential backoff
+// while it fails, e.g. until a server that is still starting accepts connections. It returns
+// the last error once the attempts of the policy are exhausted or ctx is done.
+func ConnectWithRetry(ctx context.Context, addr, user, password, dbName string, policy RetryPolicy, options ...Option) (*Conn, error) {
+	for attempt := 1; ; attempt++ {
+		c, err := ConnectWithContext(ctx, addr, user, password, dbName, policy.dialTimeout(), options...)
+		if err == nil {
+			return c, nil
+		}
+
+		if !policy.retryable(err) {
+			return nil, errors.Trace(err)
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return nil, errors.Annotatef(err, "failed to connect after %d attempts", attempt)
+		}
+
+		t := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, errors.Annotatef(ctx.Err(), "%v", err)
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	goErrors "errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/utils"
+)
+
+// RowIter is a forward-only cursor over a resultset. Unlike Execute, which buffers
+// the whole resultset in memory, RowIter reads one row at a time from the connection.
+//
+// The connection must not be used for anything else until the RowIter is closed.
+// Only the first resultset of a query is read.
+//
+// Example:
+//
+//	it, err := conn.QueryIter(`SELECT id, name FROM t WHERE id > ?`, 10)
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		var id int64
+//		var name string
+//		if err := it.Scan(&id, &name); err != nil { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+type RowIter struct {
+	c      *Conn
+	stmt   *Stmt
+	binary bool
+
+	result *Result
+
+	data []byte
+	row  []FieldValue
+
+	transcoder *transcoder
+	// checks the field sizes of the rows as they are read, see readRowPacket
+	sizeWriter *chunkedRowWriter
+
+	err    error
+	done   bool
+	closed bool
+
+	// reports the metrics of the query once its rows are read
+	observed func(*error)
+}
+
+// QueryIter executes the query and returns a RowIter to read the rows lazily.
+// When args are given, the query is executed as a prepared statement.
+func (c *Conn) QueryIter(query string, args ...interface{}) (*RowIter, error) {
+	if len(args) > 0 {
+		s, err := c.Prepare(query)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		it, err := s.QueryIter(args...)
+		if err != nil {
+			s.Close()
+			return nil, errors.Trace(err)
+		}
+		// the statement is closed with the RowIter
+		it.stmt = s
+		return it, nil
+	}
+
+	it := &RowIter{c: c}
+	it.observed = c.observeQuery(COM_QUERY, query, nil)
+	if err := c.writeCommandStr(COM_QUERY, query); err != nil {
+		it.observed(&err)
+		return nil, errors.Trace(err)
+	}
+
+	if err := it.start(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return it, nil
+}
+
+// QueryIter executes the statement and returns a RowIter to read the rows lazily.
+// The statement is left open when the RowIter is closed.
+func (s *Stmt) QueryIter(args ...interface{}) (*RowIter, error) {
+	it := &RowIter{c: s.conn, binary: true}
+	it.observed = s.conn.observeQuery(COM_STMT_EXECUTE, s.query, args)
+	if err := s.write(args...); err != nil {
+		it.observed(&err)
+		return nil, errors.Trace(err)
+	}
+
+	if err := it.start(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return it, nil
+}
+
+// start reads the response of the query up to the first row.
+func (it *RowIter) start() error {
+	if err := it.readHeader(); err != nil {
+		it.observed(&err)
+		return errors.Trace(err)
+	}
+	if it.done {
+		it.observed(&it.err)
+	}
+	return nil
+}
+
+func (it *RowIter) readHeader() error {
+	data, err := it.c.ReadPacket()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	switch data[0] {
+	case OK_HEADER:
+		it.result, err = it.c.handleOKPacket(data)
+		it.done = true
+		return err
+	case ERR_HEADER:
+		return it.c.handleErrorPacket(data)
+	case LocalInFile_HEADER:
+		return ErrMalformPacket
+	}
+
+	count, _, n := LengthEncodedInt(data)
+	if n-len(data) != 0 {
+		return ErrMalformPacket
+	}
+
+	it.result = &Result{Resultset: NewResultset(int(count))}
+	it.result.Binary = it.binary
+	if err = it.c.readResultColumns(it.result); err != nil {
+		return errors.Trace(err)
+	}
+
+	it.transcoder, err = it.c.newTranscoder(it.result.Fields)
+	if err != nil {
+		return errors.Trace(it.c.discardResultRows(err))
+	}
+	it.sizeWriter = it.c.fieldSizeWriter(it.result.Fields, it.binary)
+	return nil
+}
+
+// Fields returns the column definitions of the resultset, or nil if the query
+// returned no resultset.
+func (it *RowIter) Fields() []*Field {
+	if it.result == nil || it.result.Resultset == nil {
+		return nil
+	}
+	return it.result.Fields
+}
+
+// Result returns the status of the query, it is complete only after Next returned false.
+func (it *RowIter) Result() *Result {
+	return it.result
+}
+
+// Next reads the next row, it returns false when there are no more rows or an error
+// occurred, use Err to tell the two apart.
+func (it *RowIter) Next() bool {
+	if it.done || it.err != nil || it.closed {
+		return false
+	}
+
+	if it.next() {
+		return true
+	}
+	it.observed(&it.err)
+	return false
+}
+
+func (it *RowIter) next() bool {
+	var err error
+	it.data, err = it.c.readRowPacket(it.sizeWriter, it.data[:0])
+	if err != nil {
+		it.err = errors.Trace(it.c.rowPacketError(err))
+		if goErrors.Is(err, ErrFieldTooLarge) {
+			it.done = true
+		}
+		return false
+	}
+
+	if it.c.isEOFPacket(it.data) {
+		if it.c.capability&CLIENT_PROTOCOL_41 > 0 {
+			it.result.Warnings = binary.LittleEndian.Uint16(it.data[1:])
+			it.result.Status = binary.LittleEndian.Uint16(it.data[3:])
+			it.c.status = it.result.Status
+		}
+		it.done = true
+		return false
+	}
+
+	if it.data[0] == ERR_HEADER {
+		it.err = it.c.handleErrorPacket(bytes.Clone(it.data))
+		it.done = true
+		return false
+	}
+
+	it.row, err = RowData(it.data).Parse(it.result.Fields, it.binary, it.row)
+	if err != nil {
+		it.err = errors.Trace(it.c.discardResultRows(err))
+		it.done = true
+		return false
+	}
+
+	if err = it.transcoder.transcode(it.row); err != nil {
+		it.err = errors.Trace(it.c.discardResultRows(err))
+		it.done = true
+		return false
+	}
+
+	return true
+}
+
+// Row returns the current row. The values are only valid until the next call to Next.
+func (it *RowIter) Row() []FieldValue {
+	return it.row
+}
+
+// Scan copies the columns of the current row into the values pointed at by dest.
+// Supported destinations are *interface{}, *string, *[]byte, *int, *int64, *uint64,
+// *float64, *bool and *FieldValue.
+func (it *RowIter) Scan(dest ...interface{}) error {
+	if it.row == nil {
+		return errors.New("Scan called without calling Next")
+	}
+	if len(dest) != len(it.row) {
+		return errors.Errorf("expected %d destination arguments in Scan, not %d", len(it.row), len(dest))
+	}
+
+	for i := range dest {
+		if err := scanFieldValue(dest[i], &it.row[i]); err != nil {
+			return errors.Annotatef(err, "column %d", i)
+		}
+	}
+	return nil
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (it *RowIter) Err() error {
+	return it.err
+}
+
+// Close discards the rows that were not read yet, so the connection can be used again,
+// and closes the prepared statement used by Conn.QueryIter, if any.
+func (it *RowIter) Close() error {
+	if it.closed {
+		return nil
+	}
+
+	for it.Next() {
+	}
+	it.closed = true
+
+	if it.stmt != nil {
+		if err := it.stmt.Close(); err != nil && it.err == nil {
+			it.err = errors.Trace(err)
+		}
+	}
+
+	return it.err
+}
+
+func scanFieldValue(dest interface{}, fv *FieldValue) error {
+	switch d := dest.(type) {
+	case *FieldValue:
+		*d = NewFieldValue(fv.Type, fv.AsUint64(), bytes.Clone(fv.AsString()))
+		return nil
+	case *interface{}:
+		if fv.Type == FieldValueTypeString {
+			*d = bytes.Clone(fv.AsString())
+		} else {
+			*d = fv.Value()
+		}
+		return nil
+	}
+
+	if fv.Type == FieldValueTypeNull {
+		return errors.Errorf("converting NULL to %T is unsupported", dest)
+	}
+
+	var err error
+	switch d := dest.(type) {
+	case *string:
+		*d = fieldValueString(fv)
+	case *[]byte:
+		*d = []byte(fieldValueString(fv))
+	case *int64:
+		*d, err = fieldValueInt64(fv)
+	case *int:
+		var v int64
+		v, err = fieldValueInt64(fv)
+		*d = int(v)
+	case *uint64:
+		*d, err = fieldValueUint64(fv)
+	case *float64:
+		*d, err = fieldValueFloat64(fv)
+	case *bool:
+		var v int64
+		v, err = fieldValueInt64(fv)
+		*d = v != 0
+	default:
+		return errors.Errorf("unsupported Scan destination %T", dest)
+	}
+	return errors.Trace(err)
+}
+
+func fieldValueString(fv *FieldValue) string {
+	switch fv.Type {
+	case FieldValueTypeUnsigned:
+		return strconv.FormatUint(fv.AsUint64(), 10)
+	case FieldValueTypeSigned:
+		return strconv.FormatInt(fv.AsInt64(), 10)
+	case FieldValueTypeFloat:
+		return strconv.FormatFloat(fv.AsFloat64(), 'f', -1, 64)
+	default:
+		return string(fv.AsString())
+	}
+}
+
+func fieldValueInt64(fv *FieldValue) (int64, error) {
+	switch fv.Type {
+	case FieldValueTypeUnsigned:
+		if fv.AsUint64() > math.MaxInt64 {
+			return 0, fmt.Errorf("converting unsigned value %d to int64 overflows", fv.AsUint64())
+		}
+		return int64(fv.AsUint64()), nil
+	case FieldValueTypeSigned:
+		return fv.AsInt64(), nil
+	case FieldValueTypeFloat:
+		return int64(fv.AsFloat64()), nil
+	default:
+		return strconv.ParseInt(utils.ByteSliceToString(fv.AsString()), 10, 64)
+	}
+}
+
+func fieldValueUint64(fv *FieldValue) (uint64, error) {
+	switch fv.Type {
+	case FieldValueTypeUnsigned:
+		return fv.AsUint64(), nil
+	case FieldValueTypeSigned:
+		if fv.AsInt64() < 0 {
+			return 0, fmt.Errorf("converting negative value %d to uint64", fv.AsInt64())
+		}
+		return uint64(fv.AsInt64()), nil
+	case FieldValueTypeFloat:
+		return uint64(fv.AsFloat64()), nil
+	default:
+		return strconv.ParseUint(utils.ByteSliceToString(fv.AsString()), 10, 64)
+	}
+}
+
+func fieldValueFloat64(fv *FieldValue) (float64, error) {
+	switch fv.Type {
+	case FieldValueTypeUnsigned:
+		return float64(fv.AsUint64()), nil
+	case FieldValueTypeSigned:
+		return float64(fv.AsInt64()), nil
+	case FieldValueTypeFloat:
+		return fv.AsFloat64(), nil
+	default:
+		return strconv.ParseFloat(utils.ByteSliceToString(fv.AsString()), 64)
+	}
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pingcap/errors"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/utils"
+)
+
+// Rows is the result of Conn.QueryTyped. Like a RowIter, it reads the rows from the connection
+// as Next is called, but its Scan converts the values as the Scan of database/sql does.
+type Rows struct {
+	it *RowIter
+}
+
+// QueryTyped executes the query with the given arguments and returns its rows, which must be
+// closed before the connection is used again.
+func (c *Conn) QueryTyped(query string, args ...interface{}) (*Rows, error) {
+	it, err := c.QueryIter(query, args...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Rows{it: it}, nil
+}
+
+// Columns returns the names of the columns of the rows.
+func (r *Rows) Columns() []string {
+	fields := r.it.Fields()
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f.Name)
+	}
+	return names
+}
+
+// Fields returns the column definitions of the rows.
+func (r *Rows) Fields() []*Field {
+	return r.it.Fields()
+}
+
+// Next reads the next row, it returns false when there are no more rows or an error
+// occurred, use Err to tell the two apart.
+func (r *Rows) Next() bool {
+	return r.it.Next()
+}
+
+// Scan copies the columns of the current row into the values pointed at by dest, with the
+// conversions of sql.Rows.Scan. The supported destinations are *interface{}, *string, *[]byte,
+// *sql.RawBytes, *bool, the pointers to the integer and float types, *time.Time, for the DATE,
+// DATETIME and TIMESTAMP columns, and the sql.Scanner implementations like sql.NullString.
+// As with sql.Rows, *sql.RawBytes is only valid until the next call to Next.
+func (r *Rows) Scan(dest ...interface{}) error {
+	row := r.it.Row()
+	if row == nil {
+		return errors.New("Scan called without calling Next")
+	}
+	if len(dest) != len(row) {
+		return errors.Errorf("expected %d destination arguments in Scan, not %d", len(row), len(dest))
+	}
+
+	fields := r.it.Fields()
+	for i := range dest {
+		v, err := driverValue(fields[i], &row[i], wantsTime(dest[i]))
+		if err != nil {
+			return errors.Annotatef(err, "column %d (%s)", i, fields[i].Name)
+		}
+		if err = convertAssign(dest[i], v); err != nil {
+			return errors.Annotatef(err, "column %d (%s)", i, fields[i].Name)
+		}
+	}
+	return nil
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (r *Rows) Err() error {
+	return r.it.Err()
+}
+
+// Close discards the rows that were not read yet, so the connection can be used again.
+func (r *Rows) Close() error {
+	return r.it.Close()
+}
+
+// wantsTime reports whether dest is scanned from a time.Time.
+func wantsTime(dest interface{}) bool {
+	switch dest.(type) {
+	case *time.Time, *sql.NullTime, *sql.Null[time.Time]:
+		return true
+	default:
+		return false
+	}
+}
+
+// driverValue returns the value of fv as the one of a database/sql driver, the DATE, DATETIME
+// and TIMESTAMP values are parsed to a time.Time, in UTC, if asTime is set.
+func driverValue(f *Field, fv *FieldValue, asTime bool) (interface{}, error) {
+	switch fv.Type {
+	case FieldValueTypeNull:
+		return nil, nil
+	case FieldValueTypeUnsigned:
+		return fv.AsUint64(), nil
+	case FieldValueTypeSigned:
+		return fv.AsInt64(), nil
+	case FieldValueTypeFloat:
+		return fv.AsFloat64(), nil
+	}
+
+	if asTime {
+		switch f.Type {
+		case MYSQL_TYPE_DATE, MYSQL_TYPE_NEWDATE, MYSQL_TYPE_DATETIME, MYSQL_TYPE_TIMESTAMP:
+			return parseTimeValue(fv.AsString())
+		}
+	}
+	return fv.AsString(), nil
+}
+
+// parseTimeValue parses a DATE, DATETIME or TIMESTAMP value, the zero dates are the zero time.
+func parseTimeValue(b []byte) (time.Time, error) {
+	s := utils.ByteSliceToString(b)
+	if len(s) >= len("0000-00-00") && s[:len("0000-00-00")] == "0000-00-00" {
+		return time.Time{}, nil
+	}
+
+	layout := "2006-01-02 15:04:05.999999"
+	if len(s) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	t, err := time.ParseInLocation(layout, s, time.UTC)
+	return t, errors.Annotatef(err, "invalid time value %q", s)
+}
+
+// convertAssign stores src into dest with the conversions of database/sql, which are done by
+// the Scan of sql.Null.
+func convertAssign(dest, src interface{}) error {
+	switch d := dest.(type) {
+	case sql.Scanner:
+		return d.Scan(src)
+	case *interface{}:
+		if src == nil {
+			*d = nil
+			return nil
+		}
+		return scanInto(d, src)
+	case *[]byte:
+		if src == nil {
+			*d = nil
+			return nil
+		}
+		return scanInto(d, src)
+	case *sql.RawBytes:
+		if src == nil {
+			*d = nil
+			return nil
+		}
+		return scanInto(d, src)
+	case *string:
+		return scanInto(d, src)
+	case *bool:
+		return scanInto(d, src)
+	case *int:
+		return scanInto(d, src)
+	case *int8:
+		return scanInto(d, src)
+	case *int16:
+		return scanInto(d, src)
+	case *int32:
+		return scanInto(d, src)
+	case *int64:
+		return scanInto(d, src)
+	case *uint:
+		return scanInto(d, src)
+	case *uint8:
+		return scanInto(d, src)
+	case *uint16:
+		return scanInto(d, src)
+	case *uint32:
+		return scanInto(d, src)
+	case *uint64:
+		return scanInto(d, src)
+	case *float32:
+		return scanInto(d, src)
+	case *float64:
+		return scanInto(d, src)
+	case *time.Time:
+		return scanInto(d, src)
+	default:
+		return errors.Errorf("unsupported Scan destination %T", dest)
+	}
+}
+
+func scanInto[T any](dest *T, src interface{}) error {
+	var n sql.Null[T]
+	if err := n.Scan(src); err != nil {
+		return errors.Trace(err)
+	}
+	if !n.Valid {
+		return errors.Errorf("converting NULL to %T is unsupported", dest)
+	}
+	*dest = n.V
+	return nil
+}
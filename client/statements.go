@@ -0,0 +1,101 @@
+package client
+
+import (
+	"strings"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// statementCounter numbers, from 1, the statements of a multi-statement query as their results
+// are read: a statement has a single result, except a CALL whose resultsets come before its
+// final OK.
+type statementCounter struct {
+	// whether each statement of the query is a CALL
+	calls []bool
+	// the number of statements done
+	done int
+}
+
+func newStatementCounter(query string) *statementCounter {
+	return &statementCounter{calls: callStatements(query)}
+}
+
+// current returns the number of the statement the next result belongs to.
+func (s *statementCounter) current() int {
+	return s.done + 1
+}
+
+// add counts r, a result of the current statement.
+func (s *statementCounter) add(r *Result) {
+	if s.done < len(s.calls) && s.calls[s.done] && r.Resultset != nil {
+		return
+	}
+	s.done++
+}
+
+// callStatements tells for each statement of query whether it is a CALL, skipping the strings,
+// quoted identifiers and comments. The empty statements are left out.
+func callStatements(query string) []bool {
+	var calls []bool
+	start := true
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ';':
+			start = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '#' || strings.HasPrefix(query[i:], "-- ") || strings.HasPrefix(query[i:], "--\t") || strings.HasPrefix(query[i:], "--\n"):
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(query)
+			}
+			continue
+		case strings.HasPrefix(query[i:], "/*"):
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(query)
+			}
+			continue
+		}
+
+		if start {
+			start = false
+			calls = append(calls, len(query) >= i+4 && strings.EqualFold(query[i:i+4], "CALL") &&
+				(len(query) == i+4 || !isIdentifierChar(query[i+4])))
+		}
+
+		if c == '\'' || c == '"' || c == '`' {
+			i = quoteEnd(query, i)
+		}
+	}
+	return calls
+}
+
+// quoteEnd returns the position of the quote closing the one at i, the quotes are escaped by
+// doubling them, or with a backslash in strings.
+func quoteEnd(query string, i int) int {
+	quote := query[i]
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return i
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
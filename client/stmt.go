@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"time"
 
 	. "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/utils"
@@ -12,12 +13,23 @@ import (
 )
 
 type Stmt struct {
-	conn *Conn
-	id   uint32
+	conn  *Conn
+	id    uint32
+	query string
 
 	params   int
 	columns  int
 	warnings int
+
+	// definitions sent by the server in the prepare response
+	paramFields  []*Field
+	columnFields []*Field
+
+	// parameter types sent with the last execution, the server keeps using them
+	// until new ones are bound
+	boundTypes []byte
+
+	closed bool
 }
 
 func (s *Stmt) ParamNum() int {
@@ -32,7 +44,21 @@ func (s *Stmt) WarningsNum() int {
 	return s.warnings
 }
 
-func (s *Stmt) Execute(args ...interface{}) (*Result, error) {
+// Params returns the definitions of the parameters sent by the server when the statement was
+// prepared. The server doesn't know most of the parameter types, they are usually VAR_STRING.
+func (s *Stmt) Params() []*Field {
+	return s.paramFields
+}
+
+// Columns returns the definitions of the result columns sent by the server when the statement
+// was prepared, the resultset of an execution has the same columns.
+func (s *Stmt) Columns() []*Field {
+	return s.columnFields
+}
+
+func (s *Stmt) Execute(args ...interface{}) (_ *Result, err error) {
+	defer s.conn.observeQuery(COM_STMT_EXECUTE, s.query, args)(&err)
+
 	if err := s.write(args...); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -40,7 +66,9 @@ func (s *Stmt) Execute(args ...interface{}) (*Result, error) {
 	return s.conn.readResult(true)
 }
 
-func (s *Stmt) ExecuteSelectStreaming(result *Result, perRowCb SelectPerRowCallback, perResCb SelectPerResultCallback, args ...interface{}) error {
+func (s *Stmt) ExecuteSelectStreaming(result *Result, perRowCb SelectPerRowCallback, perResCb SelectPerResultCallback, args ...interface{}) (err error) {
+	defer s.conn.observeQuery(COM_STMT_EXECUTE, s.query, args)(&err)
+
 	if err := s.write(args...); err != nil {
 		return errors.Trace(err)
 	}
@@ -48,7 +76,40 @@ func (s *Stmt) ExecuteSelectStreaming(result *Result, perRowCb SelectPerRowCallb
 	return s.conn.readResultStreaming(true, result, perRowCb, perResCb)
 }
 
-func (s *Stmt) Close() error {
+// executeAll executes the statement and reads all the results the server sends, for
+// statements like CALL that may send more than one.
+func (s *Stmt) executeAll(args ...interface{}) (_ []*Result, err error) {
+	defer s.conn.observeQuery(COM_STMT_EXECUTE, s.query, args)(&err)
+
+	if err := s.write(args...); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var results []*Result
+	for {
+		r, err := s.conn.readResult(true)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		results = append(results, r)
+
+		if r.Status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			return results, nil
+		}
+	}
+}
+
+// Close deallocates the statement on the server. It can be called more than once, and after
+// the connection is closed, which already deallocated it; these calls return nil.
+func (s *Stmt) Close() (err error) {
+	if s.closed || s.conn.closed.Load() {
+		s.closed = true
+		return nil
+	}
+	s.closed = true
+
+	defer s.conn.observe(COM_STMT_CLOSE)(&err)
+
 	if err := s.conn.writeCommandUint32(COM_STMT_CLOSE, s.id); err != nil {
 		return errors.Trace(err)
 	}
@@ -71,8 +132,6 @@ func (s *Stmt) write(args ...interface{}) error {
 
 	length := 1 + 4 + 1 + 4 + ((paramsNum + 7) >> 3) + 1 + (paramsNum << 1)
 
-	var newParamBoundFlag byte = 0
-
 	for i := range args {
 		if args[i] == nil {
 			nullBitmap[i/8] |= 1 << (uint(i) % 8)
@@ -80,8 +139,6 @@ func (s *Stmt) write(args ...interface{}) error {
 			continue
 		}
 
-		newParamBoundFlag = 1
-
 		switch v := args[i].(type) {
 		case int8:
 			paramTypes[i<<1] = MYSQL_TYPE_TINY
@@ -137,9 +194,19 @@ func (s *Stmt) write(args ...interface{}) error {
 		case []byte:
 			paramTypes[i<<1] = MYSQL_TYPE_STRING
 			paramValues[i] = append(PutLengthEncodedInt(uint64(len(v))), v...)
+		case []float32:
+			b := AppendVectorValue(nil, v)
+			paramTypes[i<<1] = MYSQL_TYPE_STRING
+			paramValues[i] = append(PutLengthEncodedInt(uint64(len(b))), b...)
 		case json.RawMessage:
 			paramTypes[i<<1] = MYSQL_TYPE_STRING
 			paramValues[i] = append(PutLengthEncodedInt(uint64(len(v))), v...)
+		case time.Time:
+			paramTypes[i<<1] = MYSQL_TYPE_DATETIME
+			paramValues[i] = AppendBinaryDateTime(nil, v)
+		case time.Duration:
+			paramTypes[i<<1] = MYSQL_TYPE_TIME
+			paramValues[i] = AppendBinaryTime(nil, v)
 		default:
 			return fmt.Errorf("invalid argument type %T", args[i])
 		}
@@ -147,6 +214,14 @@ func (s *Stmt) write(args ...interface{}) error {
 		length += len(paramValues[i])
 	}
 
+	// the types are only sent when they differ from the bound ones, NULLs fit any type
+	newParamsBound := s.boundTypes == nil
+	for i := 0; i < paramsNum && !newParamsBound; i++ {
+		if args[i] != nil && (paramTypes[i<<1] != s.boundTypes[i<<1] || paramTypes[(i<<1)+1] != s.boundTypes[(i<<1)+1]) {
+			newParamsBound = true
+		}
+	}
+
 	data := utils.BytesBufferGet()
 	defer func() {
 		utils.BytesBufferPut(data)
@@ -169,25 +244,35 @@ func (s *Stmt) write(args ...interface{}) error {
 		data.Write(nullBitmap)
 
 		//new-params-bound-flag
-		data.WriteByte(newParamBoundFlag)
+		if newParamsBound {
+			data.WriteByte(1)
 
-		if newParamBoundFlag == 1 {
 			//type of each parameter, length: num-params * 2
 			data.Write(paramTypes)
+		} else {
+			data.WriteByte(0)
+		}
 
-			//value of each parameter
-			for _, v := range paramValues {
-				data.Write(v)
-			}
+		//value of each parameter
+		for _, v := range paramValues {
+			data.Write(v)
 		}
 	}
 
 	s.conn.ResetSequence()
 
-	return s.conn.WritePacket(data.Bytes())
+	if err := s.conn.WritePacket(data.Bytes()); err != nil {
+		return errors.Trace(err)
+	}
+	if newParamsBound {
+		s.boundTypes = paramTypes
+	}
+	return nil
 }
 
-func (c *Conn) Prepare(query string) (*Stmt, error) {
+func (c *Conn) Prepare(query string) (_ *Stmt, err error) {
+	defer c.observe(COM_STMT_PREPARE)(&err)
+
 	if err := c.writeCommandStr(COM_STMT_PREPARE, query); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -205,6 +290,7 @@ func (c *Conn) Prepare(query string) (*Stmt, error) {
 
 	s := new(Stmt)
 	s.conn = c
+	s.query = query
 
 	pos := 1
 
@@ -225,13 +311,13 @@ func (c *Conn) Prepare(query string) (*Stmt, error) {
 	// pos += 2
 
 	if s.params > 0 {
-		if err := s.conn.readUntilEOF(); err != nil {
+		if s.paramFields, err = s.conn.readFields(s.params); err != nil {
 			return nil, errors.Trace(err)
 		}
 	}
 
 	if s.columns > 0 {
-		if err := s.conn.readUntilEOF(); err != nil {
+		if s.columnFields, err = s.conn.readFields(s.columns); err != nil {
 			return nil, errors.Trace(err)
 		}
 	}
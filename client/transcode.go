@@ -0,0 +1,128 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/pkg/parser/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// charsetEncodings maps the MySQL charsets to their encoding, the charsets missing
+// from it can't be transcoded. The UTF-8 charsets map to nil.
+var charsetEncodings = map[string]encoding.Encoding{
+	"utf8":     nil,
+	"utf8mb3":  nil,
+	"utf8mb4":  nil,
+	"ascii":    nil,
+	"latin1":   charmap.Windows1252,
+	"latin2":   charmap.ISO8859_2,
+	"latin5":   charmap.ISO8859_9,
+	"latin7":   charmap.ISO8859_13,
+	"greek":    charmap.ISO8859_7,
+	"hebrew":   charmap.ISO8859_8,
+	"cp1250":   charmap.Windows1250,
+	"cp1251":   charmap.Windows1251,
+	"cp1256":   charmap.Windows1256,
+	"cp1257":   charmap.Windows1257,
+	"cp850":    charmap.CodePage850,
+	"cp866":    charmap.CodePage866,
+	"koi8r":    charmap.KOI8R,
+	"koi8u":    charmap.KOI8U,
+	"macroman": charmap.Macintosh,
+	"gbk":      simplifiedchinese.GBK,
+	"gb2312":   simplifiedchinese.GBK,
+	"gb18030":  simplifiedchinese.GB18030,
+	"big5":     traditionalchinese.Big5,
+	"sjis":     japanese.ShiftJIS,
+	"cp932":    japanese.ShiftJIS,
+	"ujis":     japanese.EUCJP,
+	"eucjpms":  japanese.EUCJP,
+	"euckr":    korean.EUCKR,
+	"utf16":    unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"utf16le":  unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+}
+
+// transcoder converts the string values of the columns of a resultset whose charset
+// isn't the one of the connection.
+type transcoder struct {
+	// the transformers of each column, nil for the columns kept as they are
+	columns []transform.Transformer
+}
+
+// newTranscoder returns the transcoder of the rows of fields, nil if TranscodeResults
+// isn't set or no column needs to be transcoded.
+func (c *Conn) newTranscoder(fields []*Field) (*transcoder, error) {
+	if !c.TranscodeResults {
+		return nil, nil
+	}
+
+	connCharset := c.charset
+	if connCharset == "" {
+		connCharset = DEFAULT_CHARSET
+	}
+	connEncoding, ok := charsetEncodings[strings.ToLower(connCharset)]
+	if !ok {
+		return nil, errors.Errorf("cannot transcode results to charset %s", connCharset)
+	}
+
+	var t *transcoder
+	for i, f := range fields {
+		// 63 is the binary charset, of the binary strings and the non string columns
+		if f.Charset == 63 || f.Charset == 0 {
+			continue
+		}
+		collation, err := charset.GetCollationByID(int(f.Charset))
+		if err != nil {
+			return nil, errors.Annotatef(err, "cannot transcode column %s", f.Name)
+		}
+		enc, ok := charsetEncodings[collation.CharsetName]
+		if !ok {
+			return nil, errors.Errorf("cannot transcode column %s from charset %s", f.Name, collation.CharsetName)
+		}
+		if enc == connEncoding {
+			continue
+		}
+
+		var tr transform.Transformer = encoding.Nop.NewDecoder()
+		if enc != nil {
+			tr = enc.NewDecoder()
+		}
+		if connEncoding != nil {
+			tr = transform.Chain(tr, encoding.ReplaceUnsupported(connEncoding.NewEncoder()))
+		}
+
+		if t == nil {
+			t = &transcoder{columns: make([]transform.Transformer, len(fields))}
+		}
+		t.columns[i] = tr
+	}
+	return t, nil
+}
+
+// transcode converts the values of row in place.
+func (t *transcoder) transcode(row []FieldValue) error {
+	if t == nil {
+		return nil
+	}
+	for i, tr := range t.columns {
+		if tr == nil || row[i].Type != FieldValueTypeString {
+			continue
+		}
+		b, _, err := transform.Bytes(tr, row[i].AsString())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		row[i] = NewFieldValue(FieldValueTypeString, 0, b)
+	}
+	return nil
+}
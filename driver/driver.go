@@ -3,14 +3,18 @@
 package driver
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	sqldriver "database/sql/driver"
 	goErrors "errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/url"
-	"regexp"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,11 +25,11 @@ import (
 	"github.com/pingcap/errors"
 )
 
-var customTLSMutex sync.Mutex
+// guards customTLSConfigMap, written by SetCustomTLSConfig while connections may be opened
+var customTLSMutex sync.RWMutex
 
 // Map of dsn address (makes more sense than full dsn?) to tls Config
 var (
-	dsnRegex           = regexp.MustCompile("@[^@]+/[^@/]+")
 	customTLSConfigMap = make(map[string]*tls.Config)
 	options            = make(map[string]DriverOption)
 
@@ -39,11 +43,13 @@ type driver struct {
 
 type connInfo struct {
 	standardDSN bool
-	addr        string
-	user        string
-	password    string
-	db          string
-	params      url.Values
+	// "unix" or "tcp" if given in the DSN, the client picks it from addr otherwise
+	network  string
+	addr     string
+	user     string
+	password string
+	db       string
+	params   url.Values
 }
 
 // ParseDSN takes a DSN string and splits it up into struct containing addr,
@@ -55,20 +61,42 @@ type connInfo struct {
 // Legacy form uses a `?` is used as the path separator: user:password@addr[?db]
 // Standard form uses a `/`: user:password@addr/db?param=value
 //
-// Optional parameters are supported in the standard DSN form
+// Optional parameters are supported in the standard DSN form.
+//
+// The addr can also name its network, like go-sql-driver/mysql: unix(/path/to/socket) or
+// tcp(host:port).
+//
+// The user and password can be percent-encoded. As the credentials end at the last `@`,
+// the password can also contain raw `@`, `:`, `/` and `?` chars, an `@` in the parameters
+// must then be percent-encoded.
 func parseDSN(dsn string) (connInfo, error) {
 	ci := connInfo{}
 
-	// If a "/" occurs after "@" and then no more "@" or "/" occur after that
-	if strings.Contains(dsn, "@") {
-		ci.standardDSN = dsnRegex.MatchString(dsn)
-	} else {
-		// when the `@` char is not present in the dsn, then look for `/` as the db separator
-		// to indicate a standard DSN. The legacy form uses the `?` char as the db separator.
-		// If neither `/` or `?` are in the dsn, simply treat the dsn as the legacy form.
-		ci.standardDSN = strings.Contains(dsn, "/")
+	if i := strings.LastIndex(dsn, "@"); i >= 0 {
+		user, password, _ := strings.Cut(dsn[:i], ":")
+		ci.user = unescapeCredential(user)
+		ci.password = unescapeCredential(password)
+		dsn = dsn[i+1:]
+	}
+
+	for _, network := range []string{"unix", "tcp"} {
+		if !strings.HasPrefix(dsn, network+"(") {
+			continue
+		}
+		end := strings.Index(dsn, ")")
+		if end < 0 {
+			return ci, errors.Errorf("invalid dsn, missing closing parenthesis of %s address", network)
+		}
+		ci.network = network
+		ci.addr = dsn[len(network)+1 : end]
+		dsn = dsn[end+1:]
+		break
 	}
 
+	// The legacy form uses the `?` char as the db separator. If neither `/` or `?` are
+	// in the dsn, simply treat the dsn as the legacy form.
+	ci.standardDSN = strings.Contains(dsn, "/")
+
 	// Add a prefix so we can parse with url.Parse
 	dsn = "mysql://" + dsn
 	parsedDSN, parseErr := url.Parse(dsn)
@@ -76,11 +104,9 @@ func parseDSN(dsn string) (connInfo, error) {
 		return ci, errors.Errorf("invalid dsn, must be user:password@addr[/db[?param=X]]")
 	}
 
-	ci.addr = parsedDSN.Host
-	ci.user = parsedDSN.User.Username()
-	// We ignore the second argument as that is just a flag for existence of a password
-	// If not set we get empty string anyway
-	ci.password, _ = parsedDSN.User.Password()
+	if ci.network == "" {
+		ci.addr = parsedDSN.Host
+	}
 
 	if ci.standardDSN {
 		ci.db = parsedDSN.Path[1:]
@@ -94,78 +120,175 @@ func parseDSN(dsn string) (connInfo, error) {
 	return ci, nil
 }
 
+// unescapeCredential decodes a percent-encoded user or password, a value that is not
+// validly encoded is used as is.
+func unescapeCredential(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
 // Open takes a supplied DSN string and opens a connection
 // See ParseDSN for more information on the form of the DSN
 func (d driver) Open(dsn string) (sqldriver.Conn, error) {
-	var (
-		c *client.Conn
-		// by default database/sql driver retries will be enabled
-		retries = true
-	)
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
 
-	ci, err := parseDSN(dsn)
+var _ sqldriver.DriverContext = driver{}
+
+// connector holds a parsed DSN, it opens connections without parsing it again.
+type connector struct {
+	ci      connInfo
+	timeout time.Duration
+	// by default database/sql driver retries will be enabled
+	retries bool
+	// DATE, DATETIME and TIMESTAMP values are returned as time.Time
+	parseTime bool
+	// the rows of queries are read from the connection as they are scanned
+	streaming bool
+	// ssl=true, the certificate of the server is verified
+	sslVerify bool
+	// the number of prepared statements cached by each connection, 0 disables the cache
+	stmtCacheSize int
+	// the queries may hold several statements
+	multiStatements bool
+	// the arguments of the queries are written in their text rather than prepared
+	interpolateParams bool
+	options           []client.Option
+}
 
+// OpenConnector parses the DSN once for all the connections opened by the returned
+// connector, to be used with sql.OpenDB.
+// See ParseDSN for more information on the form of the DSN
+func (d driver) OpenConnector(dsn string) (sqldriver.Connector, error) {
+	ci, err := parseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	if ci.standardDSN {
-		var timeout time.Duration
-		configuredOptions := make([]client.Option, 0, len(ci.params))
-		for key, value := range ci.params {
-			if key == "ssl" && len(value) > 0 {
-				tlsConfigName := value[0]
-				switch tlsConfigName {
-				case "true":
-					// This actually does insecureSkipVerify
-					// But not even sure if it makes sense to handle false? According to
-					// client_test.go it doesn't - it'd result in an error
-					configuredOptions = append(configuredOptions, UseSslOption)
-				case "custom":
-					// I was too concerned about mimicking what go-sql-driver/mysql does which will
-					// allow any name for a custom tls profile and maps the query parameter value to
-					// that TLSConfig variable... there is no need to be that clever.
-					// Instead of doing that, let's store required custom TLSConfigs in a map that
-					// uses the DSN address as the key
-					configuredOptions = append(configuredOptions, func(c *client.Conn) error {
-						c.SetTLSConfig(customTLSConfigMap[ci.addr])
-						return nil
-					})
-				default:
-					return nil, errors.Errorf("Supported options are ssl=true or ssl=custom")
-				}
-			} else if key == "timeout" && len(value) > 0 {
-				if timeout, err = time.ParseDuration(value[0]); err != nil {
-					return nil, errors.Wrap(err, "invalid duration value for timeout option")
+	c := &connector{ci: ci, retries: true}
+	// No more processing for the legacy DSN. Let's only support url parameters with the newer style DSN
+	if !ci.standardDSN {
+		return c, nil
+	}
+
+	c.options = make([]client.Option, 0, len(ci.params))
+	for key, value := range ci.params {
+		if key == "ssl" && len(value) > 0 {
+			tlsConfigName := value[0]
+			switch tlsConfigName {
+			case "true":
+				// The server certificate is verified against the system roots, for the host
+				// of the DSN. It used to be skipped, as ssl=skip-verify does now.
+				host, _, err := net.SplitHostPort(ci.addr)
+				if err != nil {
+					host = ci.addr
 				}
-			} else if key == "retries" && len(value) > 0 {
-				// by default keep the golang database/sql retry behavior enabled unless
-				// the retries driver option is explicitly set to 'off'
-				retries = !strings.EqualFold(value[0], "off")
+				c.sslVerify = true
+				c.options = append(c.options, func(c *client.Conn) error {
+					c.SetTLSConfig(&tls.Config{ServerName: host})
+					return nil
+				})
+			case "skip-verify":
+				c.options = append(c.options, UseSslOption)
+			case "preferred":
+				c.options = append(c.options, func(c *client.Conn) error {
+					c.PreferTLS(&tls.Config{InsecureSkipVerify: true})
+					return nil
+				})
+			case "custom":
+				// I was too concerned about mimicking what go-sql-driver/mysql does which will
+				// allow any name for a custom tls profile and maps the query parameter value to
+				// that TLSConfig variable... there is no need to be that clever.
+				// Instead of doing that, let's store required custom TLSConfigs in a map that
+				// uses the DSN address as the key
+				c.options = append(c.options, func(c *client.Conn) error {
+					customTLSMutex.RLock()
+					tlsConfig := customTLSConfigMap[ci.addr]
+					customTLSMutex.RUnlock()
+
+					c.SetTLSConfig(tlsConfig)
+					return nil
+				})
+			default:
+				return nil, errors.Errorf("invalid ssl option %q, supported options are ssl=true, ssl=skip-verify, ssl=preferred or ssl=custom", tlsConfigName)
+			}
+		} else if key == "timeout" && len(value) > 0 {
+			if c.timeout, err = time.ParseDuration(value[0]); err != nil {
+				return nil, errors.Wrap(err, "invalid duration value for timeout option")
+			}
+		} else if key == "retries" && len(value) > 0 {
+			// by default keep the golang database/sql retry behavior enabled unless
+			// the retries driver option is explicitly set to 'off'
+			c.retries = !strings.EqualFold(value[0], "off")
+		} else if key == "parseTime" && len(value) > 0 {
+			if c.parseTime, err = strconv.ParseBool(value[0]); err != nil {
+				return nil, errors.Wrap(err, "invalid bool value for parseTime option")
+			}
+		} else if key == "streaming" && len(value) > 0 {
+			if c.streaming, err = strconv.ParseBool(value[0]); err != nil {
+				return nil, errors.Wrap(err, "invalid bool value for streaming option")
+			}
+		} else if key == "multiStatements" && len(value) > 0 {
+			if c.multiStatements, err = strconv.ParseBool(value[0]); err != nil {
+				return nil, errors.Wrap(err, "invalid bool value for multiStatements option")
+			}
+			if c.multiStatements {
+				c.options = append(c.options, func(c *client.Conn) error {
+					c.SetCapability(mysql.CLIENT_MULTI_STATEMENTS)
+					c.SetCapability(mysql.CLIENT_MULTI_RESULTS)
+					return nil
+				})
+			}
+		} else if key == "interpolateParams" && len(value) > 0 {
+			if c.interpolateParams, err = strconv.ParseBool(value[0]); err != nil {
+				return nil, errors.Wrap(err, "invalid bool value for interpolateParams option")
+			}
+		} else if key == "stmtCacheSize" && len(value) > 0 {
+			if c.stmtCacheSize, err = strconv.Atoi(value[0]); err != nil || c.stmtCacheSize < 0 {
+				return nil, errors.Errorf("invalid stmtCacheSize option %q, it must be a number of statements, 0 to disable the cache", value[0])
+			}
+		} else {
+			if option, ok := options[key]; ok {
+				opt := func(o DriverOption, v string) client.Option {
+					return func(c *client.Conn) error {
+						return o(c, v)
+					}
+				}(option, value[0])
+				c.options = append(c.options, opt)
 			} else {
-				if option, ok := options[key]; ok {
-					opt := func(o DriverOption, v string) client.Option {
-						return func(c *client.Conn) error {
-							return o(c, v)
-						}
-					}(option, value[0])
-					configuredOptions = append(configuredOptions, opt)
-				} else {
-					return nil, errors.Errorf("unsupported connection option: %s", key)
-				}
+				return nil, errors.Errorf("unsupported connection option: %s", key)
 			}
 		}
+	}
 
-		if timeout > 0 {
-			c, err = client.ConnectWithTimeout(ci.addr, ci.user, ci.password, ci.db, timeout, configuredOptions...)
-		} else {
-			c, err = client.Connect(ci.addr, ci.user, ci.password, ci.db, configuredOptions...)
-		}
-	} else {
-		// No more processing here. Let's only support url parameters with the newer style DSN
-		c, err = client.Connect(ci.addr, ci.user, ci.password, ci.db)
+	return c, nil
+}
+
+// Connect opens a connection, ctx bounds the time it takes.
+func (c *connector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
 	}
+
+	// the timeout bounds the handshake too, a server accepting connections but not answering
+	// fails the connection as one that can't be reached does
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{Timeout: timeout}
+	mc, err := client.ConnectWithDialer(ctx, c.ci.network, c.ci.addr, c.ci.user, c.ci.password, c.ci.db, dialer.DialContext, c.options...)
 	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if c.sslVerify && goErrors.As(err, &certErr) {
+			return nil, errors.Annotate(err, "ssl=true verifies the certificate of the server, use ssl=skip-verify to connect without verifying it")
+		}
 		return nil, err
 	}
 
@@ -174,18 +297,49 @@ func (d driver) Open(dsn string) (sqldriver.Conn, error) {
 	// the native go-mysql-org/go-mysql 'mysql.ErrBadConn' erorr which will prevent a retry.
 	// In this case the sqldriver.Validator interface is implemented and will return
 	// false for IsValid() signaling the connection is bad and should be discarded.
-	return &conn{Conn: c, state: &state{valid: true, useStdLibErrors: retries}}, nil
+	st := &state{valid: true, useStdLibErrors: c.retries, parseTime: c.parseTime, streaming: c.streaming, multiStatements: c.multiStatements,
+		interpolateParams: c.interpolateParams}
+	if c.stmtCacheSize > 0 {
+		st.stmtCache = newStmtCache(c.stmtCacheSize)
+	}
+	return &conn{Conn: mc, state: st}, nil
+}
+
+func (c *connector) Driver() sqldriver.Driver {
+	return driver{}
 }
 
 type CheckNamedValueFunc func(*sqldriver.NamedValue) error
 
 var _ sqldriver.NamedValueChecker = &conn{}
 var _ sqldriver.Validator = &conn{}
+var _ sqldriver.Pinger = &conn{}
+var _ sqldriver.SessionResetter = &conn{}
+var _ sqldriver.QueryerContext = &conn{}
+var _ sqldriver.ExecerContext = &conn{}
+var _ sqldriver.StmtQueryContext = &stmt{}
+var _ sqldriver.StmtExecContext = &stmt{}
+var _ sqldriver.NamedValueChecker = &stmt{}
+var _ sqldriver.RowsColumnTypeScanType = &rows{}
+var _ sqldriver.RowsColumnTypeDatabaseTypeName = &rows{}
+var _ sqldriver.RowsColumnTypeNullable = &rows{}
+var _ sqldriver.RowsColumnTypeLength = &rows{}
+var _ sqldriver.RowsColumnTypePrecisionScale = &rows{}
+var _ sqldriver.RowsNextResultSet = &rows{}
 
 type state struct {
 	valid bool
+	// the number of statements prepared on the connection and not closed yet
+	openStmts int
 	// when true, the driver connection will return ErrBadConn from the golang Standard Library
 	useStdLibErrors bool
+	// the parseTime, streaming, multiStatements and interpolateParams options of the DSN
+	parseTime         bool
+	streaming         bool
+	multiStatements   bool
+	interpolateParams bool
+	// the statements prepared by query, nil when the stmtCacheSize option is 0
+	stmtCache *stmtCache
 }
 
 type conn struct {
@@ -194,6 +348,12 @@ type conn struct {
 }
 
 func (c *conn) CheckNamedValue(nv *sqldriver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+// checkNamedValue runs the namedValueCheckers, it returns ErrSkip for the default
+// conversion if none handled nv.
+func checkNamedValue(nv *sqldriver.NamedValue) error {
 	for _, nvChecker := range namedValueCheckers {
 		err := nvChecker(nv)
 		if err == nil {
@@ -215,13 +375,30 @@ func (c *conn) IsValid() bool {
 	return c.state.valid
 }
 
+// Prepare prepares query, which may have :name placeholders for named parameters. A query
+// also having ? placeholders is prepared as is, only positional parameters can be bound then.
 func (c *conn) Prepare(query string) (sqldriver.Stmt, error) {
+	var names []string
+	if q, n, err := parseNamedParams(query); err == nil {
+		query, names = q, n
+	}
+
+	if c.state.stmtCache != nil {
+		cs, err := c.state.stmtCache.get(c.Conn, query)
+		if err != nil {
+			return nil, c.state.replyError(err)
+		}
+		c.state.openStmts++
+		return &stmt{Stmt: cs.stmt, conn: c.Conn, connectionState: c.state, names: names, cached: cs}, nil
+	}
+
 	st, err := c.Conn.Prepare(query)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	return &stmt{Stmt: st, connectionState: c.state}, nil
+	c.state.openStmts++
+	return &stmt{Stmt: st, conn: c.Conn, connectionState: c.state, names: names}, nil
 }
 
 func (c *conn) Close() error {
@@ -237,20 +414,48 @@ func (c *conn) Begin() (sqldriver.Tx, error) {
 	return &tx{c.Conn}, nil
 }
 
+// timeArgLayout is the layout of the DATETIME literals time.Time arguments are sent as.
+const timeArgLayout = "2006-01-02 15:04:05.999999"
+
+// buildArgs converts the arguments given by database/sql to the ones of client.Stmt.Execute.
+// time.Time is sent as a DATETIME literal, in its location, the zero time as the zero DATETIME.
+// nil, []byte, string, the numbers and the types of the NamedValueCheckers are kept as they are.
 func buildArgs(args []sqldriver.Value) []interface{} {
 	a := make([]interface{}, len(args))
 
 	for i, arg := range args {
-		a[i] = arg
+		switch v := arg.(type) {
+		case time.Time:
+			if v.IsZero() {
+				a[i] = "0000-00-00 00:00:00"
+			} else {
+				a[i] = v.Format(timeArgLayout)
+			}
+		default:
+			a[i] = arg
+		}
 	}
 
 	return a
 }
 
+// replyStmtError is replyError for the executions of queries and prepared statements.
+// database/sql retries a query failing with driver.ErrBadConn on another connection, which is
+// only safe if the query wasn't sent, as it may have been applied otherwise. The connection is
+// marked bad and the error returned as is then, wrapping mysql.ErrBadConn.
+func (st *state) replyStmtError(err error) error {
+	if st.useStdLibErrors && mysql.ErrorEqual(err, mysql.ErrBadConn) && !goErrors.Is(err, mysql.ErrNotSent) {
+		st.valid = false
+		return errors.Trace(err)
+	}
+	return st.replyError(err)
+}
+
 func (st *state) replyError(err error) error {
 	isBadConnection := mysql.ErrorEqual(err, mysql.ErrBadConn)
 
-	if st.useStdLibErrors && isBadConnection {
+	// a timeout isn't retried by database/sql, the command may still run on the server
+	if st.useStdLibErrors && isBadConnection && !goErrors.Is(err, mysql.ErrTimeout) {
 		return sqldriver.ErrBadConn
 	} else {
 		// if we have a bad connection, this mark the state of this connection as not valid
@@ -261,53 +466,347 @@ func (st *state) replyError(err error) error {
 	}
 }
 
+// watchCancel makes the pending read or write on c fail once ctx is done, by closing the
+// network connection. It returns ctx.Err() if ctx is already done. The returned function
+// stops watching and returns ctx.Err() if the connection was closed, which it is if ctx is
+// done by then, it is then marked bad so the database/sql pool discards it.
+func (st *state) watchCancel(ctx context.Context, c *client.Conn) (func() error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return func() error { return nil }, nil
+	}
+
+	done := make(chan struct{})
+	cancelled := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		if ctx.Err() != nil {
+			// the net.Conn under the packet conn, whose state is left alone
+			_ = c.Conn.Conn.Close()
+			cancelled <- true
+			return
+		}
+		cancelled <- false
+	}()
+
+	return func() error {
+		close(done)
+		if <-cancelled {
+			st.valid = false
+			return ctx.Err()
+		}
+		return nil
+	}, nil
+}
+
+// Ping checks the connection with COM_PING. A connection failing it is reported with
+// driver.ErrBadConn, so the database/sql pool discards it.
+func (c *conn) Ping(ctx context.Context) error {
+	stop, err := c.state.watchCancel(ctx, c.Conn)
+	if err != nil {
+		return err
+	}
+
+	err = c.Conn.Ping()
+	if ctxErr := stop(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		c.state.valid = false
+		return sqldriver.ErrBadConn
+	}
+	return nil
+}
+
+// ResetSession is called by database/sql before reusing a pooled connection. The session is
+// reset with COM_RESET_CONNECTION, unless statements prepared on the connection are still open
+// or cached as it would drop them, only the transaction and the autocommit mode are reset then.
+// A connection failing it is reported with driver.ErrBadConn, so the pool discards it.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if !c.state.valid {
+		return sqldriver.ErrBadConn
+	}
+	stop, err := c.state.watchCancel(ctx, c.Conn)
+	if err != nil {
+		return err
+	}
+
+	if c.state.openStmts == 0 && (c.state.stmtCache == nil || c.state.stmtCache.len() == 0) {
+		err = c.Conn.ResetConnection()
+		var myErr *mysql.MyError
+		if goErrors.As(err, &myErr) && myErr.Code == mysql.ER_UNKNOWN_COM_ERROR {
+			// before MySQL 5.7.3
+			err = c.Conn.ResetSession()
+		}
+	} else {
+		err = c.Conn.ResetSession()
+	}
+	if ctxErr := stop(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		c.state.valid = false
+		return sqldriver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	query, values, err := namedQuery(query, args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := c.state.watchCancel(ctx, c.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Exec(query, values)
+	if ctxErr := stop(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return r, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	query, values, err := namedQuery(query, args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := c.state.watchCancel(ctx, c.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.Query(query, values)
+	return watchRows(r, err, stop)
+}
+
+// watchRows returns the rows of a query run while ctx was watched by watchCancel. The
+// streamed rows are read after the query returns, ctx is watched until they are closed then,
+// and stop is called by their Close.
+func watchRows(r sqldriver.Rows, err error, stop func() error) (sqldriver.Rows, error) {
+	if rs, ok := r.(*rows); ok && err == nil && rs.iter != nil {
+		rs.stop = stop
+		return rs, nil
+	}
+	if ctxErr := stop(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return r, err
+}
+
+// interpolate returns query with the literals of args for the interpolateParams option, or
+// query and args as they are if they must be prepared.
+func (c *conn) interpolate(query string, args []interface{}) (string, []interface{}) {
+	if !c.state.interpolateParams || len(args) == 0 {
+		return query, args
+	}
+	if q, ok := interpolateParams(query, args, c.GetCharset(), c.NoBackslashEscapes()); ok {
+		return q, nil
+	}
+	return query, args
+}
+
 func (c *conn) Exec(query string, args []sqldriver.Value) (sqldriver.Result, error) {
-	a := buildArgs(args)
+	query, a := c.interpolate(query, buildArgs(args))
+	if len(a) > 0 && c.state.stmtCache != nil {
+		cs, err := c.state.stmtCache.get(c.Conn, query)
+		if err != nil {
+			return nil, c.state.replyError(err)
+		}
+		r, err := cs.stmt.Execute(a...)
+		if relErr := c.state.stmtCache.release(cs); err == nil {
+			err = relErr
+		}
+		if err != nil {
+			return nil, c.state.replyStmtError(err)
+		}
+		return &result{r}, nil
+	}
+
+	if len(a) == 0 && c.state.multiStatements {
+		results, err := c.Conn.ExecuteAll(query)
+		if err != nil {
+			return nil, c.state.replyStmtError(err)
+		}
+		// the result of the last statement
+		for _, r := range results[:len(results)-1] {
+			r.Close()
+		}
+		return &result{results[len(results)-1]}, nil
+	}
+
 	r, err := c.Conn.Execute(query, a...)
 	if err != nil {
-		return nil, c.state.replyError(err)
+		return nil, c.state.replyStmtError(err)
 	}
 	return &result{r}, nil
 }
 
 func (c *conn) Query(query string, args []sqldriver.Value) (sqldriver.Rows, error) {
-	a := buildArgs(args)
+	query, a := c.interpolate(query, buildArgs(args))
+	if len(a) > 0 && c.state.stmtCache != nil {
+		return c.queryCached(query, a)
+	}
+	if len(a) == 0 && c.state.multiStatements {
+		// the resultsets of all the statements are read, streaming doesn't apply
+		results, err := c.Conn.ExecuteAll(query)
+		if err != nil {
+			return nil, c.state.replyStmtError(err)
+		}
+		return newMultiRows(results, c.ColumnNameFunc, c.state.parseTime)
+	}
+	if c.state.streaming {
+		it, err := c.Conn.QueryIter(query, a...)
+		if err != nil {
+			return nil, c.state.replyStmtError(err)
+		}
+		return newStreamingRows(it, c.ColumnNameFunc, c.state.parseTime)
+	}
+
 	r, err := c.Conn.Execute(query, a...)
+	if err != nil {
+		return nil, c.state.replyStmtError(err)
+	}
+	return newRows(r.Resultset, c.ColumnNameFunc, c.state.parseTime)
+}
+
+// queryCached runs query with the statement of the cache, which is released once the rows
+// are read.
+func (c *conn) queryCached(query string, args []interface{}) (sqldriver.Rows, error) {
+	cache := c.state.stmtCache
+	cs, err := cache.get(c.Conn, query)
 	if err != nil {
 		return nil, c.state.replyError(err)
 	}
-	return newRows(r.Resultset)
+
+	if c.state.streaming {
+		it, err := cs.stmt.QueryIter(args...)
+		if err != nil {
+			_ = cache.release(cs)
+			return nil, c.state.replyStmtError(err)
+		}
+		rs, err := newStreamingRows(it, c.ColumnNameFunc, c.state.parseTime)
+		if err != nil {
+			_ = it.Close()
+			_ = cache.release(cs)
+			return nil, err
+		}
+		rs.release = func() error { return cache.release(cs) }
+		return rs, nil
+	}
+
+	r, err := cs.stmt.Execute(args...)
+	if relErr := cache.release(cs); err == nil {
+		err = relErr
+	}
+	if err != nil {
+		return nil, c.state.replyStmtError(err)
+	}
+	return newRows(r.Resultset, c.ColumnNameFunc, c.state.parseTime)
 }
 
 type stmt struct {
 	*client.Stmt
+	conn            *client.Conn
 	connectionState *state
+	// the :name placeholders of the query, in order
+	names []string
+	// the entry of the statement cache, released instead of closing the statement
+	cached *cachedStmt
+	closed bool
 }
 
 func (s *stmt) Close() error {
+	if s.cached != nil {
+		if s.closed {
+			return nil
+		}
+		s.closed = true
+		s.connectionState.openStmts--
+		return s.connectionState.stmtCache.release(s.cached)
+	}
+
+	if !s.closed {
+		s.closed = true
+		s.connectionState.openStmts--
+	}
 	return s.Stmt.Close()
 }
 
 func (s *stmt) NumInput() int {
+	// a name may be used more than once
+	if s.names != nil {
+		return -1
+	}
 	return s.Stmt.ParamNum()
 }
 
+func (s *stmt) CheckNamedValue(nv *sqldriver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
 func (s *stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
 	a := buildArgs(args)
 	r, err := s.Stmt.Execute(a...)
 	if err != nil {
-		return nil, s.connectionState.replyError(err)
+		return nil, s.connectionState.replyStmtError(err)
 	}
 	return &result{r}, nil
 }
 
 func (s *stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
 	a := buildArgs(args)
+	if s.connectionState.streaming {
+		it, err := s.Stmt.QueryIter(a...)
+		if err != nil {
+			return nil, s.connectionState.replyStmtError(err)
+		}
+		return newStreamingRows(it, s.conn.ColumnNameFunc, s.connectionState.parseTime)
+	}
+
 	r, err := s.Stmt.Execute(a...)
 	if err != nil {
-		return nil, s.connectionState.replyError(err)
+		return nil, s.connectionState.replyStmtError(err)
+	}
+	return newRows(r.Resultset, s.conn.ColumnNameFunc, s.connectionState.parseTime)
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	values, err := bindNamedParams(s.names, args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := s.connectionState.watchCancel(ctx, s.conn)
+	if err != nil {
+		return nil, err
 	}
-	return newRows(r.Resultset)
+
+	r, err := s.Exec(values)
+	if ctxErr := stop(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return r, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	values, err := bindNamedParams(s.names, args)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := s.connectionState.watchCancel(ctx, s.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.Query(values)
+	return watchRows(r, err, stop)
 }
 
 type tx struct {
@@ -326,36 +825,124 @@ type result struct {
 	*mysql.Result
 }
 
+// LastInsertId returns the id generated for an AUTO_INCREMENT column. An id above
+// math.MaxInt64, of a BIGINT UNSIGNED column, is an error rather than a negative number,
+// the Result of the client package holds it.
 func (r *result) LastInsertId() (int64, error) {
+	if r.Result.InsertId > math.MaxInt64 {
+		return 0, errors.Errorf("last insert id %d overflows int64", r.Result.InsertId)
+	}
 	return int64(r.Result.InsertId), nil
 }
 
+// ErrNoAffectedRows is returned by RowsAffected when the server didn't tell the number of
+// affected rows, like for a statement returning a resultset.
+var ErrNoAffectedRows = errors.New("no affected rows information")
+
+// RowsAffected returns the number of rows changed, an error if it is above math.MaxInt64.
 func (r *result) RowsAffected() (int64, error) {
+	if !r.Result.HasAffectedRows {
+		return 0, ErrNoAffectedRows
+	}
+	if r.Result.AffectedRows > math.MaxInt64 {
+		return 0, errors.Errorf("affected rows %d overflows int64", r.Result.AffectedRows)
+	}
 	return int64(r.Result.AffectedRows), nil
 }
 
 type rows struct {
 	*mysql.Resultset
-
-	columns []string
-	step    int
+	// iter reads the rows when they are streamed, the Resultset only holds the fields then
+	iter *client.RowIter
+
+	columns   []string
+	step      int
+	parseTime bool
+	// release, if set, is called once the rows are closed
+	release func() error
+	// stop, if set, stops watching the context of the query of the streamed rows
+	stop func() error
+
+	// the resultsets of the next statements of a multi statement query, and the function
+	// the column names go through
+	next       []*mysql.Resultset
+	columnName func(string) string
 }
 
-func newRows(r *mysql.Resultset) (*rows, error) {
+// newRows returns the rows of r, whose column names go through columnName if it is not nil.
+// With parseTime, the DATE, DATETIME and TIMESTAMP values are returned as time.Time.
+func newRows(r *mysql.Resultset, columnName func(string) string, parseTime bool) (*rows, error) {
 	if r == nil {
 		return nil, fmt.Errorf("invalid mysql query, no correct result")
 	}
 
-	rs := new(rows)
-	rs.Resultset = r
+	rs := &rows{parseTime: parseTime, columnName: columnName}
+	rs.setResultset(r)
+	return rs, nil
+}
+
+// newMultiRows returns the rows of the resultsets of results, the results of the statements
+// without one are skipped.
+func newMultiRows(results []*mysql.Result, columnName func(string) string, parseTime bool) (*rows, error) {
+	var resultsets []*mysql.Resultset
+	for _, r := range results {
+		if r.Resultset != nil {
+			resultsets = append(resultsets, r.Resultset)
+		}
+	}
+	if len(resultsets) == 0 {
+		return newRows(nil, columnName, parseTime)
+	}
+
+	rs, err := newRows(resultsets[0], columnName, parseTime)
+	if err != nil {
+		return nil, err
+	}
+	rs.next = resultsets[1:]
+	return rs, nil
+}
+
+// setResultset makes r the resultset the rows are read from.
+func (r *rows) setResultset(rs *mysql.Resultset) {
+	r.Resultset = rs
+
+	// the names must outlive the resultset, which is released on Close
+	r.columns = make([]string, len(rs.Fields))
+	for i, f := range rs.Fields {
+		r.columns[i] = string(f.Name)
+		if r.columnName != nil {
+			r.columns[i] = r.columnName(r.columns[i])
+		}
+	}
+	r.step = 0
+}
 
-	rs.columns = make([]string, len(r.Fields))
+// HasNextResultSet reports whether there is another resultset after the current one, for
+// the queries of several statements of the multiStatements option.
+func (r *rows) HasNextResultSet() bool {
+	return r.step != -1 && len(r.next) > 0
+}
 
-	for i, f := range r.Fields {
-		rs.columns[i] = utils.ByteSliceToString(f.Name)
+// NextResultSet advances to the next resultset, the rows of the current one that were not
+// read are discarded. It returns io.EOF when there are no more resultsets.
+func (r *rows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
 	}
-	rs.step = 0
 
+	r.Resultset.Release()
+	r.setResultset(r.next[0])
+	r.next = r.next[1:]
+	return nil
+}
+
+// newStreamingRows returns the rows read by it, one at a time as Next is called.
+func newStreamingRows(it *client.RowIter, columnName func(string) string, parseTime bool) (*rows, error) {
+	rs, err := newRows(&mysql.Resultset{Fields: it.Fields()}, columnName, parseTime)
+	if err != nil {
+		return nil, err
+	}
+	rs.iter = it
 	return rs, nil
 }
 
@@ -363,16 +950,253 @@ func (r *rows) Columns() []string {
 	return r.columns
 }
 
+var (
+	scanTypeInt64       = reflect.TypeOf(int64(0))
+	scanTypeNullInt64   = reflect.TypeOf(sql.NullInt64{})
+	scanTypeUint64      = reflect.TypeOf(uint64(0))
+	scanTypeNullUint64  = reflect.TypeOf(sql.Null[uint64]{})
+	scanTypeFloat64     = reflect.TypeOf(float64(0))
+	scanTypeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
+	scanTypeTime        = reflect.TypeOf(time.Time{})
+	scanTypeNullTime    = reflect.TypeOf(sql.NullTime{})
+	scanTypeRawBytes    = reflect.TypeOf(sql.RawBytes{})
+	scanTypeUnknown     = reflect.TypeOf(new(interface{})).Elem()
+)
+
+// ColumnTypeScanType returns the type matching the values Next returns for the column, a
+// sql.Null type if the column is nullable.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	f := r.Fields[index]
+	nullable := f.Flag&mysql.NOT_NULL_FLAG == 0
+
+	switch f.Type {
+	case mysql.MYSQL_TYPE_TINY, mysql.MYSQL_TYPE_SHORT, mysql.MYSQL_TYPE_INT24, mysql.MYSQL_TYPE_LONG,
+		mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_YEAR:
+		// only a BIGINT UNSIGNED may not fit an int64
+		if f.Type == mysql.MYSQL_TYPE_LONGLONG && f.Flag&mysql.UNSIGNED_FLAG != 0 {
+			if nullable {
+				return scanTypeNullUint64
+			}
+			return scanTypeUint64
+		}
+		if nullable {
+			return scanTypeNullInt64
+		}
+		return scanTypeInt64
+	case mysql.MYSQL_TYPE_FLOAT, mysql.MYSQL_TYPE_DOUBLE:
+		if nullable {
+			return scanTypeNullFloat64
+		}
+		return scanTypeFloat64
+	case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE, mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_TIMESTAMP:
+		if !r.parseTime {
+			return scanTypeRawBytes
+		}
+		if nullable {
+			return scanTypeNullTime
+		}
+		return scanTypeTime
+	case mysql.MYSQL_TYPE_NULL:
+		return scanTypeUnknown
+	default:
+		return scanTypeRawBytes
+	}
+}
+
+// binaryCharset is the collation id of the binary character set, it tells BLOB and
+// BINARY columns from TEXT and CHAR ones sharing the same field type.
+const binaryCharset = 63
+
+// ColumnTypeDatabaseTypeName returns the MySQL type name of the column, e.g. "VARCHAR",
+// "INT UNSIGNED" or "DATETIME".
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	f := r.Fields[index]
+	binary := f.Charset == binaryCharset
+
+	var name string
+	switch f.Type {
+	case mysql.MYSQL_TYPE_DECIMAL, mysql.MYSQL_TYPE_NEWDECIMAL:
+		name = "DECIMAL"
+	case mysql.MYSQL_TYPE_TINY:
+		name = "TINYINT"
+	case mysql.MYSQL_TYPE_SHORT:
+		name = "SMALLINT"
+	case mysql.MYSQL_TYPE_INT24:
+		name = "MEDIUMINT"
+	case mysql.MYSQL_TYPE_LONG:
+		name = "INT"
+	case mysql.MYSQL_TYPE_LONGLONG:
+		name = "BIGINT"
+	case mysql.MYSQL_TYPE_FLOAT:
+		name = "FLOAT"
+	case mysql.MYSQL_TYPE_DOUBLE:
+		name = "DOUBLE"
+	case mysql.MYSQL_TYPE_NULL:
+		return "NULL"
+	case mysql.MYSQL_TYPE_TIMESTAMP, mysql.MYSQL_TYPE_TIMESTAMP2:
+		return "TIMESTAMP"
+	case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE:
+		return "DATE"
+	case mysql.MYSQL_TYPE_TIME, mysql.MYSQL_TYPE_TIME2:
+		return "TIME"
+	case mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_DATETIME2:
+		return "DATETIME"
+	case mysql.MYSQL_TYPE_YEAR:
+		return "YEAR"
+	case mysql.MYSQL_TYPE_BIT:
+		return "BIT"
+	case mysql.MYSQL_TYPE_JSON:
+		return "JSON"
+	case mysql.MYSQL_TYPE_VECTOR:
+		return "VECTOR"
+	case mysql.MYSQL_TYPE_GEOMETRY:
+		return "GEOMETRY"
+	case mysql.MYSQL_TYPE_ENUM:
+		return "ENUM"
+	case mysql.MYSQL_TYPE_SET:
+		return "SET"
+	case mysql.MYSQL_TYPE_TINY_BLOB:
+		if binary {
+			return "TINYBLOB"
+		}
+		return "TINYTEXT"
+	case mysql.MYSQL_TYPE_MEDIUM_BLOB:
+		if binary {
+			return "MEDIUMBLOB"
+		}
+		return "MEDIUMTEXT"
+	case mysql.MYSQL_TYPE_LONG_BLOB:
+		if binary {
+			return "LONGBLOB"
+		}
+		return "LONGTEXT"
+	case mysql.MYSQL_TYPE_BLOB:
+		if binary {
+			return "BLOB"
+		}
+		return "TEXT"
+	case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING:
+		if binary {
+			return "VARBINARY"
+		}
+		return "VARCHAR"
+	case mysql.MYSQL_TYPE_STRING:
+		// ENUM and SET columns are sent as MYSQL_TYPE_STRING with a flag
+		switch {
+		case f.Flag&mysql.ENUM_FLAG != 0:
+			return "ENUM"
+		case f.Flag&mysql.SET_FLAG != 0:
+			return "SET"
+		case binary:
+			return "BINARY"
+		}
+		return "CHAR"
+	default:
+		return ""
+	}
+
+	if f.Flag&mysql.UNSIGNED_FLAG != 0 {
+		name += " UNSIGNED"
+	}
+	return name
+}
+
+// ColumnTypeNullable reports whether the column may be NULL, from its NOT_NULL flag.
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.Fields[index].Flag&mysql.NOT_NULL_FLAG == 0, true
+}
+
+// ColumnTypeLength returns the maximum length in bytes of the values of string and blob
+// columns, ok is false for the other types.
+func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	f := r.Fields[index]
+	switch f.Type {
+	case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING, mysql.MYSQL_TYPE_STRING,
+		mysql.MYSQL_TYPE_TINY_BLOB, mysql.MYSQL_TYPE_MEDIUM_BLOB, mysql.MYSQL_TYPE_LONG_BLOB,
+		mysql.MYSQL_TYPE_BLOB, mysql.MYSQL_TYPE_JSON:
+		return int64(f.ColumnLength), true
+	default:
+		return 0, false
+	}
+}
+
+// ColumnTypePrecisionScale returns the precision and scale of DECIMAL columns, ok is false
+// for the other types.
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	f := r.Fields[index]
+	if f.Type != mysql.MYSQL_TYPE_DECIMAL && f.Type != mysql.MYSQL_TYPE_NEWDECIMAL {
+		return 0, 0, false
+	}
+
+	// the column length counts the sign and the decimal point
+	precision, scale = int64(f.ColumnLength), int64(f.Decimal)
+	if scale > 0 {
+		precision--
+	}
+	if f.Flag&mysql.UNSIGNED_FLAG == 0 {
+		precision--
+	}
+	return precision, scale, true
+}
+
 func (r *rows) Close() error {
+	if r.iter != nil {
+		if r.step == -1 {
+			return nil
+		}
+		r.step = -1
+		var err error
+		if r.stop != nil {
+			// the rows of a cancelled query aren't drained, its connection was closed
+			err = r.stop()
+		}
+		if err == nil {
+			err = r.iter.Close()
+		}
+		if r.release != nil {
+			if relErr := r.release(); err == nil {
+				err = relErr
+			}
+		}
+		return err
+	}
+
+	if r.step != -1 {
+		r.Resultset.Release()
+		r.Resultset = nil
+		for _, rs := range r.next {
+			rs.Release()
+		}
+		r.next = nil
+	}
 	r.step = -1
 	return nil
 }
 
 func (r *rows) Next(dest []sqldriver.Value) error {
+	if r.step == -1 {
+		return io.ErrUnexpectedEOF
+	}
+
+	if r.iter != nil {
+		if !r.iter.Next() {
+			if err := r.iter.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+		row := r.iter.Row()
+		for i := range row {
+			var err error
+			if dest[i], err = r.driverValue(r.Fields[i], row[i].Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if r.step >= r.Resultset.RowNumber() {
 		return io.EOF
-	} else if r.step == -1 {
-		return io.ErrUnexpectedEOF
 	}
 
 	for i := 0; i < r.Resultset.ColumnNumber(); i++ {
@@ -381,7 +1205,10 @@ func (r *rows) Next(dest []sqldriver.Value) error {
 			return err
 		}
 
-		dest[i] = sqldriver.Value(value)
+		dest[i], err = r.driverValue(r.Fields[i], value)
+		if err != nil {
+			return err
+		}
 	}
 
 	r.step++
@@ -389,15 +1216,59 @@ func (r *rows) Next(dest []sqldriver.Value) error {
 	return nil
 }
 
-var driverName = "mysql"
+// driverValue converts a value of the column f to a type of sqldriver.Value. SQL NULL is
+// an untyped nil for every column type, integers are int64, unless an unsigned value
+// doesn't fit, floats are float64, and the other values are []byte or, with parseTime,
+// time.Time.
+func (r *rows) driverValue(f *mysql.Field, value interface{}) (sqldriver.Value, error) {
+	switch v := value.(type) {
+	case []byte:
+		// Empty strings may be held as a nil []byte, which database/sql would scan into
+		// []byte as NULL.
+		if v == nil {
+			return []byte{}, nil
+		}
+		if r.parseTime {
+			switch f.Type {
+			case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE, mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_TIMESTAMP:
+				return parseDateTime(v)
+			}
+		}
+	case uint64:
+		// database/sql scans uint64 into unsigned and string destinations
+		if v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+	}
+	return value, nil
+}
+
+// parseDateTime parses a DATE, DATETIME or TIMESTAMP value in UTC, a zero date like
+// '0000-00-00' is the zero time.Time.
+func parseDateTime(v []byte) (time.Time, error) {
+	s := utils.ByteSliceToString(v)
+	if strings.HasPrefix(s, "0000-00-00") {
+		return time.Time{}, nil
+	}
+
+	layout := "2006-01-02 15:04:05"
+	if len(s) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	t, err := time.ParseInLocation(layout, s, time.UTC)
+	return t, errors.Annotatef(err, "invalid time value %q", s)
+}
 
 func init() {
+	options["allowPublicKeyRetrieval"] = AllowPublicKeyRetrievalOption
 	options["compress"] = CompressOption
+	options["connectionAttributes"] = ConnectionAttributesOption
+	options["charset"] = CharsetOption
 	options["collation"] = CollationOption
 	options["readTimeout"] = ReadTimeoutOption
 	options["writeTimeout"] = WriteTimeoutOption
-
-	sql.Register(driverName, driver{})
+	options["normalizeColumnNames"] = NormalizeColumnNamesOption
+	options["serverPubKey"] = ServerPubKeyOption
 }
 
 // SetCustomTLSConfig sets a custom TLSConfig for the address (host:port) of the supplied DSN.
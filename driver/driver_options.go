@@ -1,6 +1,12 @@
 package driver
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-mysql-org/go-mysql/client"
@@ -21,23 +27,119 @@ func CollationOption(c *client.Conn, value string) error {
 	return c.SetCollation(value)
 }
 
+// CharsetOption sets the charset of the connection, it is ignored when the collation
+// option is set too. An unknown charset fails the connection.
+func CharsetOption(c *client.Conn, value string) error {
+	return c.SetCharset(value)
+}
+
+// ReadTimeoutOption bounds the time each read of the connection takes, a read that times out
+// fails with an error wrapping mysql.ErrTimeout and the connection is discarded.
 func ReadTimeoutOption(c *client.Conn, value string) error {
 	var err error
-	c.ReadTimeout, err = time.ParseDuration(value)
-	return errors.Wrap(err, "invalid duration value for readTimeout option")
+	c.ReadTimeout, err = parseTimeout("readTimeout", value)
+	return err
 }
 
+// WriteTimeoutOption is ReadTimeoutOption for the writes of the connection.
 func WriteTimeoutOption(c *client.Conn, value string) error {
 	var err error
-	c.WriteTimeout, err = time.ParseDuration(value)
-	return errors.Wrap(err, "invalid duration value for writeTimeout option")
+	c.WriteTimeout, err = parseTimeout("writeTimeout", value)
+	return err
+}
+
+// parseTimeout parses the value of the timeout option name, which can't be negative.
+func parseTimeout(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid duration value for %s option", name)
+	}
+	if d < 0 {
+		return 0, errors.Errorf("invalid duration value for %s option: %s is negative", name, value)
+	}
+	return d, nil
+}
+
+// NormalizeColumnNamesOption makes the column names of the rows lowercase and without
+// backticks when the value is true, see client.NormalizeColumnName.
+func NormalizeColumnNamesOption(c *client.Conn, value string) error {
+	normalize, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.Wrap(err, "invalid bool value for normalizeColumnNames option")
+	}
+	if normalize {
+		c.ColumnNameFunc = client.NormalizeColumnName
+	} else {
+		c.ColumnNameFunc = nil
+	}
+	return nil
+}
+
+// ConnectionAttributesOption sets the connection attributes sent in the handshake, which the
+// server shows in performance_schema.session_connect_attrs, from a list of key:value pairs
+// separated by commas like program_name:myapp,env:prod. They are added to the ones of the
+// client, like _client_name, or replace them.
+func ConnectionAttributesOption(c *client.Conn, value string) error {
+	attributes := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, ":")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return errors.Errorf("invalid connectionAttributes option %q, %q is not a key:value pair", value, pair)
+		}
+		if _, dup := attributes[k]; dup {
+			return errors.Errorf("invalid connectionAttributes option %q, the attribute %s is set twice", value, k)
+		}
+		attributes[k] = strings.TrimSpace(v)
+	}
+	c.SetAttributes(attributes)
+	return nil
+}
+
+// AllowPublicKeyRetrievalOption sets whether the RSA public key of the server may be requested
+// from the server for the sha256_password and caching_sha2_password full authentication over a
+// connection without TLS. A server impersonating the real one could send its own key and read the
+// password, with false the authentication fails instead, unless the key is set by ServerPubKeyOption.
+func AllowPublicKeyRetrievalOption(c *client.Conn, value string) error {
+	allow, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.Wrap(err, "invalid bool value for allowPublicKeyRetrieval option")
+	}
+	c.SetAllowPublicKeyRetrieval(allow)
+	return nil
+}
+
+// ServerPubKeyOption reads the RSA public key of the server from the PEM file at the path value,
+// the password is encrypted with it for the sha256_password and caching_sha2_password full
+// authentication over a connection without TLS, instead of requesting the key from the server.
+func ServerPubKeyOption(c *client.Conn, value string) error {
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return errors.Annotate(err, "invalid serverPubKey option")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.Errorf("invalid serverPubKey option, %s is not a PEM file", value)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Annotatef(err, "invalid serverPubKey option, %s", value)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return errors.Errorf("invalid serverPubKey option, %s is not an RSA public key", value)
+	}
+	c.SetServerPubKey(rsaPub)
+	return nil
 }
 
 func CompressOption(c *client.Conn, value string) error {
 	switch value {
 	case "zlib":
+		c.UnsetCapability(mysql.CLIENT_ZSTD_COMPRESSION_ALGORITHM)
 		c.SetCapability(mysql.CLIENT_COMPRESS)
 	case "zstd":
+		c.UnsetCapability(mysql.CLIENT_COMPRESS)
 		c.SetCapability(mysql.CLIENT_ZSTD_COMPRESSION_ALGORITHM)
 	case "uncompressed":
 		c.UnsetCapability(mysql.CLIENT_COMPRESS)
@@ -2,11 +2,14 @@ package driver
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	sqlDriver "database/sql/driver"
 	"fmt"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -22,6 +25,7 @@ import (
 	"github.com/go-mysql-org/go-mysql/client"
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/server"
+	"github.com/go-mysql-org/go-mysql/test_util/test_keys"
 )
 
 var _ server.Handler = &mockHandler{}
@@ -33,10 +37,23 @@ type testServer struct {
 	handler  *mockHandler
 }
 
+// credentialProvider hides the InMemoryProvider from the server, which otherwise never asks
+// the client for the caching_sha2_password full authentication.
+type credentialProvider struct {
+	*server.InMemoryProvider
+}
+
 type mockHandler struct {
 	// the number of times a query executed
 	queryCount atomic.Int32
 	modifier   *sync.WaitGroup
+	// the number of COM_RESET_CONNECTION received
+	resetCount atomic.Int32
+	// the number of statements prepared and closed
+	prepareCount atomic.Int32
+	closeCount   atomic.Int32
+	// the connection attributes of the last connection
+	attributes atomic.Value
 }
 
 func TestDriverOptions_SetRetriesOn(t *testing.T) {
@@ -45,7 +62,7 @@ func TestDriverOptions_SetRetriesOn(t *testing.T) {
 	defer srv.Stop()
 	var wg sync.WaitGroup
 	srv.handler.modifier = &wg
-	wg.Add(3)
+	wg.Add(1)
 
 	conn, err := sql.Open("mysql", "root@127.0.0.1:3307/test?readTimeout=100ms")
 	defer func() {
@@ -56,13 +73,13 @@ func TestDriverOptions_SetRetriesOn(t *testing.T) {
 	rows, err := conn.QueryContext(context.TODO(), "select * from slow;")
 	require.Nil(t, rows)
 
-	// we want to get a golang database/sql/driver ErrBadConn
-	require.ErrorIs(t, err, sqlDriver.ErrBadConn)
+	// the query was sent before timing out, it is not retried as it may have been applied
+	require.NotErrorIs(t, err, sqlDriver.ErrBadConn)
+	require.ErrorIs(t, err, mysql.ErrBadConn)
+	require.ErrorIs(t, err, mysql.ErrTimeout)
 
 	wg.Wait()
-	// here we issue assert that even though we only issued 1 query, that the retries
-	// remained on and there were 3 calls to the DB.
-	require.EqualValues(t, 3, srv.handler.queryCount.Load())
+	require.EqualValues(t, 1, srv.handler.queryCount.Load())
 }
 
 func TestDriverOptions_SetRetriesOff(t *testing.T) {
@@ -83,6 +100,7 @@ func TestDriverOptions_SetRetriesOff(t *testing.T) {
 	require.Nil(t, rows)
 	// we want the native error from this driver implementation
 	require.ErrorIs(t, err, mysql.ErrBadConn)
+	require.ErrorIs(t, err, mysql.ErrTimeout)
 
 	wg.Wait()
 	// here we issue assert that even though we only issued 1 query, that the retries
@@ -95,6 +113,91 @@ func TestDriverOptions_SetCollation(t *testing.T) {
 	err := CollationOption(c, "latin2_bin")
 	require.NoError(t, err)
 	require.Equal(t, "latin2_bin", c.GetCollation())
+
+	err = CollationOption(c, "utf8mb4_unicod_ci")
+	require.ErrorContains(t, err, "unknown collation utf8mb4_unicod_ci, did you mean utf8mb4_unicode_ci")
+	require.EqualError(t, CollationOption(c, "nope"), "unknown collation nope")
+	require.Equal(t, "latin2_bin", c.GetCollation())
+
+	// collations with an id over 255 are set once connected
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?collation=utf8mb4_0900_as_cs")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+}
+
+func TestDriverOptions_SetCharset(t *testing.T) {
+	c := &client.Conn{}
+	require.NoError(t, CharsetOption(c, "utf8mb4"))
+	require.Equal(t, "utf8mb4", c.GetCharset())
+	require.NoError(t, CharsetOption(c, "cp1251"))
+	require.ErrorContains(t, CharsetOption(c, "utf9"), "unknown charset utf9")
+	require.Equal(t, "cp1251", c.GetCharset())
+
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?charset=utf9")
+	require.NoError(t, err)
+	require.ErrorContains(t, db.Ping(), "unknown charset utf9")
+	db.Close()
+
+	charsetOf := func(dsn string) string {
+		db, err := sql.Open("mysql", dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		sc, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		defer sc.Close()
+
+		var charset string
+		require.NoError(t, sc.Raw(func(driverConn interface{}) error {
+			charset = driverConn.(*conn).GetCharset()
+			return nil
+		}))
+		return charset
+	}
+	require.Equal(t, "utf8mb4", charsetOf("root@127.0.0.1:3307/test?charset=utf8mb4"))
+	// the collation wins
+	require.Equal(t, "utf8mb4", charsetOf("root@127.0.0.1:3307/test?charset=latin1&collation=utf8mb4_unicode_ci"))
+}
+
+func TestDriverOptions_SSL(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?ssl=yes")
+	require.ErrorContains(t, err, `invalid ssl option "yes"`)
+
+	ping := func(dsn string) error {
+		db, err := sql.Open("mysql", dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		return db.Ping()
+	}
+
+	// the certificate of the test server isn't trusted
+	err = ping("root@127.0.0.1:3307/test?ssl=true&retries=off")
+	require.ErrorContains(t, err, "use ssl=skip-verify to connect without verifying it")
+	require.NoError(t, ping("root@127.0.0.1:3307/test?ssl=skip-verify"))
+	require.NoError(t, ping("root@127.0.0.1:3307/test?ssl=preferred"))
+
+	// a server without TLS
+	plainSrv := createMockServer(t, server.NewServer("8.0.12", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_NATIVE_PASSWORD, nil, nil), "127.0.0.1:3308")
+	defer plainSrv.Stop()
+
+	require.ErrorContains(t, ping("root@127.0.0.1:3308/test?ssl=skip-verify&retries=off"), "does not support TLS")
+	require.NoError(t, ping("root@127.0.0.1:3308/test?ssl=preferred"))
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3308/test?ssl=preferred")
+	require.NoError(t, err)
+	defer db.Close()
+	var a int64
+	var b string
+	require.NoError(t, db.QueryRow("select a, b from t").Scan(&a, &b))
+	require.Equal(t, "hello world", b)
 }
 
 func TestDriverOptions_SetCompression(t *testing.T) {
@@ -114,6 +217,99 @@ func TestDriverOptions_SetCompression(t *testing.T) {
 	require.False(t, c.HasCapability(mysql.CLIENT_ZSTD_COMPRESSION_ALGORITHM))
 
 	require.Error(t, CompressOption(c, "foo"))
+
+	// the connection is uncompressed if the server doesn't support it
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+	for _, compress := range []string{"zlib", "zstd"} {
+		db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?compress="+compress)
+		require.NoError(t, err)
+		sc, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, sc.Raw(func(driverConn any) error {
+			info := driverConn.(*conn).HandshakeInfo()
+			require.NotContains(t, info.Capabilities, "CLIENT_COMPRESS")
+			require.NotContains(t, info.Capabilities, "CLIENT_ZSTD_COMPRESSION_ALGORITHM")
+			return nil
+		}))
+		var a int64
+		require.NoError(t, sc.QueryRowContext(context.Background(), "select ?", 5).Scan(&a))
+		require.Equal(t, int64(5), a)
+		require.NoError(t, sc.Close())
+		require.NoError(t, db.Close())
+	}
+}
+
+func TestDriverOptions_ConnectionAttributes(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?connectionAttributes=program_name:myapp,env:prod")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+	attributes := srv.handler.attributes.Load().(map[string]string)
+	require.Equal(t, "myapp", attributes["program_name"])
+	require.Equal(t, "prod", attributes["env"])
+	require.Equal(t, "go-mysql", attributes["_client_name"])
+
+	for _, value := range []string{"program_name", "program_name:myapp,", ":myapp", "env:prod,env:dev"} {
+		db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?connectionAttributes="+value)
+		require.NoError(t, err)
+		require.ErrorContains(t, db.Ping(), "invalid connectionAttributes option", value)
+		db.Close()
+	}
+}
+
+func TestDriverOptions_CachingSha2Password(t *testing.T) {
+	pubKeyFile := filepath.Join(t.TempDir(), "server_pub.pem")
+	require.NoError(t, os.WriteFile(pubKeyFile, test_keys.PubPem, 0o600))
+	tlsConf := server.NewServerTLSConfig(test_keys.CaPem, test_keys.CertPem, test_keys.KeyPem, tls.VerifyClientCertIfGiven)
+
+	tests := []struct {
+		params string
+		err    string
+	}{
+		// full authentication with the key requested from the server
+		{params: ""},
+		{params: "allowPublicKeyRetrieval=false", err: "needs the server public key"},
+		{params: "allowPublicKeyRetrieval=false&serverPubKey=" + pubKeyFile},
+		// over TLS the password is sent as is
+		{params: "allowPublicKeyRetrieval=false&ssl=skip-verify"},
+		{params: "allowPublicKeyRetrieval=maybe", err: "invalid bool value for allowPublicKeyRetrieval option"},
+		{params: "serverPubKey=" + filepath.Join(t.TempDir(), "missing.pem"), err: "invalid serverPubKey option"},
+	}
+	for _, test := range tests {
+		// a new server for each connection, the first authentication of a user is always a full one
+		srv := createMockServer(t, server.NewServer("8.0.12", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_CACHING_SHA2_PASSWORD, test_keys.PubPem, tlsConf), "127.0.0.1:3307")
+
+		db, err := sql.Open("mysql", "sha2:secret@127.0.0.1:3307/test?"+test.params)
+		require.NoError(t, err)
+		err = db.Ping()
+		if test.err == "" {
+			require.NoError(t, err, test.params)
+		} else {
+			require.ErrorContains(t, err, test.err, test.params)
+		}
+		db.Close()
+		srv.Stop()
+	}
+}
+
+func TestDriverOptions_NormalizeColumnNames(t *testing.T) {
+	c := &client.Conn{}
+	require.NoError(t, NormalizeColumnNamesOption(c, "true"))
+	require.NotNil(t, c.ColumnNameFunc)
+
+	rs, err := mysql.BuildSimpleTextResultset([]string{"`UserID`", "Name"}, [][]interface{}{{1, "x"}})
+	require.NoError(t, err)
+	r, err := newRows(rs, c.ColumnNameFunc, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"userid", "name"}, r.Columns())
+
+	require.NoError(t, NormalizeColumnNamesOption(c, "false"))
+	require.Nil(t, c.ColumnNameFunc)
+	require.Error(t, NormalizeColumnNamesOption(c, "lower"))
 }
 
 func TestDriverOptions_ConnectTimeout(t *testing.T) {
@@ -134,6 +330,49 @@ func TestDriverOptions_ConnectTimeout(t *testing.T) {
 	conn.Close()
 }
 
+func TestDriverOptions_ConnectTimeoutUnreachable(t *testing.T) {
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?timeout=fast")
+	require.ErrorContains(t, err, "invalid duration value for timeout option")
+
+	// an unroutable address, the dial fails as soon as it times out or the network is unreachable
+	db, err := sql.Open("mysql", "root@10.255.255.1:3306/test?timeout=200ms&retries=off")
+	require.NoError(t, err)
+	defer db.Close()
+	start := time.Now()
+	require.Error(t, db.PingContext(context.Background()))
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	// the context interrupts the dial too
+	db, err = sql.Open("mysql", "root@10.255.255.1:3306/test?timeout=1m&retries=off")
+	require.NoError(t, err)
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	require.Error(t, db.PingContext(ctx))
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	// a server not answering the handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	db, err = sql.Open("mysql", "root@"+l.Addr().String()+"/test?timeout=200ms&retries=off")
+	require.NoError(t, err)
+	defer db.Close()
+	start = time.Now()
+	require.ErrorIs(t, db.PingContext(context.Background()), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
 func TestDriverOptions_BufferSize(t *testing.T) {
 	log.SetLevel(log.LevelDebug)
 	srv := CreateMockServer(t)
@@ -174,6 +413,7 @@ func TestDriverOptions_ReadTimeout(t *testing.T) {
 	rows, err := conn.QueryContext(context.TODO(), "select * from slow;")
 	require.Nil(t, rows)
 	require.Error(t, err)
+	require.ErrorIs(t, err, mysql.ErrTimeout)
 
 	rows, err = conn.QueryContext(context.TODO(), "select * from fast;")
 	require.NotNil(t, rows)
@@ -202,6 +442,12 @@ func TestDriverOptions_writeTimeout(t *testing.T) {
 	require.Nil(t, result)
 	require.NoError(t, conn.Close())
 
+	conn, err = sql.Open("mysql", "root@127.0.0.1:3307/test?writeTimeout=-1s")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(context.TODO(), "select 1;")
+	require.ErrorContains(t, err, "invalid duration value for writeTimeout option: -1s is negative")
+	require.NoError(t, conn.Close())
+
 	// use an almost zero (1ns) writeTimeout to ensure the insert statement
 	// can't write before the timeout. Just want to make sure ExecContext()
 	// will throw an error.
@@ -215,6 +461,7 @@ func TestDriverOptions_writeTimeout(t *testing.T) {
 	result, err = conn.ExecContext(context.TODO(), "insert into slow(a,b) values(1,2);")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "i/o timeout")
+	require.ErrorIs(t, err, mysql.ErrTimeout)
 	require.Nil(t, result)
 
 	conn.Close()
@@ -273,12 +520,404 @@ func TestDriverOptions_namedValueChecker(t *testing.T) {
 	require.True(t, math.MaxUint64 == a)
 }
 
+func TestDriverContextCancel(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, "select * from slow;")
+	require.Nil(t, rows)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	// the closed connection is discarded
+	rows, err = db.QueryContext(context.Background(), "select * from fast;")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	stmt, err := db.Prepare("select a, b from slow where id = ?")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = stmt.ExecContext(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	_, err = db.ExecContext(context.Background(), "insert into fast values (1);")
+	require.NoError(t, err)
+}
+
+func TestDriverConnector(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?unknown=1")
+	require.ErrorContains(t, err, "unsupported connection option")
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?timeout=1s&retries=off")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, c.(*connector).timeout)
+	require.False(t, c.(*connector).retries)
+
+	c2, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?parseTime=true")
+	require.NoError(t, err)
+	require.True(t, c2.(*connector).parseTime)
+	_, err = driver{}.OpenConnector("root@127.0.0.1:3307/test?parseTime=yes")
+	require.ErrorContains(t, err, "invalid bool value for parseTime option")
+
+	db := sql.OpenDB(c)
+	defer db.Close()
+	var a, b string
+	require.NoError(t, db.QueryRow("select * from fast;").Scan(&a, &b))
+	require.Equal(t, "hello world", b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Connect(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDriverPing(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test")
+	require.NoError(t, err)
+	db := sql.OpenDB(c)
+	defer db.Close()
+	require.NoError(t, db.PingContext(context.Background()))
+
+	dc, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc := dc.(*conn)
+	require.NoError(t, mc.Ping(context.Background()))
+
+	// the server is gone
+	require.NoError(t, mc.Conn.Conn.Conn.Close())
+	require.ErrorIs(t, mc.Ping(context.Background()), sqlDriver.ErrBadConn)
+	require.False(t, mc.IsValid())
+}
+
+func TestDriverResetSession(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test")
+	require.NoError(t, err)
+
+	// database/sql resets a connection before reusing it
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for i := 0; i < 3; i++ {
+		_, err = db.Exec("insert into fast values (1);")
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), srv.handler.resetCount.Load())
+
+	dc, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc := dc.(*conn)
+	srv.handler.resetCount.Store(0)
+	require.NoError(t, mc.ResetSession(context.Background()))
+	require.Equal(t, int32(1), srv.handler.resetCount.Load())
+
+	// COM_RESET_CONNECTION would drop the open statement
+	st, err := mc.Prepare("select ?")
+	require.NoError(t, err)
+	require.NoError(t, mc.ResetSession(context.Background()))
+	require.Equal(t, int32(1), srv.handler.resetCount.Load())
+	require.NoError(t, st.Close())
+	require.NoError(t, st.Close())
+	require.NoError(t, mc.ResetSession(context.Background()))
+	require.Equal(t, int32(2), srv.handler.resetCount.Load())
+
+	// the server is gone
+	require.NoError(t, mc.Conn.Conn.Conn.Close())
+	require.ErrorIs(t, mc.ResetSession(context.Background()), sqlDriver.ErrBadConn)
+	require.False(t, mc.IsValid())
+}
+
+func TestDriverStreaming(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?streaming=yes")
+	require.ErrorContains(t, err, "invalid bool value for streaming option")
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?streaming=true")
+	require.NoError(t, err)
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	rs, err := db.Query("select * from table;")
+	require.NoError(t, err)
+	columns, err := rs.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, columns)
+	require.True(t, rs.Next())
+	var a int64
+	var b string
+	require.NoError(t, rs.Scan(&a, &b))
+	require.Equal(t, int64(1), a)
+	require.Equal(t, "hello world", b)
+	require.False(t, rs.Next())
+	require.NoError(t, rs.Err())
+	require.NoError(t, rs.Close())
+
+	// the rows of a prepared statement
+	require.NoError(t, db.QueryRow("select ?", 7).Scan(&a))
+	require.Equal(t, int64(7), a)
+
+	// rows closed before they are all read leave the connection usable
+	rs, err = db.Query("select * from table;")
+	require.NoError(t, err)
+	require.NoError(t, rs.Close())
+	_, err = db.Exec("insert into fast values (1);")
+	require.NoError(t, err)
+
+	dc, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	r, err := dc.(*conn).Query("select * from table;", nil)
+	require.NoError(t, err)
+	require.NotNil(t, r.(*rows).iter)
+	require.NoError(t, r.Close())
+
+	// the context is watched until the rows are closed, they aren't drained once it is done
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err = dc.(*conn).QueryContext(ctx, "select * from table;", nil)
+	require.NoError(t, err)
+	require.True(t, dc.(*conn).IsValid())
+	cancel()
+	require.ErrorIs(t, r.Close(), context.Canceled)
+	require.False(t, dc.(*conn).IsValid())
+}
+
+func TestDriverNamedParams(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var n int64
+	require.NoError(t, db.QueryRow("select :id", sql.Named("id", 42)).Scan(&n))
+	require.Equal(t, int64(42), n)
+
+	stmt, err := db.Prepare("select :id")
+	require.NoError(t, err)
+	defer stmt.Close()
+	require.NoError(t, stmt.QueryRow(sql.Named("id", 7)).Scan(&n))
+	require.Equal(t, int64(7), n)
+
+	err = stmt.QueryRow(sql.Named("other", 7)).Scan(&n)
+	require.ErrorContains(t, err, "missing value for named parameter id")
+	err = stmt.QueryRow(7).Scan(&n)
+	require.ErrorContains(t, err, "positional parameter 1")
+
+	// a query mixing both is prepared as is, for positional parameters
+	stmt2, err := db.Prepare("select ? /* :note */ + :x")
+	require.NoError(t, err)
+	defer stmt2.Close()
+	require.ErrorContains(t, stmt2.QueryRow(sql.Named("x", 7)).Scan(&n), "named parameter x is not in the query")
+}
+
+func TestDriverStmtCache(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?stmtCacheSize=-1")
+	require.ErrorContains(t, err, "invalid stmtCacheSize option")
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?stmtCacheSize=2")
+	require.NoError(t, err)
+	dc, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc := dc.(*conn)
+
+	query := func(q string, v int64) {
+		r, err := mc.Query(q, []sqlDriver.Value{v})
+		require.NoError(t, err)
+		dest := make([]sqlDriver.Value, 1)
+		require.NoError(t, r.Next(dest))
+		require.Equal(t, v, dest[0])
+		require.NoError(t, r.Close())
+	}
+
+	// the statements are prepared once
+	query("select ?", 1)
+	query("select ?", 2)
+	_, err = mc.Exec("insert into fast values (?)", []sqlDriver.Value{int64(1)})
+	require.NoError(t, err)
+	_, err = mc.Exec("insert into fast values (?)", []sqlDriver.Value{int64(2)})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), srv.handler.prepareCount.Load())
+	require.Equal(t, int32(0), srv.handler.closeCount.Load())
+
+	// the least recently used statement is closed beyond the size
+	query("select ?  ", 3)
+	require.Equal(t, int32(3), srv.handler.prepareCount.Load())
+	require.Equal(t, int32(1), srv.handler.closeCount.Load())
+	require.Equal(t, 2, mc.state.stmtCache.len())
+
+	// a statement in use isn't shared, the query is prepared again for this use only
+	st, err := mc.Prepare("select ?")
+	require.NoError(t, err)
+	require.Equal(t, int32(4), srv.handler.prepareCount.Load())
+	require.Equal(t, int32(2), srv.handler.closeCount.Load())
+	query("select ?", 4)
+	require.Equal(t, int32(5), srv.handler.prepareCount.Load())
+
+	// it is closed once released if it was evicted meanwhile,
+	// COM_STMT_CLOSE has no response, the next command tells it was handled
+	query("select ?  ", 5)
+	require.Equal(t, int32(3), srv.handler.closeCount.Load())
+	query("select ? ", 6)
+	require.Equal(t, int32(6), srv.handler.prepareCount.Load())
+	require.Equal(t, int32(3), srv.handler.closeCount.Load())
+	require.NoError(t, st.Close())
+	require.NoError(t, st.Close())
+	require.NoError(t, mc.Ping(context.Background()))
+	require.Equal(t, int32(4), srv.handler.closeCount.Load())
+
+	// COM_RESET_CONNECTION would drop the cached statements
+	require.NoError(t, mc.ResetSession(context.Background()))
+	require.Equal(t, int32(0), srv.handler.resetCount.Load())
+
+	// the rows of a cached statement are streamed too
+	c, err = driver{}.OpenConnector("root@127.0.0.1:3307/test?stmtCacheSize=2&streaming=true")
+	require.NoError(t, err)
+	db := sql.OpenDB(c)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	srv.handler.prepareCount.Store(0)
+	var a int64
+	for i := int64(0); i < 3; i++ {
+		require.NoError(t, db.QueryRow("select ?", i).Scan(&a))
+		require.Equal(t, i, a)
+	}
+	require.Equal(t, int32(1), srv.handler.prepareCount.Load())
+}
+
+func TestDriverInterpolateParams(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	_, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?interpolateParams=yes")
+	require.ErrorContains(t, err, "invalid bool value for interpolateParams option")
+
+	db, err := sql.Open("mysql", "root@127.0.0.1:3307/test?interpolateParams=true")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a single COM_QUERY
+	_, err = db.Exec("insert into fast values (?)", 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), srv.handler.prepareCount.Load())
+
+	// the arguments that can't be interpolated are prepared
+	_, err = db.Exec("insert into fast values (?)", math.NaN())
+	require.NoError(t, err)
+	require.Equal(t, int32(1), srv.handler.prepareCount.Load())
+
+	// the prepared statements are left alone
+	st, err := db.Prepare("select ?")
+	require.NoError(t, err)
+	defer st.Close()
+	var a int64
+	require.NoError(t, st.QueryRow(7).Scan(&a))
+	require.Equal(t, int64(7), a)
+	require.Equal(t, int32(2), srv.handler.prepareCount.Load())
+}
+
+func TestReplyErrorTimeout(t *testing.T) {
+	st := &state{useStdLibErrors: true, valid: true}
+	err := st.replyError(errors.Annotate(mysql.ErrTimeout, "read failed"))
+	require.ErrorIs(t, err, mysql.ErrTimeout)
+	require.NotErrorIs(t, err, sqlDriver.ErrBadConn)
+	require.False(t, st.valid)
+
+	st.valid = true
+	require.Equal(t, sqlDriver.ErrBadConn, st.replyError(errors.Annotate(mysql.ErrBadConn, "write failed")))
+}
+
+func TestDriverStmtBadConn(t *testing.T) {
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3307/test?readTimeout=100ms")
+	require.NoError(t, err)
+	dc, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc := dc.(*conn)
+
+	st, err := mc.Prepare("select * from slow where a = ?")
+	require.NoError(t, err)
+	defer st.Close()
+
+	// the execution was sent, retrying it on another connection could apply it twice
+	_, err = st.(*stmt).Query([]sqlDriver.Value{int64(1)})
+	require.ErrorIs(t, err, mysql.ErrTimeout)
+	require.NotErrorIs(t, err, sqlDriver.ErrBadConn)
+	require.False(t, mc.IsValid())
+
+	// nothing was sent, database/sql prepares the statement again on another connection
+	dc, err = c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc = dc.(*conn)
+	st, err = mc.Prepare("insert into fast values (?)")
+	require.NoError(t, err)
+	require.NoError(t, mc.Conn.Conn.Conn.Close())
+	_, err = st.(*stmt).Exec([]sqlDriver.Value{int64(1)})
+	require.ErrorIs(t, err, sqlDriver.ErrBadConn)
+
+	// the same goes for the queries
+	dc, err = c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc = dc.(*conn)
+	_, err = mc.Query("select * from slow", nil)
+	require.ErrorIs(t, err, mysql.ErrTimeout)
+	require.NotErrorIs(t, err, sqlDriver.ErrBadConn)
+	require.False(t, mc.IsValid())
+
+	dc, err = c.Connect(context.Background())
+	require.NoError(t, err)
+	defer dc.Close()
+	mc = dc.(*conn)
+	require.NoError(t, mc.Conn.Conn.Conn.Close())
+	_, err = mc.Exec("insert into fast values (1)", nil)
+	require.ErrorIs(t, err, sqlDriver.ErrBadConn)
+}
+
 func CreateMockServer(t *testing.T) *testServer {
+	return createMockServer(t, server.NewDefaultServer(), "127.0.0.1:3307")
+}
+
+func createMockServer(t *testing.T, defaultServer *server.Server, addr string) *testServer {
 	inMemProvider := server.NewInMemoryProvider()
 	inMemProvider.AddUser(*testUser, *testPassword)
-	defaultServer := server.NewDefaultServer()
+	// an empty password is never checked, so the auth plugins are tested with another user
+	inMemProvider.AddUser("sha2", "secret")
 
-	l, err := net.Listen("tcp", "127.0.0.1:3307")
+	l, err := net.Listen("tcp", addr)
 	require.NoError(t, err)
 
 	handler := &mockHandler{}
@@ -291,10 +930,11 @@ func CreateMockServer(t *testing.T) *testServer {
 			}
 
 			go func() {
-				co, err := server.NewCustomizedConn(conn, defaultServer, inMemProvider, handler)
+				co, err := server.NewCustomizedConn(conn, defaultServer, &credentialProvider{inMemProvider}, handler)
 				if err != nil {
 					return
 				}
+				handler.attributes.Store(co.Attributes())
 				for {
 					err = co.HandleCommand()
 					if err != nil {
@@ -387,11 +1027,16 @@ func (h *mockHandler) HandleQuery(query string) (*mysql.Result, error) {
 	return h.handleQuery(query, false, nil)
 }
 
+func (h *mockHandler) HandleSetNames(charset string, collation string) error {
+	return nil
+}
+
 func (h *mockHandler) HandleFieldList(table string, fieldWildcard string) ([]*mysql.Field, error) {
 	return nil, nil
 }
 
 func (h *mockHandler) HandleStmtPrepare(query string) (params int, columns int, context interface{}, err error) {
+	h.prepareCount.Add(1)
 	params = 1
 	columns = 2
 	return params, columns, nil, nil
@@ -412,9 +1057,13 @@ func (h *mockHandler) HandleStmtExecute(context interface{}, query string, args
 }
 
 func (h *mockHandler) HandleStmtClose(context interface{}) error {
+	h.closeCount.Add(1)
 	return nil
 }
 
 func (h *mockHandler) HandleOtherCommand(cmd byte, data []byte) error {
+	if cmd == mysql.COM_RESET_CONNECTION {
+		h.resetCount.Add(1)
+	}
 	return nil
 }
@@ -1,16 +1,26 @@
 package driver
 
 import (
+	"database/sql"
+	sqldriver "database/sql/driver"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"net/url"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/test_util"
+	"github.com/go-mysql-org/go-mysql/test_util/test_keys"
 )
 
 var testUser = flag.String("user", "root", "MySQL user")
@@ -77,6 +87,66 @@ func (s *testDriverSuite) TestTransaction() {
 	require.NoError(s.T(), err)
 }
 
+func (s *testDriverSuite) TestTimeArg() {
+	_, err := s.db.Exec("CREATE TABLE IF NOT EXISTS test_time_arg (id INT PRIMARY KEY, dt DATETIME(6), d DATE)")
+	require.NoError(s.T(), err)
+	defer func() {
+		_, err := s.db.Exec("DROP TABLE test_time_arg")
+		require.NoError(s.T(), err)
+	}()
+
+	ts := time.Date(2024, 2, 29, 13, 14, 15, 123456000, time.UTC)
+	_, err = s.db.Exec("INSERT INTO test_time_arg VALUES (?, ?, ?)", 1, ts, ts.Format(time.DateOnly))
+	require.NoError(s.T(), err)
+	_, err = s.db.Exec("INSERT INTO test_time_arg VALUES (?, ?, ?)", 2, nil, nil)
+	require.NoError(s.T(), err)
+
+	var dt, d string
+	require.NoError(s.T(), s.db.QueryRow("SELECT dt, d FROM test_time_arg WHERE dt = ?", ts).Scan(&dt, &d))
+	require.Equal(s.T(), "2024-02-29 13:14:15.123456", dt)
+	require.Equal(s.T(), "2024-02-29", d)
+
+	var null sql.NullString
+	require.NoError(s.T(), s.db.QueryRow("SELECT dt FROM test_time_arg WHERE id = ?", 2).Scan(&null))
+	require.False(s.T(), null.Valid)
+}
+
+func TestRegister(t *testing.T) {
+	require.Contains(t, sql.Drivers(), "mysql")
+
+	Register("go-mysql")
+	Register("go-mysql")
+	Register("mysql")
+	require.Contains(t, sql.Drivers(), "go-mysql")
+
+	srv := CreateMockServer(t)
+	defer srv.Stop()
+	db, err := sql.Open("go-mysql", "root@127.0.0.1:3307/test")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	// a name taken by another driver
+	sql.Register("other-mysql", otherDriver{})
+	require.Panics(t, func() { Register("other-mysql") })
+}
+
+type otherDriver struct {
+	sqldriver.Driver
+}
+
+func TestBuildArgs(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 13, 14, 15, 123456789, time.UTC)
+	args := buildArgs([]sqldriver.Value{
+		nil, int64(1), 1.5, true, "s", []byte("b"),
+		ts, ts.Truncate(time.Second), time.Date(2024, 2, 29, 0, 0, 0, 0, time.FixedZone("", 3600)), time.Time{},
+	})
+	require.Equal(t, []interface{}{
+		nil, int64(1), 1.5, true, "s", []byte("b"),
+		"2024-02-29 13:14:15.123456", "2024-02-29 13:14:15", "2024-02-29 00:00:00", "0000-00-00 00:00:00",
+	}, args)
+}
+
 func TestParseDSN(t *testing.T) {
 	// List of DSNs to test and expected results
 	// Use different numbered domains to more readily see what has failed - since we
@@ -98,6 +168,21 @@ func TestParseDSN(t *testing.T) {
 		"7.domain.com?db":               {standardDSN: false, addr: "7.domain.com", user: "", password: "", db: "db", params: url.Values{}},
 		"8.domain.com/db":               {standardDSN: true, addr: "8.domain.com", user: "", password: "", db: "db", params: url.Values{}},
 		"9.domain.com/db?compress=zlib": {standardDSN: true, addr: "9.domain.com", user: "", password: "", db: "db", params: url.Values{"compress": []string{"zlib"}}},
+
+		// passwords with special chars, raw or percent-encoded
+		"user:p@ss@10.domain.com/db":               {standardDSN: true, addr: "10.domain.com", user: "user", password: "p@ss", db: "db", params: url.Values{}},
+		"user:p:ss@11.domain.com/db":               {standardDSN: true, addr: "11.domain.com", user: "user", password: "p:ss", db: "db", params: url.Values{}},
+		"user:p/ss@12.domain.com/db?ssl=true":      {standardDSN: true, addr: "12.domain.com", user: "user", password: "p/ss", db: "db", params: url.Values{"ssl": []string{"true"}}},
+		"user:p?ss@13.domain.com?db":               {standardDSN: false, addr: "13.domain.com", user: "user", password: "p?ss", db: "db", params: url.Values{}},
+		"user:p@ss:w/rd?@14.domain.com:3306/db":    {standardDSN: true, addr: "14.domain.com:3306", user: "user", password: "p@ss:w/rd?", db: "db", params: url.Values{}},
+		"user:p%40ss%3Aw%2Frd%3F@15.domain.com/db": {standardDSN: true, addr: "15.domain.com", user: "user", password: "p@ss:w/rd?", db: "db", params: url.Values{}},
+		"us%40er:100%@16.domain.com/db":            {standardDSN: true, addr: "16.domain.com", user: "us@er", password: "100%", db: "db", params: url.Values{}},
+
+		// the network named with the addr
+		"user:password@unix(/var/run/mysqld/mysqld.sock)/db": {standardDSN: true, network: "unix", addr: "/var/run/mysqld/mysqld.sock", user: "user", password: "password", db: "db", params: url.Values{}},
+		"user:p@ss@unix(/tmp/mysql.sock)/db?timeout=1s":      {standardDSN: true, network: "unix", addr: "/tmp/mysql.sock", user: "user", password: "p@ss", db: "db", params: url.Values{"timeout": []string{"1s"}}},
+		"unix(/tmp/mysql.sock)?db":                           {standardDSN: false, network: "unix", addr: "/tmp/mysql.sock", user: "", password: "", db: "db", params: url.Values{}},
+		"user@tcp(17.domain.com:3306)/db":                    {standardDSN: true, network: "tcp", addr: "17.domain.com:3306", user: "user", password: "", db: "db", params: url.Values{}},
 	}
 
 	for supplied, expected := range testDSNs {
@@ -106,4 +191,503 @@ func TestParseDSN(t *testing.T) {
 		// Compare that with expected
 		require.Equal(t, expected, actual)
 	}
+
+	_, err := parseDSN("user@unix(/tmp/mysql.sock/db")
+	require.ErrorContains(t, err, "missing closing parenthesis")
+}
+
+func TestRowsCloseReleasesResultset(t *testing.T) {
+	rs, err := mysql.BuildSimpleTextResultset([]string{"a"}, [][]interface{}{{"x"}})
+	require.NoError(t, err)
+
+	r, err := newRows(rs, nil, false)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Nil(t, r.Resultset)
+	require.Equal(t, []string{"a"}, r.Columns())
+
+	require.ErrorIs(t, r.Next(make([]sqldriver.Value, 1)), io.ErrUnexpectedEOF)
+	// closing twice must not release the resultset twice
+	require.NoError(t, r.Close())
+}
+
+func TestRowsNextResultSet(t *testing.T) {
+	resultset := func(names []string, rows ...[]string) *mysql.Resultset {
+		rs := &mysql.Resultset{}
+		for _, name := range names {
+			rs.Fields = append(rs.Fields, &mysql.Field{Name: []byte(name), Type: mysql.MYSQL_TYPE_VAR_STRING})
+		}
+		for _, row := range rows {
+			values := make([]mysql.FieldValue, len(row))
+			for i, v := range row {
+				values[i] = mysql.NewFieldValue(mysql.FieldValueTypeString, 0, []byte(v))
+			}
+			rs.Values = append(rs.Values, values)
+		}
+		return rs
+	}
+	rs1 := resultset([]string{"a"}, []string{"1"}, []string{"2"})
+	rs2 := resultset([]string{"b", "c"}, []string{"3", "4"})
+
+	// the result of a statement without a resultset is skipped
+	r, err := newMultiRows([]*mysql.Result{{Resultset: rs1}, {AffectedRows: 1}, {Resultset: rs2}}, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, r.Columns())
+	dest := make([]sqldriver.Value, 1)
+	require.NoError(t, r.Next(dest))
+	require.Equal(t, []byte("1"), dest[0])
+
+	// the rows not read are discarded
+	require.True(t, r.HasNextResultSet())
+	require.NoError(t, r.NextResultSet())
+	require.Equal(t, []string{"b", "c"}, r.Columns())
+	dest = make([]sqldriver.Value, 2)
+	require.NoError(t, r.Next(dest))
+	require.Equal(t, []sqldriver.Value{[]byte("3"), []byte("4")}, dest)
+	require.ErrorIs(t, r.Next(dest), io.EOF)
+
+	require.False(t, r.HasNextResultSet())
+	require.ErrorIs(t, r.NextResultSet(), io.EOF)
+	require.NoError(t, r.Close())
+
+	_, err = newMultiRows([]*mysql.Result{{AffectedRows: 1}}, nil, false)
+	require.Error(t, err)
+
+	_, err = driver{}.OpenConnector("root@127.0.0.1:3307/test?multiStatements=yes")
+	require.ErrorContains(t, err, "invalid bool value for multiStatements option")
+}
+
+func TestRowsNull(t *testing.T) {
+	// the size of fixed size values in the binary protocol, others are length encoded
+	types := []struct {
+		tp   byte
+		size int
+	}{
+		{mysql.MYSQL_TYPE_TINY, 1}, {mysql.MYSQL_TYPE_SHORT, 2}, {mysql.MYSQL_TYPE_YEAR, 2},
+		{mysql.MYSQL_TYPE_INT24, 4}, {mysql.MYSQL_TYPE_LONG, 4}, {mysql.MYSQL_TYPE_LONGLONG, 8},
+		{mysql.MYSQL_TYPE_FLOAT, 4}, {mysql.MYSQL_TYPE_DOUBLE, 8},
+		{mysql.MYSQL_TYPE_NEWDECIMAL, -1}, {mysql.MYSQL_TYPE_VARCHAR, -1}, {mysql.MYSQL_TYPE_VAR_STRING, -1},
+		{mysql.MYSQL_TYPE_STRING, -1}, {mysql.MYSQL_TYPE_BLOB, -1}, {mysql.MYSQL_TYPE_JSON, -1},
+		{mysql.MYSQL_TYPE_BIT, -1}, {mysql.MYSQL_TYPE_ENUM, -1}, {mysql.MYSQL_TYPE_SET, -1},
+		{mysql.MYSQL_TYPE_GEOMETRY, -1}, {mysql.MYSQL_TYPE_DATE, -1}, {mysql.MYSQL_TYPE_DATETIME, -1},
+		{mysql.MYSQL_TYPE_TIMESTAMP, -1}, {mysql.MYSQL_TYPE_TIME, -1},
+	}
+
+	fields := make([]*mysql.Field, len(types))
+	bitmapLen := (len(types) + 7 + 2) / 8
+	// a row of NULLs and a row of zero values or empty strings
+	nullText, zeroText := mysql.RowData{}, mysql.RowData{}
+	nullBinary := make(mysql.RowData, 1+bitmapLen)
+	zeroBinary := make(mysql.RowData, 1+bitmapLen)
+	for i, c := range types {
+		fields[i] = &mysql.Field{Name: []byte(fmt.Sprintf("c%d", i)), Type: c.tp}
+
+		nullText = append(nullText, 0xfb)
+		nullBinary[1+(i+2)/8] |= 1 << ((i + 2) % 8)
+
+		if c.size > 0 {
+			zeroText = append(zeroText, 1, '0')
+			zeroBinary = append(zeroBinary, make([]byte, c.size)...)
+		} else {
+			zeroText = append(zeroText, 0)
+			zeroBinary = append(zeroBinary, 0)
+		}
+	}
+
+	for _, binary := range []bool{false, true} {
+		rs := &mysql.Resultset{Fields: fields, Binary: binary, RowDatas: []mysql.RowData{nullText, zeroText}}
+		if binary {
+			rs.RowDatas = []mysql.RowData{nullBinary, zeroBinary}
+		}
+		for _, data := range rs.RowDatas {
+			values, err := data.Parse(fields, binary, nil)
+			require.NoError(t, err)
+			rs.Values = append(rs.Values, values)
+		}
+
+		r, err := newRows(rs, nil, false)
+		require.NoError(t, err)
+
+		for _, valid := range []bool{false, true} {
+			dest := make([]sqldriver.Value, len(types))
+			require.NoError(t, r.Next(dest))
+
+			for i, c := range types {
+				msg := fmt.Sprintf("binary %v, type %d", binary, c.tp)
+				if !valid {
+					require.Nil(t, dest[i], msg)
+				}
+
+				switch {
+				case c.tp == mysql.MYSQL_TYPE_FLOAT || c.tp == mysql.MYSQL_TYPE_DOUBLE:
+					var v sql.NullFloat64
+					require.NoError(t, v.Scan(dest[i]), msg)
+					require.Equal(t, valid, v.Valid, msg)
+				case c.size > 0:
+					var v sql.NullInt64
+					require.NoError(t, v.Scan(dest[i]), msg)
+					require.Equal(t, valid, v.Valid, msg)
+				default:
+					var v sql.NullString
+					require.NoError(t, v.Scan(dest[i]), msg)
+					require.Equal(t, valid, v.Valid, msg)
+					if valid {
+						// must not be read as NULL when scanned into []byte
+						require.NotNil(t, dest[i], msg)
+					}
+				}
+			}
+		}
+		require.ErrorIs(t, r.Next(make([]sqldriver.Value, len(types))), io.EOF)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestRowsDriverValue(t *testing.T) {
+	fields := []*mysql.Field{
+		{Name: []byte("u"), Type: mysql.MYSQL_TYPE_LONGLONG, Flag: mysql.UNSIGNED_FLAG},
+		{Name: []byte("i"), Type: mysql.MYSQL_TYPE_LONG},
+		{Name: []byte("f"), Type: mysql.MYSQL_TYPE_DOUBLE},
+		{Name: []byte("d"), Type: mysql.MYSQL_TYPE_DATE},
+		{Name: []byte("dt"), Type: mysql.MYSQL_TYPE_DATETIME},
+		{Name: []byte("ts"), Type: mysql.MYSQL_TYPE_TIMESTAMP},
+		{Name: []byte("s"), Type: mysql.MYSQL_TYPE_VAR_STRING},
+	}
+	lenenc := func(values ...string) mysql.RowData {
+		var data mysql.RowData
+		for _, v := range values {
+			data = append(data, mysql.PutLengthEncodedString([]byte(v))...)
+		}
+		return data
+	}
+	rowDatas := []mysql.RowData{
+		lenenc("18446744073709551615", "-1", "1.5", "2024-01-02", "2024-01-02 03:04:05.123456", "0000-00-00 00:00:00", "2024-01-02"),
+		lenenc("1", "2", "3", "2024-01-02", "2024-01-02 03:04:05", "2024-01-02 03:04:05", "x"),
+	}
+
+	for _, parseTime := range []bool{false, true} {
+		rs := &mysql.Resultset{Fields: fields, RowDatas: rowDatas}
+		for _, data := range rs.RowDatas {
+			values, err := data.Parse(fields, false, nil)
+			require.NoError(t, err)
+			rs.Values = append(rs.Values, values)
+		}
+		r, err := newRows(rs, nil, parseTime)
+		require.NoError(t, err)
+
+		dest := make([]sqldriver.Value, len(fields))
+		require.NoError(t, r.Next(dest))
+		// the unsigned value doesn't fit an int64
+		require.Equal(t, uint64(math.MaxUint64), dest[0])
+		require.Equal(t, int64(-1), dest[1])
+		require.Equal(t, 1.5, dest[2])
+		// a DATE like string stays a string
+		require.Equal(t, []byte("2024-01-02"), dest[6])
+		if parseTime {
+			require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), dest[3])
+			require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC), dest[4])
+			require.Equal(t, time.Time{}, dest[5])
+		} else {
+			require.Equal(t, []byte("2024-01-02"), dest[3])
+			require.Equal(t, []byte("0000-00-00 00:00:00"), dest[5])
+		}
+
+		// database/sql scans it into unsigned destinations
+		var u sql.Null[uint64]
+		require.NoError(t, u.Scan(dest[0]))
+		require.Equal(t, uint64(math.MaxUint64), u.V)
+
+		require.NoError(t, r.Next(dest))
+		require.Equal(t, int64(1), dest[0])
+		require.NoError(t, r.Close())
+	}
+
+	_, err := parseDateTime([]byte("2024-13-01"))
+	require.ErrorContains(t, err, "invalid time value")
+}
+
+func TestRowsColumnTypeScanType(t *testing.T) {
+	notNull := uint16(mysql.NOT_NULL_FLAG)
+	columns := []struct {
+		field    mysql.Field
+		value    string
+		scanType interface{}
+	}{
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONG, Flag: notNull}, "-1", int64(0)},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_TINY}, "1", sql.NullInt64{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONG, Flag: notNull | mysql.UNSIGNED_FLAG}, "4294967295", int64(0)},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONGLONG, Flag: notNull | mysql.UNSIGNED_FLAG}, "18446744073709551615", uint64(0)},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONGLONG, Flag: mysql.UNSIGNED_FLAG}, "1", sql.Null[uint64]{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_DOUBLE, Flag: notNull}, "1.5", float64(0)},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_FLOAT}, "1.5", sql.NullFloat64{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_DATETIME, Flag: notNull}, "2024-01-02 03:04:05", time.Time{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_DATE}, "2024-01-02", sql.NullTime{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_NEWDECIMAL, Flag: notNull}, "1.50", sql.RawBytes{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_VAR_STRING}, "a", sql.RawBytes{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_BLOB, Flag: mysql.BINARY_FLAG}, "b", sql.RawBytes{}},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_JSON}, "{}", sql.RawBytes{}},
+	}
+
+	fields := make([]*mysql.Field, len(columns))
+	var data mysql.RowData
+	for i := range columns {
+		fields[i] = &columns[i].field
+		fields[i].Name = []byte(fmt.Sprintf("c%d", i))
+		data = append(data, mysql.PutLengthEncodedString([]byte(columns[i].value))...)
+	}
+	values, err := data.Parse(fields, false, nil)
+	require.NoError(t, err)
+	rs := &mysql.Resultset{Fields: fields, RowDatas: []mysql.RowData{data}, Values: [][]mysql.FieldValue{values}}
+
+	r, err := newRows(rs, nil, true)
+	require.NoError(t, err)
+	dest := make([]sqldriver.Value, len(columns))
+	require.NoError(t, r.Next(dest))
+	for i, c := range columns {
+		scanType := r.ColumnTypeScanType(i)
+		require.Equal(t, reflect.TypeOf(c.scanType), scanType, i)
+
+		// the value of Next fits the type
+		v := reflect.New(scanType)
+		if scanner, ok := v.Interface().(sql.Scanner); ok {
+			require.NoError(t, scanner.Scan(dest[i]), i)
+		} else {
+			require.True(t, reflect.TypeOf(dest[i]).ConvertibleTo(scanType), i)
+		}
+	}
+
+	// without parseTime, the times are strings
+	r, err = newRows(rs, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, reflect.TypeOf(sql.RawBytes{}), r.ColumnTypeScanType(7))
+}
+
+func TestRowsColumnTypeDatabaseTypeName(t *testing.T) {
+	columns := []struct {
+		field    mysql.Field
+		typeName string
+	}{
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONG}, "INT"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONG, Flag: mysql.UNSIGNED_FLAG}, "INT UNSIGNED"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_LONGLONG, Flag: mysql.UNSIGNED_FLAG}, "BIGINT UNSIGNED"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_NEWDECIMAL}, "DECIMAL"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_DATETIME}, "DATETIME"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: 45}, "VARCHAR"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_VAR_STRING, Charset: binaryCharset, Flag: mysql.BINARY_FLAG}, "VARBINARY"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_STRING, Charset: 45}, "CHAR"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_STRING, Charset: 45, Flag: mysql.ENUM_FLAG}, "ENUM"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_BLOB, Charset: 45}, "TEXT"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_BLOB, Charset: 46, Flag: mysql.BINARY_FLAG}, "TEXT"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_BLOB, Charset: binaryCharset, Flag: mysql.BINARY_FLAG}, "BLOB"},
+		{mysql.Field{Type: mysql.MYSQL_TYPE_JSON, Charset: binaryCharset}, "JSON"},
+	}
+
+	fields := make([]*mysql.Field, len(columns))
+	for i := range columns {
+		fields[i] = &columns[i].field
+	}
+	r, err := newRows(&mysql.Resultset{Fields: fields}, nil, false)
+	require.NoError(t, err)
+	for i, c := range columns {
+		require.Equal(t, c.typeName, r.ColumnTypeDatabaseTypeName(i), i)
+	}
+}
+
+func TestRowsColumnTypeNullableLength(t *testing.T) {
+	fields := []*mysql.Field{
+		{Type: mysql.MYSQL_TYPE_LONG, Flag: mysql.NOT_NULL_FLAG, ColumnLength: 11},
+		{Type: mysql.MYSQL_TYPE_VAR_STRING, ColumnLength: 1020},
+		{Type: mysql.MYSQL_TYPE_BLOB, Flag: mysql.NOT_NULL_FLAG | mysql.BLOB_FLAG, ColumnLength: 65535},
+		{Type: mysql.MYSQL_TYPE_DATETIME, ColumnLength: 19},
+	}
+	r, err := newRows(&mysql.Resultset{Fields: fields}, nil, false)
+	require.NoError(t, err)
+
+	for i, expected := range []bool{false, true, false, true} {
+		nullable, ok := r.ColumnTypeNullable(i)
+		require.True(t, ok)
+		require.Equal(t, expected, nullable, i)
+	}
+
+	for i, expected := range []int64{0, 1020, 65535, 0} {
+		length, ok := r.ColumnTypeLength(i)
+		require.Equal(t, expected != 0, ok, i)
+		require.Equal(t, expected, length, i)
+	}
+}
+
+func TestRowsColumnTypePrecisionScale(t *testing.T) {
+	fields := []*mysql.Field{
+		// DECIMAL(10,2)
+		{Type: mysql.MYSQL_TYPE_NEWDECIMAL, ColumnLength: 12, Decimal: 2},
+		// DECIMAL(10,2) UNSIGNED
+		{Type: mysql.MYSQL_TYPE_NEWDECIMAL, Flag: mysql.UNSIGNED_FLAG, ColumnLength: 11, Decimal: 2},
+		// DECIMAL(5,0)
+		{Type: mysql.MYSQL_TYPE_NEWDECIMAL, ColumnLength: 6},
+		{Type: mysql.MYSQL_TYPE_DOUBLE, ColumnLength: 22, Decimal: 31},
+	}
+	r, err := newRows(&mysql.Resultset{Fields: fields}, nil, false)
+	require.NoError(t, err)
+
+	for i, expected := range [][2]int64{{10, 2}, {10, 2}, {5, 0}} {
+		precision, scale, ok := r.ColumnTypePrecisionScale(i)
+		require.True(t, ok, i)
+		require.Equal(t, expected, [2]int64{precision, scale}, i)
+	}
+
+	_, _, ok := r.ColumnTypePrecisionScale(3)
+	require.False(t, ok)
+}
+
+func TestResultRowsAffected(t *testing.T) {
+	n, err := (&result{&mysql.Result{AffectedRows: 2, HasAffectedRows: true}}).RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	_, err = (&result{&mysql.Result{}}).RowsAffected()
+	require.ErrorIs(t, err, ErrNoAffectedRows)
+
+	n, err = (&result{&mysql.Result{AffectedRows: math.MaxInt64, HasAffectedRows: true}}).RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), n)
+	_, err = (&result{&mysql.Result{AffectedRows: math.MaxInt64 + 1, HasAffectedRows: true}}).RowsAffected()
+	require.ErrorContains(t, err, "affected rows 9223372036854775808 overflows int64")
+}
+
+func TestResultLastInsertId(t *testing.T) {
+	for _, id := range []uint64{0, 1, math.MaxInt64} {
+		n, err := (&result{&mysql.Result{InsertId: id}}).LastInsertId()
+		require.NoError(t, err)
+		require.Equal(t, int64(id), n)
+	}
+
+	for _, id := range []uint64{math.MaxInt64 + 1, math.MaxUint64} {
+		_, err := (&result{&mysql.Result{InsertId: id}}).LastInsertId()
+		require.ErrorContains(t, err, fmt.Sprintf("last insert id %d overflows int64", id))
+	}
+}
+
+func TestInterpolateParams(t *testing.T) {
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  string
+	}{
+		{"SELECT ?, ?, ?, ?", []interface{}{nil, int64(-1), uint64(math.MaxUint64), true}, "SELECT NULL, -1, 18446744073709551615, 1"},
+		{"SELECT ?, ?", []interface{}{1.5, float32(0.1)}, "SELECT 1.5, 0.1"},
+		{"SELECT ?", []interface{}{"it's"}, `SELECT 'it\'s'`},
+		{"SELECT ?", []interface{}{`\' OR 1=1 -- `}, `SELECT '\\\' OR 1=1 -- '`},
+		{"SELECT ?", []interface{}{"a\x00\n\r\x1a\"b"}, `SELECT 'a\0\n\r\Z\"b'`},
+		{"SELECT ?, ?", []interface{}{[]byte("'\\\x00"), []byte(nil)}, "SELECT _binary X'275c00', NULL"},
+		// the placeholders in literals and comments are left alone
+		{"SELECT '?', \"?\", `?`, 'it''s ?', 'a\\'?' /* ? */, ? # ?\n, ? -- ?", []interface{}{int64(1), int64(2)},
+			"SELECT '?', \"?\", `?`, 'it''s ?', 'a\\'?' /* ? */, 1 # ?\n, 2 -- ?"},
+	}
+	for _, tt := range tests {
+		got, ok := interpolateParams(tt.query, tt.args, "utf8mb4", false)
+		require.True(t, ok, tt.query)
+		require.Equal(t, tt.want, got)
+	}
+
+	// with NO_BACKSLASH_ESCAPES, a backslash doesn't escape the quote
+	got, ok := interpolateParams("SELECT 'a\\', ?", []interface{}{`\' OR 1=1 -- `}, "utf8", true)
+	require.True(t, ok)
+	require.Equal(t, `SELECT 'a\', '\'' OR 1=1 -- '`, got)
+
+	// the query is prepared when it can't be interpolated safely
+	for _, tt := range []struct {
+		query   string
+		args    []interface{}
+		charset string
+	}{
+		{"SELECT ?", []interface{}{"\xbf'"}, "gbk"},
+		{"SELECT ?", []interface{}{math.NaN()}, "utf8mb4"},
+		{"SELECT ?", []interface{}{math.Inf(1)}, "utf8mb4"},
+		{"SELECT ?", []interface{}{struct{}{}}, "utf8mb4"},
+		{"SELECT ?, ?", []interface{}{int64(1)}, "utf8mb4"},
+		{"SELECT ?", []interface{}{int64(1), int64(2)}, "utf8mb4"},
+		{"SELECT '?", []interface{}{int64(1)}, "utf8mb4"},
+		{"SELECT ? /* ?", []interface{}{int64(1)}, "utf8mb4"},
+	} {
+		_, ok := interpolateParams(tt.query, tt.args, tt.charset, false)
+		require.False(t, ok, tt.query)
+	}
+}
+
+func TestParseNamedParams(t *testing.T) {
+	query, names, err := parseNamedParams("SELECT * FROM t WHERE id = :id AND (a = :a_1 OR b = :id) AND c = ':x' AND d = `:y` AND e = \"\\\":z\"")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM t WHERE id = ? AND (a = ? OR b = ?) AND c = ':x' AND d = `:y` AND e = \"\\\":z\"", query)
+	require.Equal(t, []string{"id", "a_1", "id"}, names)
+
+	query, names, err = parseNamedParams("SELECT @a := 1, ? FROM t WHERE s = '?'")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT @a := 1, ? FROM t WHERE s = '?'", query)
+	require.Nil(t, names)
+
+	_, _, err = parseNamedParams("SELECT ? FROM t WHERE id = :id")
+	require.ErrorContains(t, err, "mixes named and positional")
+
+	// comments are left alone, even with quotes
+	for _, q := range []string{
+		"SELECT a /* note:x it's */ FROM t WHERE id = ?",
+		"SELECT a FROM t WHERE id = ? -- see:doc, it's\n",
+		"SELECT a FROM t WHERE id = ? # see:doc",
+		"SELECT a FROM t WHERE id = ? /* see:doc",
+	} {
+		query, names, err = parseNamedParams(q)
+		require.NoError(t, err, q)
+		require.Equal(t, q, query)
+		require.Nil(t, names)
+	}
+	query, names, err = parseNamedParams("SELECT a /* it's */ FROM t WHERE id = :id -- it's :x\nAND b = :b")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT a /* it's */ FROM t WHERE id = ? -- it's :x\nAND b = ?", query)
+	require.Equal(t, []string{"id", "b"}, names)
+}
+
+func TestBindNamedParams(t *testing.T) {
+	values, err := bindNamedParams([]string{"id", "a", "id"}, []sqldriver.NamedValue{
+		{Name: "a", Ordinal: 1, Value: "x"},
+		{Name: "id", Ordinal: 2, Value: int64(1)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []sqldriver.Value{int64(1), "x", int64(1)}, values)
+
+	values, err = bindNamedParams(nil, []sqldriver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+	require.NoError(t, err)
+	require.Equal(t, []sqldriver.Value{int64(1)}, values)
+
+	_, err = bindNamedParams([]string{"id"}, []sqldriver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+	require.ErrorContains(t, err, "positional parameter 1")
+	_, err = bindNamedParams([]string{"id"}, []sqldriver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}})
+	require.ErrorContains(t, err, "missing value for named parameter id")
+	_, err = bindNamedParams([]string{"id"}, []sqldriver.NamedValue{
+		{Name: "id", Ordinal: 1, Value: int64(1)},
+		{Name: "a", Ordinal: 2, Value: int64(1)},
+	})
+	require.ErrorContains(t, err, "named parameter a is not in the query")
+	_, err = bindNamedParams(nil, []sqldriver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}})
+	require.ErrorContains(t, err, "named parameter a is not in the query")
+}
+
+func TestCustomTLSConfigConcurrency(t *testing.T) {
+	c, err := driver{}.OpenConnector("root@127.0.0.1:3306/test?ssl=custom")
+	require.NoError(t, err)
+	options := c.(*connector).options
+	require.Len(t, options, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, SetCustomTLSConfig("mysql://root@127.0.0.1:3306/test", test_keys.CaPem, []byte{}, []byte{}, false, "localhost"))
+		}()
+		go func() {
+			defer wg.Done()
+			require.NoError(t, options[0](&client.Conn{}))
+		}()
+	}
+	wg.Wait()
 }
@@ -0,0 +1,154 @@
+package driver
+
+import (
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// interpolateCharsets are the connection charsets in which a backslash or a quote is never a
+// byte of a multibyte character, so the escaped strings can't be read differently by the server.
+var interpolateCharsets = map[string]bool{
+	"utf8":    true,
+	"utf8mb3": true,
+	"utf8mb4": true,
+	"latin1":  true,
+	"ascii":   true,
+	"binary":  true,
+}
+
+// interpolateParams returns query with its ? placeholders replaced by the literals of args,
+// for the interpolateParams option. ok is false if it can't be done safely, in a charset
+// missing from interpolateCharsets, for an argument of another type than nil, the integers,
+// the floats, bool, []byte and string, or if the placeholders don't match args; the query is
+// prepared then. With noBackslashEscapes, the sql_mode NO_BACKSLASH_ESCAPES of the session,
+// the quotes are doubled instead of escaped with a backslash.
+func interpolateParams(query string, args []interface{}, charset string, noBackslashEscapes bool) (_ string, ok bool) {
+	if !interpolateCharsets[strings.ToLower(charset)] {
+		return "", false
+	}
+
+	var (
+		b     strings.Builder
+		n     int
+		quote byte
+	)
+	b.Grow(len(query) + 16*len(args))
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' && !noBackslashEscapes && i+1 < len(query) {
+				b.WriteByte(ch)
+				i++
+				ch = query[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '#' || (ch == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || query[i+2] <= ' ')):
+			// a comment up to the end of the line
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+			continue
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(query[i : i+2+end+2])
+			i += 2 + end + 1
+			continue
+		case ch == '?':
+			if n == len(args) {
+				return "", false
+			}
+			if !appendLiteral(&b, args[n], noBackslashEscapes) {
+				return "", false
+			}
+			n++
+			continue
+		}
+		b.WriteByte(ch)
+	}
+
+	if quote != 0 || n != len(args) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// appendLiteral writes the SQL literal of v, it returns false for the types it doesn't support.
+func appendLiteral(b *strings.Builder, v interface{}, noBackslashEscapes bool) bool {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("NULL")
+	case int:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int8:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int16:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int32:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case uint:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		b.WriteString(strconv.FormatUint(v, 10))
+	case float32:
+		return appendFloat(b, float64(v), 32)
+	case float64:
+		return appendFloat(b, v, 64)
+	case bool:
+		if v {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	case []byte:
+		if v == nil {
+			b.WriteString("NULL")
+			break
+		}
+		// hexadecimal, whatever the bytes are
+		b.WriteString("_binary X'")
+		b.WriteString(hex.EncodeToString(v))
+		b.WriteByte('\'')
+	case string:
+		b.WriteByte('\'')
+		if noBackslashEscapes {
+			b.WriteString(strings.ReplaceAll(v, "'", "''"))
+		} else {
+			b.WriteString(mysql.Escape(v))
+		}
+		b.WriteByte('\'')
+	default:
+		return false
+	}
+	return true
+}
+
+// appendFloat writes f, NaN and the infinities have no literal.
+func appendFloat(b *strings.Builder, f float64, bitSize int) bool {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return false
+	}
+	b.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	return true
+}
@@ -0,0 +1,143 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// parseNamedParams replaces the :name placeholders of query by ? and returns the names in
+// the order they appear, names is nil if query has none. Placeholders in quoted strings,
+// identifiers and comments are left alone, and query can't mix :name and ? placeholders.
+func parseNamedParams(query string) (string, []string, error) {
+	var (
+		b          strings.Builder
+		names      []string
+		positional bool
+		quote      byte
+	)
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' && i+1 < len(query) {
+				b.WriteByte(ch)
+				i++
+				ch = query[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '#' || (ch == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || query[i+2] <= ' ')):
+			// a comment up to the end of the line
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+			continue
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				// unterminated, the server reports it
+				end = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+2+end+2])
+			i += 2 + end + 1
+			continue
+		case ch == '?':
+			positional = true
+		case ch == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			end := i + 2
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			names = append(names, query[i+1:end])
+			b.WriteByte('?')
+			i = end - 1
+			continue
+		}
+		b.WriteByte(ch)
+	}
+
+	if names == nil {
+		return query, nil, nil
+	}
+	if positional {
+		return "", nil, errors.Errorf("query mixes named and positional parameters")
+	}
+	return b.String(), names, nil
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNamePart(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+// bindNamedParams returns the values of args for the placeholders names returned by
+// parseNamedParams: all the args must be named and used if there are names, and none
+// of them otherwise.
+func bindNamedParams(names []string, args []sqldriver.NamedValue) ([]sqldriver.Value, error) {
+	if names == nil {
+		values := make([]sqldriver.Value, len(args))
+		for i, arg := range args {
+			if arg.Name != "" {
+				return nil, errors.Errorf("named parameter %s is not in the query", arg.Name)
+			}
+			values[i] = arg.Value
+		}
+		return values, nil
+	}
+
+	named := make(map[string]sqldriver.Value, len(args))
+	for _, arg := range args {
+		if arg.Name == "" {
+			return nil, errors.Errorf("positional parameter %d given to a query with named parameters", arg.Ordinal)
+		}
+		named[arg.Name] = arg.Value
+	}
+
+	used := make(map[string]bool, len(names))
+	values := make([]sqldriver.Value, len(names))
+	for i, name := range names {
+		v, ok := named[name]
+		if !ok {
+			return nil, errors.Errorf("missing value for named parameter %s", name)
+		}
+		values[i] = v
+		used[name] = true
+	}
+	for _, arg := range args {
+		if !used[arg.Name] {
+			return nil, errors.Errorf("named parameter %s is not in the query", arg.Name)
+		}
+	}
+	return values, nil
+}
+
+// namedQuery returns query with ? placeholders and the values to bind to them, it is
+// rewritten only if args are named.
+func namedQuery(query string, args []sqldriver.NamedValue) (string, []sqldriver.Value, error) {
+	var names []string
+	for _, arg := range args {
+		if arg.Name != "" {
+			var err error
+			if query, names, err = parseNamedParams(query); err != nil {
+				return "", nil, err
+			}
+			break
+		}
+	}
+
+	values, err := bindNamedParams(names, args)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, values, nil
+}
@@ -0,0 +1,26 @@
+package driver
+
+import (
+	"database/sql"
+	"sync"
+)
+
+var (
+	registerMutex sync.Mutex
+	// the names the driver was registered with
+	registered = make(map[string]bool)
+)
+
+// Register makes the driver available to sql.Open by the given name too, e.g. to be used
+// with another MySQL driver registered as "mysql". Registering the same name again is a no-op,
+// but, like sql.Register, it panics if another driver is registered with the name.
+func Register(name string) {
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+
+	if registered[name] {
+		return
+	}
+	sql.Register(name, driver{})
+	registered[name] = true
+}
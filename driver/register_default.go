@@ -0,0 +1,22 @@
+//go:build !gomysql_noregister
+
+package driver
+
+import (
+	"database/sql"
+	"slices"
+)
+
+// the name the driver is registered with by default
+var driverName = "mysql"
+
+// The driver is registered as "mysql", unless another driver, like github.com/go-sql-driver/mysql,
+// was registered with that name before. Building with the gomysql_noregister tag leaves the name
+// to a driver registered after this one, the driver can still be registered by another name
+// with Register.
+func init() {
+	if slices.Contains(sql.Drivers(), driverName) {
+		return
+	}
+	Register(driverName)
+}
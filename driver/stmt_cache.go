@@ -0,0 +1,92 @@
+package driver
+
+import (
+	"container/list"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/pingcap/errors"
+)
+
+// stmtCache keeps the statements prepared on a connection by query, up to size of them, so the
+// queries run again reuse them instead of being prepared again. The least recently used
+// statement is closed once there are more, or when it is released if it is in use then.
+// Like the connection, it is not safe for concurrent use.
+type stmtCache struct {
+	size  int
+	order *list.List // of *cachedStmt, the most recently used first
+	stmts map[string]*list.Element
+}
+
+type cachedStmt struct {
+	query string
+	stmt  *client.Stmt
+	// a statement is used by one caller at a time, between get and release
+	inUse bool
+	// the statement isn't in the cache anymore, it is closed when released
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		order: list.New(),
+		stmts: make(map[string]*list.Element),
+	}
+}
+
+// get returns the statement of query, prepared on conn if it is not cached yet. A cached
+// statement still in use, like one of a prepared statement not closed yet or of rows being
+// read, is not returned, the query is prepared again for this use only then.
+// The statement must be released once it is not used anymore.
+func (c *stmtCache) get(conn *client.Conn, query string) (*cachedStmt, error) {
+	e, ok := c.stmts[query]
+	if ok {
+		cs := e.Value.(*cachedStmt)
+		if !cs.inUse {
+			c.order.MoveToFront(e)
+			cs.inUse = true
+			return cs, nil
+		}
+	}
+
+	if !ok {
+		// the least recently used statements make room before the new one is prepared,
+		// so the server doesn't hold more than size of them
+		for c.order.Len() >= c.size {
+			old := c.order.Remove(c.order.Back()).(*cachedStmt)
+			delete(c.stmts, old.query)
+			old.evicted = true
+			if !old.inUse {
+				if err := old.stmt.Close(); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+		}
+	}
+
+	st, err := conn.Prepare(query)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	cs := &cachedStmt{query: query, stmt: st, inUse: true}
+	if ok {
+		cs.evicted = true
+		return cs, nil
+	}
+	c.stmts[query] = c.order.PushFront(cs)
+	return cs, nil
+}
+
+// release ends the use of a statement returned by get, closing it if it isn't cached.
+func (c *stmtCache) release(cs *cachedStmt) error {
+	cs.inUse = false
+	if cs.evicted {
+		return errors.Trace(cs.stmt.Close())
+	}
+	return nil
+}
+
+// len returns the number of cached statements.
+func (c *stmtCache) len() int {
+	return c.order.Len()
+}
@@ -20,6 +20,9 @@ import (
 type Dumper struct {
 	// mysqldump execution path, like mysqldump or /usr/bin/mysqldump, etc...
 	ExecutionPath string
+	// Arguments passed before the mysqldump arguments, useful when ExecutionPath
+	// is a wrapper, like `docker exec mysql mysqldump`.
+	ExecutionArgs []string
 
 	Addr     string
 	User     string
@@ -55,6 +58,12 @@ type Dumper struct {
 }
 
 func NewDumper(executionPath string, addr string, user string, password string) (*Dumper, error) {
+	return NewDumperWithArgs(executionPath, nil, addr, user, password)
+}
+
+// NewDumperWithArgs is like NewDumper, but executionArgs are always passed to the
+// execution before the mysqldump arguments.
+func NewDumperWithArgs(executionPath string, executionArgs []string, addr string, user string, password string) (*Dumper, error) {
 	var path string
 	var err error
 
@@ -76,6 +85,7 @@ func NewDumper(executionPath string, addr string, user string, password string)
 
 	d := new(Dumper)
 	d.ExecutionPath = path
+	d.ExecutionArgs = executionArgs
 	d.Addr = addr
 	d.User = user
 	d.Password = password
@@ -86,7 +96,8 @@ func NewDumper(executionPath string, addr string, user string, password string)
 	d.ExtraOptions = make([]string, 0, 5)
 	d.masterDataSkipped = false
 
-	out, err := exec.Command(d.ExecutionPath, `--help`).CombinedOutput()
+	helpArgs := append(append([]string{}, d.ExecutionArgs...), `--help`)
+	out, err := exec.Command(d.ExecutionPath, helpArgs...).CombinedOutput()
 	if err != nil {
 		return d, err
 	}
@@ -212,8 +223,10 @@ func (d *Dumper) Reset() {
 	d.Where = ""
 }
 
-func (d *Dumper) Dump(w io.Writer) error {
-	args := make([]string, 0, 16)
+// buildArgs returns the arguments of the execution, and the index of the password argument.
+func (d *Dumper) buildArgs() ([]string, int) {
+	args := make([]string, 0, 16+len(d.ExecutionArgs))
+	args = append(args, d.ExecutionArgs...)
 
 	// Common args
 	if strings.Contains(d.Addr, "/") {
@@ -301,7 +314,16 @@ func (d *Dumper) Dump(w io.Writer) error {
 	} else {
 		args = append(args, d.TableDB)
 		args = append(args, d.Tables...)
+	}
+
+	return args, passwordArgIndex
+}
+
+func (d *Dumper) Dump(w io.Writer) error {
+	args, passwordArgIndex := d.buildArgs()
+	passwordArg := args[passwordArgIndex]
 
+	if len(d.Tables) != 0 {
 		// If we only dump some tables, the dump data will not have database name
 		// which makes us hard to parse, so here we add it manually.
 
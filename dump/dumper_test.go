@@ -60,3 +60,22 @@ func TestDetectSourceDataSupported(t *testing.T) {
 		require.Equal(t, v.supported, d.detectSourceDataSupported(v.version), v.version)
 	}
 }
+
+func TestDumperBuildArgs(t *testing.T) {
+	d := &Dumper{
+		ExecutionArgs: []string{"exec", "mysql", "mysqldump"},
+		Addr:          "127.0.0.1:3306",
+		User:          "root",
+		Password:      "secret",
+		ExtraOptions:  []string{"--set-gtid-purged=OFF"},
+	}
+	d.AddDatabases("test")
+
+	args, passwordArgIndex := d.buildArgs()
+	require.Equal(t, []string{"exec", "mysql", "mysqldump"}, args[:3])
+	require.Equal(t, "--host=127.0.0.1", args[3])
+	require.Equal(t, "--password=secret", args[passwordArgIndex])
+	require.Contains(t, args, "--single-transaction")
+	require.Contains(t, args, "--set-gtid-purged=OFF")
+	require.Equal(t, []string{"--databases", "test"}, args[len(args)-2:])
+}
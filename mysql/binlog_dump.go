@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"encoding/binary"
+)
+
+// AppendBinlogDumpCommand appends a COM_BINLOG_DUMP command to b, asking the server
+// to stream binlog events starting from p.
+//
+// flags is a combination of the BINLOG_DUMP_* flags defined in the replication package,
+// e.g. BINLOG_DUMP_NON_BLOCK makes the server send an EOF packet once all events available
+// have been sent, instead of waiting for new ones.
+func AppendBinlogDumpCommand(b []byte, serverID uint32, flags uint16, p Position) []byte {
+	b = append(b, COM_BINLOG_DUMP)
+	b = binary.LittleEndian.AppendUint32(b, p.Pos)
+	b = binary.LittleEndian.AppendUint16(b, flags)
+	b = binary.LittleEndian.AppendUint32(b, serverID)
+	return append(b, p.Name...)
+}
+
+// AppendBinlogDumpGTIDCommand appends a COM_BINLOG_DUMP_GTID command to b, asking the server
+// to stream the binlog events of the transactions that are not in gset.
+//
+// p is only used by the server when flags contain BINLOG_THROUGH_POSITION, it is usually
+// left empty with position 4.
+func AppendBinlogDumpGTIDCommand(b []byte, serverID uint32, flags uint16, p Position, gset GTIDSet) []byte {
+	gtidData := gset.Encode()
+
+	b = append(b, COM_BINLOG_DUMP_GTID)
+	b = binary.LittleEndian.AppendUint16(b, flags)
+	b = binary.LittleEndian.AppendUint32(b, serverID)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(p.Name)))
+	b = append(b, p.Name...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(p.Pos))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(gtidData)))
+	return append(b, gtidData...)
+}
@@ -39,6 +39,17 @@ const (
 	SERVER_STATUS_METADATA_CHANGED     uint16 = 0x0400
 	SERVER_QUERY_WAS_SLOW              uint16 = 0x0800
 	SERVER_PS_OUT_PARAMS               uint16 = 0x1000
+	SERVER_SESSION_STATE_CHANGED       uint16 = 0x4000
+)
+
+// types of the session state changes in OK packets, with CLIENT_SESSION_TRACK
+const (
+	SESSION_TRACK_SYSTEM_VARIABLES byte = iota
+	SESSION_TRACK_SCHEMA
+	SESSION_TRACK_STATE_CHANGE
+	SESSION_TRACK_GTIDS
+	SESSION_TRACK_TRANSACTION_CHARACTERISTICS
+	SESSION_TRACK_TRANSACTION_STATE
 )
 
 const (
@@ -138,6 +149,9 @@ const (
 	MYSQL_TYPE_TIME2
 )
 
+// mysql 9.0
+const MYSQL_TYPE_VECTOR byte = 0xf2
+
 const (
 	MYSQL_TYPE_JSON byte = iota + 0xf5
 	MYSQL_TYPE_NEWDECIMAL
@@ -868,3 +868,11 @@ const (
 	ER_ROW_IN_WRONG_PARTITION                                           = 1863
 	ER_ERROR_LAST                                                       = 1863
 )
+
+// Error codes added after MySQL 5.6.
+const (
+	ER_QUERY_TIMEOUT              = 3024
+	ER_LOCK_NOWAIT                = 3572
+	ER_CHECK_CONSTRAINT_VIOLATED  = 3819
+	ER_CLIENT_INTERACTION_TIMEOUT = 4031
+)
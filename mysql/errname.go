@@ -865,4 +865,8 @@ var MySQLErrName = map[uint16]string{
 	ER_ALTER_OPERATION_NOT_SUPPORTED_REASON_NOT_NULL:                    "cannot silently convert NULL values, as required in this SQL_MODE",
 	ER_MUST_CHANGE_PASSWORD_LOGIN:                                       "Your password has expired. To log in you must change it using a client that supports expired passwords.",
 	ER_ROW_IN_WRONG_PARTITION:                                           "Found a row in wrong partition %s",
+	ER_QUERY_TIMEOUT:              "Query execution was interrupted, maximum statement execution time exceeded",
+	ER_LOCK_NOWAIT:                "Statement aborted because lock(s) could not be acquired immediately and NOWAIT is set.",
+	ER_CHECK_CONSTRAINT_VIOLATED:  "Check constraint '%-.192s' is violated.",
+	ER_CLIENT_INTERACTION_TIMEOUT: "The client was disconnected by the server because of inactivity. See wait_timeout and interactive_timeout for configuring this behavior.",
 }
@@ -10,7 +10,48 @@ var (
 	ErrBadConn       = errors.New("connection was bad")
 	ErrMalformPacket = errors.New("Malform packet error")
 
+	// ErrTimeout is wrapped by the errors of reads and writes that exceed the read or write
+	// timeout of a connection. It wraps ErrBadConn, the connection can't be used anymore.
+	ErrTimeout = errors.Annotate(ErrBadConn, "i/o timeout")
+
+	// ErrNotSent is wrapped by the errors of commands that failed before any of their bytes
+	// were written to the connection, so they had no effect on the server. It wraps ErrBadConn.
+	ErrNotSent = errors.Annotate(ErrBadConn, "command not sent")
+
 	ErrTxDone = errors.New("sql: Transaction has already been committed or rolled back")
+
+	ErrFieldTooLarge = errors.New("field value is too large")
+
+	// ErrFieldValueNull and ErrFieldValueType are returned by the FieldValue conversions,
+	// like ToInt64, for a NULL value or a value of another type.
+	ErrFieldValueNull = errors.New("field value is NULL")
+	ErrFieldValueType = errors.New("field value type mismatch")
+)
+
+// Errors of common error codes of the server, errors.Is matches them with any *MyError of the
+// same code, e.g. errors.Is(err, mysql.ErrDupEntry).
+var (
+	ErrConCount                 = NewError(ER_CON_COUNT_ERROR, "Too many connections")
+	ErrDBAccessDenied           = NewError(ER_DBACCESS_DENIED_ERROR, "Access denied to database")
+	ErrUserAccessDenied         = NewError(ER_ACCESS_DENIED_ERROR, "Access denied")
+	ErrUnknownCom               = NewError(ER_UNKNOWN_COM_ERROR, "Unknown command")
+	ErrBadDB                    = NewError(ER_BAD_DB_ERROR, "Unknown database")
+	ErrServerShutdown           = NewError(ER_SERVER_SHUTDOWN, "Server shutdown in progress")
+	ErrDupEntry                 = NewError(ER_DUP_ENTRY, "Duplicate entry")
+	ErrParse                    = NewError(ER_PARSE_ERROR, "You have an error in your SQL syntax")
+	ErrNoSuchTable              = NewError(ER_NO_SUCH_TABLE, "Table doesn't exist")
+	ErrTooManyUserConnections   = NewError(ER_TOO_MANY_USER_CONNECTIONS, "User has too many connections")
+	ErrLockWaitTimeout          = NewError(ER_LOCK_WAIT_TIMEOUT, "Lock wait timeout exceeded")
+	ErrLockDeadlock             = NewError(ER_LOCK_DEADLOCK, "Deadlock found when trying to get lock")
+	ErrOptionPreventsStatement  = NewError(ER_OPTION_PREVENTS_STATEMENT, "The server is running with an option that prevents this statement")
+	ErrQueryInterrupted         = NewError(ER_QUERY_INTERRUPTED, "Query execution was interrupted")
+	ErrDataTooLong              = NewError(ER_DATA_TOO_LONG, "Data too long for column")
+	ErrRowIsReferenced          = NewError(ER_ROW_IS_REFERENCED_2, "Cannot delete or update a parent row: a foreign key constraint fails")
+	ErrNoReferencedRow          = NewError(ER_NO_REFERENCED_ROW_2, "Cannot add or update a child row: a foreign key constraint fails")
+	ErrReadOnlyMode             = NewError(ER_READ_ONLY_MODE, "Running in read-only mode")
+	ErrQueryTimeout             = NewError(ER_QUERY_TIMEOUT, "Maximum statement execution time exceeded")
+	ErrLockNowait               = NewError(ER_LOCK_NOWAIT, "Lock(s) could not be acquired immediately and NOWAIT is set")
+	ErrClientInteractionTimeout = NewError(ER_CLIENT_INTERACTION_TIMEOUT, "The client was disconnected by the server because of inactivity")
 )
 
 type MyError struct {
@@ -23,6 +64,13 @@ func (e *MyError) Error() string {
 	return fmt.Sprintf("ERROR %d (%s): %s", e.Code, e.State, e.Message)
 }
 
+// Is reports whether target is a *MyError of the same code, for errors.Is to match the
+// errors of the server with the sentinel errors, like ErrDupEntry.
+func (e *MyError) Is(target error) bool {
+	t, ok := target.(*MyError)
+	return ok && t.Code == e.Code
+}
+
 // NewDefaultError: default mysql error, must adapt errname message format
 func NewDefaultError(errCode uint16, args ...interface{}) *MyError {
 	e := new(MyError)
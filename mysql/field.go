@@ -3,6 +3,7 @@ package mysql
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -207,6 +208,89 @@ func (fv *FieldValue) AsString() []byte {
 	return fv.str
 }
 
+// ToInt64 returns the value of a signed integer, or of an unsigned integer up to math.MaxInt64.
+// Unlike AsInt64, it returns an error for the other values, wrapping ErrFieldValueNull or
+// ErrFieldValueType.
+func (fv *FieldValue) ToInt64() (int64, error) {
+	switch fv.Type {
+	case FieldValueTypeSigned:
+		return fv.AsInt64(), nil
+	case FieldValueTypeUnsigned:
+		if fv.value > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: unsigned value %d overflows int64", ErrFieldValueType, fv.value)
+		}
+		return int64(fv.value), nil
+	default:
+		return 0, fv.conversionError("int64")
+	}
+}
+
+// ToUint64 returns the value of an unsigned integer, or of a non-negative signed integer,
+// see ToInt64.
+func (fv *FieldValue) ToUint64() (uint64, error) {
+	switch fv.Type {
+	case FieldValueTypeUnsigned:
+		return fv.value, nil
+	case FieldValueTypeSigned:
+		if fv.AsInt64() < 0 {
+			return 0, fmt.Errorf("%w: negative value %d to uint64", ErrFieldValueType, fv.AsInt64())
+		}
+		return fv.value, nil
+	default:
+		return 0, fv.conversionError("uint64")
+	}
+}
+
+// ToFloat64 returns the value of a float or of an integer, see ToInt64.
+func (fv *FieldValue) ToFloat64() (float64, error) {
+	switch fv.Type {
+	case FieldValueTypeFloat:
+		return fv.AsFloat64(), nil
+	case FieldValueTypeSigned:
+		return float64(fv.AsInt64()), nil
+	case FieldValueTypeUnsigned:
+		return float64(fv.value), nil
+	default:
+		return 0, fv.conversionError("float64")
+	}
+}
+
+// ToString returns a copy of a string value, like a VARCHAR or a DECIMAL, see ToInt64.
+func (fv *FieldValue) ToString() (string, error) {
+	if fv.Type != FieldValueTypeString {
+		return "", fv.conversionError("string")
+	}
+	return string(fv.str), nil
+}
+
+// ToBytes returns a copy of a string value, like ToString. Unlike AsString, the copy remains
+// valid after the resultset is released.
+func (fv *FieldValue) ToBytes() ([]byte, error) {
+	if fv.Type != FieldValueTypeString {
+		return nil, fv.conversionError("[]byte")
+	}
+	return append([]byte{}, fv.str...), nil
+}
+
+func (fv *FieldValue) conversionError(to string) error {
+	var from string
+	switch fv.Type {
+	case FieldValueTypeNull:
+		return fmt.Errorf("%w: can't convert it to %s", ErrFieldValueNull, to)
+	case FieldValueTypeUnsigned:
+		from = "unsigned integer"
+	case FieldValueTypeSigned:
+		from = "signed integer"
+	case FieldValueTypeFloat:
+		from = "float"
+	case FieldValueTypeString:
+		from = "string"
+	default:
+		from = fmt.Sprintf("type %d", fv.Type)
+	}
+	return fmt.Errorf("%w: can't convert %s value to %s", ErrFieldValueType, from, to)
+}
+
 func (fv *FieldValue) Value() interface{} {
 	switch fv.Type {
 	case FieldValueTypeUnsigned:
@@ -0,0 +1,71 @@
+package mysql
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pingcap/errors"
+)
+
+// GeometryType is the subtype of a geometry, with its WKB code.
+type GeometryType uint32
+
+const (
+	GeometryTypePoint GeometryType = iota + 1
+	GeometryTypeLineString
+	GeometryTypePolygon
+	GeometryTypeMultiPoint
+	GeometryTypeMultiLineString
+	GeometryTypeMultiPolygon
+	GeometryTypeGeometryCollection
+)
+
+var geometryTypeNames = map[GeometryType]string{
+	GeometryTypePoint:              "POINT",
+	GeometryTypeLineString:         "LINESTRING",
+	GeometryTypePolygon:            "POLYGON",
+	GeometryTypeMultiPoint:         "MULTIPOINT",
+	GeometryTypeMultiLineString:    "MULTILINESTRING",
+	GeometryTypeMultiPolygon:       "MULTIPOLYGON",
+	GeometryTypeGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+func (t GeometryType) String() string {
+	if name, ok := geometryTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("GeometryType(%d)", uint32(t))
+}
+
+// Geometry is the value of a spatial column of any subtype, like read from a resultset or
+// a rows event: MySQL stores it as a 4-byte little endian SRID followed by its WKB.
+type Geometry struct {
+	SRID uint32
+	// WKB is the well-known binary representation, starting with its byte order byte.
+	WKB []byte
+}
+
+// ParseGeometry parses the value of a spatial column. WKB refers to data.
+func ParseGeometry(data []byte) (*Geometry, error) {
+	// SRID, byte order, type
+	if len(data) < 4+1+4 {
+		return nil, errors.Errorf("geometry value of %d bytes is too short", len(data))
+	}
+
+	g := &Geometry{SRID: binary.LittleEndian.Uint32(data), WKB: data[4:]}
+	if g.WKB[0] > 1 {
+		return nil, errors.Errorf("invalid WKB byte order %d", g.WKB[0])
+	}
+	if _, ok := geometryTypeNames[g.Type()]; !ok {
+		return nil, errors.Errorf("invalid WKB geometry type %d", uint32(g.Type()))
+	}
+	return g, nil
+}
+
+// Type returns the subtype of the geometry, read in the byte order of the WKB.
+func (g *Geometry) Type() GeometryType {
+	if g.WKB[0] == 0 {
+		return GeometryType(binary.BigEndian.Uint32(g.WKB[1:]))
+	}
+	return GeometryType(binary.LittleEndian.Uint32(g.WKB[1:]))
+}
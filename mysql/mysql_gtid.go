@@ -596,3 +596,13 @@ func (gtid *MysqlGTIDSet) Clone() GTIDSet {
 
 	return clone
 }
+
+// ByUUID returns the intervals of the set grouped by the UUID of their source server, in its
+// string form. The UUIDSets are copies, changing them doesn't change s.
+func (s *MysqlGTIDSet) ByUUID() map[string]*UUIDSet {
+	sets := make(map[string]*UUIDSet, len(s.Sets))
+	for sid, uuidSet := range s.Sets {
+		sets[sid] = uuidSet.Clone()
+	}
+	return sets
+}
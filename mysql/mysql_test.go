@@ -1,7 +1,10 @@
 package mysql
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 
@@ -243,6 +246,22 @@ func TestMysqlGTIDMinus(t *testing.T) {
 	}
 }
 
+func TestMysqlGTIDByUUID(t *testing.T) {
+	m := mysqlGTIDfromString(t, "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:8,ABCDEF12-1234-5678-9012-345678901234:1-1000")
+
+	sets := m.ByUUID()
+	require.Len(t, sets, 2)
+	require.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:8", sets["3e11fa47-71ca-11e1-9e33-c80aa9429562"].String())
+	require.Equal(t, IntervalSlice{{1, 1001}}, sets["abcdef12-1234-5678-9012-345678901234"].Intervals)
+
+	// the sets are copies
+	sets["3e11fa47-71ca-11e1-9e33-c80aa9429562"].AddInterval(IntervalSlice{{6, 8}})
+	require.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:8", m.Sets["3e11fa47-71ca-11e1-9e33-c80aa9429562"].String())
+
+	empty := mysqlGTIDfromString(t, "")
+	require.Empty(t, empty.ByUUID())
+}
+
 func TestMysqlParseBinaryInt8(t *testing.T) {
 	i8 := ParseBinaryInt8([]byte{128})
 	require.Equal(t, int8(-128), i8)
@@ -360,3 +379,273 @@ func TestValidateFlavor(t *testing.T) {
 		}
 	}
 }
+
+func TestResultsetGetRaw(t *testing.T) {
+	names := []string{"id", "name", "note"}
+	values := [][]interface{}{{int64(1), "a", nil}, {int64(-2), "bc", "x"}}
+
+	for _, binary := range []bool{false, true} {
+		r, err := BuildSimpleResultset(names, values, binary)
+		require.NoError(t, err)
+
+		data, typ, isNull, err := r.GetRaw(1, 1)
+		require.NoError(t, err)
+		require.False(t, isNull)
+		require.Equal(t, []byte("bc"), data)
+		require.Equal(t, MYSQL_TYPE_VAR_STRING, typ)
+
+		_, _, isNull, err = r.GetRaw(0, 2)
+		require.NoError(t, err)
+		require.True(t, isNull)
+
+		data, typ, _, err = r.GetRaw(1, 0)
+		require.NoError(t, err)
+		require.Equal(t, MYSQL_TYPE_LONGLONG, typ)
+		if binary {
+			require.Equal(t, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, data)
+		} else {
+			require.Equal(t, []byte("-2"), data)
+		}
+
+		_, _, _, err = r.GetRaw(2, 0)
+		require.Error(t, err)
+		_, _, _, err = r.GetRaw(0, 3)
+		require.Error(t, err)
+	}
+}
+
+func TestParseBinaryNullBitmap(t *testing.T) {
+	// 3 columns with the second one NULL: bit 1+2 of the bitmap
+	fields := []*Field{{Type: MYSQL_TYPE_TINY}, {Type: MYSQL_TYPE_TINY}, {Type: MYSQL_TYPE_TINY}}
+	row, err := RowData([]byte{0x00, 0x08, 1, 3}).ParseBinary(fields, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), row[0].AsInt64())
+	require.Equal(t, FieldValueType(FieldValueTypeNull), row[1].Type)
+	require.Equal(t, int64(3), row[2].AsInt64())
+
+	_, err = RowData([]byte{0x00}).ParseBinary(fields, nil)
+	require.ErrorIs(t, err, ErrMalformPacket)
+
+	// enough columns for the bitmap to span 3 bytes, with NULLs around the byte boundaries
+	const columns = 20
+	names := make([]string, columns)
+	first := make([]interface{}, columns)
+	second := make([]interface{}, columns)
+	for i := range names {
+		names[i] = fmt.Sprintf("c%d", i)
+		first[i] = int64(i)
+		if i%3 == 0 || i == 5 || i == 6 || i == 13 || i == 14 {
+			second[i] = nil
+		} else {
+			second[i] = int64(i * 10)
+		}
+	}
+
+	r, err := BuildSimpleBinaryResultset(names, [][]interface{}{first, second})
+	require.NoError(t, err)
+
+	for i, expected := range [][]interface{}{first, second} {
+		values, err := r.RowDatas[i].ParseBinary(r.Fields, nil)
+		require.NoError(t, err)
+		require.Len(t, values, columns)
+		for j := range values {
+			if expected[j] == nil {
+				require.Equal(t, FieldValueType(FieldValueTypeNull), values[j].Type, "row %d column %d", i, j)
+			} else {
+				require.Equal(t, expected[j], values[j].Value(), "row %d column %d", i, j)
+			}
+		}
+	}
+}
+
+func TestParseBinarySignedness(t *testing.T) {
+	types := []struct {
+		tp       byte
+		size     int
+		unsigned uint64
+	}{
+		{MYSQL_TYPE_TINY, 1, math.MaxUint8},
+		{MYSQL_TYPE_SHORT, 2, math.MaxUint16},
+		{MYSQL_TYPE_INT24, 4, math.MaxUint32},
+		{MYSQL_TYPE_LONG, 4, math.MaxUint32},
+		{MYSQL_TYPE_LONGLONG, 8, math.MaxUint64},
+	}
+
+	for _, tt := range types {
+		// all bits set: the max of the unsigned type, -1 for the signed one
+		data := append([]byte{OK_HEADER, 0}, bytes.Repeat([]byte{0xff}, tt.size)...)
+
+		row, err := RowData(data).ParseBinary([]*Field{{Type: tt.tp, Flag: UNSIGNED_FLAG}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, FieldValueType(FieldValueTypeUnsigned), row[0].Type, "type %d", tt.tp)
+		require.Equal(t, tt.unsigned, row[0].Value(), "type %d", tt.tp)
+
+		row, err = RowData(data).ParseBinary([]*Field{{Type: tt.tp}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, FieldValueType(FieldValueTypeSigned), row[0].Type, "type %d", tt.tp)
+		require.Equal(t, int64(-1), row[0].Value(), "type %d", tt.tp)
+	}
+}
+
+func TestResultsetRows(t *testing.T) {
+	names := []string{"id", "name", "note", "id"}
+	values := [][]interface{}{{int64(1), "a", "x", uint64(7)}, {int64(-2), "bc", nil, uint64(8)}}
+
+	for _, binary := range []bool{false, true} {
+		r, err := BuildSimpleResultset(names, values, binary)
+		require.NoError(t, err)
+		for _, data := range r.RowDatas {
+			row, err := data.Parse(r.Fields, binary, nil)
+			require.NoError(t, err)
+			r.Values = append(r.Values, row)
+		}
+
+		rows := r.Rows()
+		require.Equal(t, [][]interface{}{
+			{int64(1), []byte("a"), []byte("x"), uint64(7)},
+			{int64(-2), []byte("bc"), nil, uint64(8)},
+		}, rows)
+
+		maps := r.RowMaps()
+		require.Len(t, maps, 2)
+		require.Equal(t, map[string]interface{}{"id": uint64(7), "name": []byte("a"), "note": []byte("x")}, maps[0])
+		require.Contains(t, maps[1], "note")
+		require.Nil(t, maps[1]["note"])
+
+		// the values don't alias the resultset
+		r.Values[0][1].AsString()[0] = 'z'
+		require.Equal(t, []byte("a"), rows[0][1])
+	}
+}
+
+func TestFieldValueConversions(t *testing.T) {
+	signed := NewFieldValue(FieldValueTypeSigned, uint64(0xffffffffffffffff), nil) // -1
+	unsigned := NewFieldValue(FieldValueTypeUnsigned, math.MaxUint64, nil)
+	float := NewFieldValue(FieldValueTypeFloat, math.Float64bits(1.5), nil)
+	str := NewFieldValue(FieldValueTypeString, 0, []byte("abc"))
+	null := NewFieldValue(FieldValueTypeNull, 0, nil)
+
+	i, err := signed.ToInt64()
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), i)
+	_, err = signed.ToUint64()
+	require.ErrorIs(t, err, ErrFieldValueType)
+	f, err := signed.ToFloat64()
+	require.NoError(t, err)
+	require.Equal(t, float64(-1), f)
+
+	u, err := unsigned.ToUint64()
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), u)
+	_, err = unsigned.ToInt64()
+	require.ErrorIs(t, err, ErrFieldValueType)
+
+	f, err = float.ToFloat64()
+	require.NoError(t, err)
+	require.Equal(t, 1.5, f)
+	_, err = float.ToInt64()
+	require.ErrorIs(t, err, ErrFieldValueType)
+	require.ErrorContains(t, err, "can't convert float value to int64")
+
+	s, err := str.ToString()
+	require.NoError(t, err)
+	require.Equal(t, "abc", s)
+	b, err := str.ToBytes()
+	require.NoError(t, err)
+	str.AsString()[0] = 'x'
+	require.Equal(t, []byte("abc"), b)
+	_, err = str.ToFloat64()
+	require.ErrorIs(t, err, ErrFieldValueType)
+
+	_, err = null.ToString()
+	require.ErrorIs(t, err, ErrFieldValueNull)
+	_, err = null.ToBytes()
+	require.ErrorIs(t, err, ErrFieldValueNull)
+	_, err = unsigned.ToString()
+	require.ErrorIs(t, err, ErrFieldValueType)
+}
+
+func TestParseMediumInt(t *testing.T) {
+	signed := &Field{Type: MYSQL_TYPE_INT24}
+	unsigned := &Field{Type: MYSQL_TYPE_INT24, Flag: UNSIGNED_FLAG}
+
+	for _, tt := range []struct {
+		field *Field
+		value interface{}
+	}{
+		{signed, int64(-8388608)},
+		{signed, int64(-1)},
+		{signed, int64(8388607)},
+		{unsigned, uint64(0)},
+		{unsigned, uint64(8388608)},
+		{unsigned, uint64(16777215)},
+	} {
+		text := PutLengthEncodedString([]byte(fmt.Sprint(tt.value)))
+		row, err := RowData(text).ParseText([]*Field{tt.field}, nil)
+		require.NoError(t, err)
+		require.Equal(t, tt.value, row[0].Value())
+
+		// MEDIUMINT is sent on 4 bytes in the binary protocol, sign extended
+		var v uint32
+		switch n := tt.value.(type) {
+		case int64:
+			v = uint32(int32(n))
+		case uint64:
+			v = uint32(n)
+		}
+		bin := binary.LittleEndian.AppendUint32([]byte{OK_HEADER, 0}, v)
+		row, err = RowData(bin).ParseBinary([]*Field{tt.field}, nil)
+		require.NoError(t, err)
+		require.Equal(t, tt.value, row[0].Value())
+	}
+}
+
+func TestParseGeometry(t *testing.T) {
+	// ST_GeomFromText('POINT(1 2)', 4326)
+	point := []byte{0xe6, 0x10, 0, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0, 0, 0, 0, 0, 0, 0, 0x40}
+	g, err := ParseGeometry(point)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4326), g.SRID)
+	require.Equal(t, GeometryTypePoint, g.Type())
+	require.Equal(t, "POINT", g.Type().String())
+	require.Equal(t, point[4:], g.WKB)
+
+	for tp := GeometryTypePoint; tp <= GeometryTypeGeometryCollection; tp++ {
+		// little and big endian WKB headers, the bodies are not parsed
+		data := binary.LittleEndian.AppendUint32([]byte{0, 0, 0, 0, 1}, uint32(tp))
+		g, err = ParseGeometry(data)
+		require.NoError(t, err)
+		require.Equal(t, tp, g.Type())
+
+		data = binary.BigEndian.AppendUint32([]byte{1, 0, 0, 0, 0}, uint32(tp))
+		g, err = ParseGeometry(data)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), g.SRID)
+		require.Equal(t, tp, g.Type())
+	}
+	require.Equal(t, "MULTIPOLYGON", GeometryTypeMultiPolygon.String())
+
+	for _, data := range [][]byte{
+		{0, 0, 0, 0, 1, 1, 0, 0},
+		{0, 0, 0, 0, 2, 1, 0, 0, 0},
+		{0, 0, 0, 0, 1, 8, 0, 0, 0},
+		{0, 0, 0, 0, 1, 0, 0, 0, 0},
+	} {
+		_, err = ParseGeometry(data)
+		require.Error(t, err)
+	}
+}
+
+func TestMyErrorIs(t *testing.T) {
+	err := fmt.Errorf("insert: %w", NewDefaultError(ER_DUP_ENTRY, "1", 1))
+	require.ErrorIs(t, err, ErrDupEntry)
+	require.NotErrorIs(t, err, ErrLockDeadlock)
+
+	var myErr *MyError
+	require.ErrorAs(t, err, &myErr)
+	require.Equal(t, "23000", myErr.State)
+
+	require.ErrorIs(t, NewError(ER_LOCK_DEADLOCK, "Deadlock found"), ErrLockDeadlock)
+	require.NotErrorIs(t, ErrBadConn, ErrLockDeadlock)
+	require.Equal(t, uint16(4031), ErrClientInteractionTimeout.Code)
+}
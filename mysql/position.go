@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/pingcap/errors"
 )
 
 // Position for binlog filename + position based replication
@@ -85,3 +87,40 @@ func CompareBinlogFileName(a, b string) int {
 		return 0
 	}
 }
+
+// the highest numeric extension of a binlog file, mysqld refuses to go past it
+const maxBinlogSeq = 0x7FFFFFFF
+
+// ParseBinlogName splits a binlog file name like mysql-bin.000009 into its base name
+// and the sequence number of its numeric extension.
+func ParseBinlogName(name string) (base string, seq uint32, err error) {
+	i := strings.LastIndexByte(name, '.')
+	if i == -1 || i == len(name)-1 {
+		return "", 0, errors.Errorf("binlog file %s doesn't contain numeric extension", name)
+	}
+	for _, c := range name[i+1:] {
+		if c < '0' || c > '9' {
+			return "", 0, errors.Errorf("binlog file %s doesn't contain numeric extension", name)
+		}
+	}
+
+	n, err := strconv.ParseUint(name[i+1:], 10, 32)
+	if err != nil || n > maxBinlogSeq {
+		return "", 0, errors.Errorf("binlog file %s has an out of range numeric extension", name)
+	}
+	return name[:i], uint32(n), nil
+}
+
+// NextBinlogName returns the name of the binlog file that follows name, like mysqld
+// names it: mysql-bin.000009 is followed by mysql-bin.000010, and mysql-bin.999999 by
+// mysql-bin.1000000. The extension keeps its width, with leading zeros.
+func NextBinlogName(name string) (string, error) {
+	base, seq, err := ParseBinlogName(name)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if seq == maxBinlogSeq {
+		return "", errors.Errorf("binlog file %s has the last numeric extension", name)
+	}
+	return fmt.Sprintf("%s.%0*d", base, len(name)-len(base)-1, seq+1), nil
+}
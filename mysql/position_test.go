@@ -46,3 +46,40 @@ func TestPosCompare(t *testing.T) {
 		require.Equal(t, 0, p.Compare(p))
 	}
 }
+
+func TestBinlogName(t *testing.T) {
+	base, seq, err := ParseBinlogName("mysql-bin.000009")
+	require.NoError(t, err)
+	require.Equal(t, "mysql-bin", base)
+	require.Equal(t, uint32(9), seq)
+
+	base, seq, err = ParseBinlogName("/var/lib/mysql/binlog.v8.1000000")
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/mysql/binlog.v8", base)
+	require.Equal(t, uint32(1000000), seq)
+
+	for _, name := range []string{"mysql-bin", "mysql-bin.", "mysql-bin.00a1", "mysql-bin.+1", "mysql-bin.2147483648"} {
+		_, _, err = ParseBinlogName(name)
+		require.Error(t, err, name)
+	}
+
+	for name, next := range map[string]string{
+		"mysql-bin.000009":   "mysql-bin.000010",
+		"mysql-bin.000099":   "mysql-bin.000100",
+		"mysql-bin.999999":   "mysql-bin.1000000",
+		"mysql-bin.1000000":  "mysql-bin.1000001",
+		"mysql-bin.1":        "mysql-bin.2",
+		"mysql-bin.9":        "mysql-bin.10",
+		"mysql-bin.00000000": "mysql-bin.00000001",
+	} {
+		n, err := NextBinlogName(name)
+		require.NoError(t, err)
+		require.Equal(t, next, n)
+		require.Equal(t, -1, CompareBinlogFileName(name, n))
+	}
+
+	_, err = NextBinlogName("mysql-bin.2147483647")
+	require.Error(t, err)
+	_, err = NextBinlogName("mysql-bin")
+	require.Error(t, err)
+}
@@ -6,6 +6,9 @@ type Result struct {
 
 	InsertId     uint64
 	AffectedRows uint64
+	// HasAffectedRows is false when the server sent no affected rows count, which is the
+	// case for resultsets and for the -1 "no info" value, and AffectedRows is then 0.
+	HasAffectedRows bool
 
 	*Resultset
 }
@@ -31,6 +31,9 @@ type Resultset struct {
 
 	RowDatas []RowData
 
+	// Binary is true if RowDatas are in the binary protocol of prepared statements
+	Binary bool
+
 	Streaming     StreamingType
 	StreamingDone bool
 }
@@ -53,12 +56,21 @@ func (r *Resultset) returnToPool() {
 	resultsetPool.Put(r)
 }
 
+// Release puts the resultset back into the pool it is allocated from, so its buffers are reused
+// by the next query instead of being garbage collected. Neither the resultset nor any value read
+// from it without being copied may be used afterwards, and Release must be called only once.
+// Result.Close calls it for the resultset of the result.
+func (r *Resultset) Release() {
+	r.returnToPool()
+}
+
 func (r *Resultset) Reset(fieldsCount int) {
 	r.RawPkg = r.RawPkg[:0]
 
 	r.Fields = r.Fields[:0]
 	r.Values = r.Values[:0]
 	r.RowDatas = r.RowDatas[:0]
+	r.Binary = false
 
 	if r.FieldNames != nil {
 		for k := range r.FieldNames {
@@ -102,6 +114,26 @@ func (r *Resultset) GetValue(row, column int) (interface{}, error) {
 	return r.Values[row][column].Value(), nil
 }
 
+// GetRaw returns the value of the column as it was sent by the server, without decoding it,
+// along with the MySQL type of the column. In the binary protocol, fixed size numbers are
+// little endian and other values have their length prefix removed.
+// It needs RowDatas, so it can't be used with streamed resultsets.
+func (r *Resultset) GetRaw(row, column int) (data []byte, typ byte, isNull bool, err error) {
+	if row >= len(r.RowDatas) || row < 0 {
+		return nil, 0, false, errors.Errorf("invalid row index %d", row)
+	}
+
+	if column >= len(r.Fields) || column < 0 {
+		return nil, 0, false, errors.Errorf("invalid column index %d", column)
+	}
+
+	data, isNull, err = r.RowDatas[row].RawValue(r.Fields, r.Binary, column)
+	if err != nil {
+		return nil, 0, false, errors.Trace(err)
+	}
+	return data, r.Fields[column].Type, isNull, nil
+}
+
 func (r *Resultset) NameIndex(name string) (int, error) {
 	if column, ok := r.FieldNames[name]; ok {
 		return column, nil
@@ -118,6 +150,44 @@ func (r *Resultset) GetValueByName(row int, name string) (interface{}, error) {
 	}
 }
 
+// Rows returns the values of all the rows, with the types of GetValue: NULL is nil, integers
+// are int64 or uint64, floats float64, and other values []byte. The values are copied, they
+// remain valid after the resultset is released.
+func (r *Resultset) Rows() [][]interface{} {
+	rows := make([][]interface{}, len(r.Values))
+	for i := range r.Values {
+		rows[i] = r.rowValues(i)
+	}
+	return rows
+}
+
+// RowMaps returns the values of all the rows like Rows, keyed by column name. If several
+// columns have the same name, the last one wins.
+func (r *Resultset) RowMaps() []map[string]interface{} {
+	rows := make([]map[string]interface{}, len(r.Values))
+	for i := range r.Values {
+		values := r.rowValues(i)
+		m := make(map[string]interface{}, len(values))
+		for j, v := range values {
+			m[string(r.Fields[j].Name)] = v
+		}
+		rows[i] = m
+	}
+	return rows
+}
+
+func (r *Resultset) rowValues(row int) []interface{} {
+	values := make([]interface{}, len(r.Values[row]))
+	for j := range r.Values[row] {
+		v := r.Values[row][j].Value()
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		values[j] = v
+	}
+	return values
+}
+
 func (r *Resultset) IsNull(row, column int) (bool, error) {
 	d, err := r.GetValue(row, column)
 	if err != nil {
@@ -285,3 +355,53 @@ func (r *Resultset) GetStringByName(row int, name string) (string, error) {
 		return r.GetString(row, column)
 	}
 }
+
+// GetSet returns the members of a SET column, nil if it is NULL and an empty slice for the empty set.
+func (r *Resultset) GetSet(row, column int) ([]string, error) {
+	d, err := r.GetValue(row, column)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, nil
+	}
+
+	s, err := r.GetString(row, column)
+	if err != nil {
+		return nil, err
+	}
+	return SplitSetValue(s), nil
+}
+
+// GetVector returns the floats of a VECTOR column, or nil for NULL.
+func (r *Resultset) GetVector(row, column int) ([]float32, error) {
+	d, err := r.GetValue(row, column)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, nil
+	}
+
+	s, err := r.GetString(row, column)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeVectorValue(utils.StringToByteSlice(s))
+}
+
+func (r *Resultset) GetVectorByName(row int, name string) ([]float32, error) {
+	if column, err := r.NameIndex(name); err != nil {
+		return nil, err
+	} else {
+		return r.GetVector(row, column)
+	}
+}
+
+func (r *Resultset) GetSetByName(row int, name string) ([]string, error) {
+	if column, err := r.NameIndex(name); err != nil {
+		return nil, err
+	} else {
+		return r.GetSet(row, column)
+	}
+}
@@ -1,8 +1,6 @@
 package mysql
 
 import (
-	"bytes"
-	"encoding/binary"
 	"math"
 	"strconv"
 	"time"
@@ -52,41 +50,11 @@ func FormatTextValue(value interface{}) ([]byte, error) {
 }
 
 func toBinaryDateTime(t time.Time) ([]byte, error) {
-	var buf bytes.Buffer
-
 	if t.IsZero() {
 		return nil, nil
 	}
 
-	year, month, day := t.Year(), t.Month(), t.Day()
-	hour, min, sec := t.Hour(), t.Minute(), t.Second()
-	nanosec := t.Nanosecond()
-
-	if nanosec > 0 {
-		buf.WriteByte(byte(11))
-		_ = binary.Write(&buf, binary.LittleEndian, uint16(year))
-		buf.WriteByte(byte(month))
-		buf.WriteByte(byte(day))
-		buf.WriteByte(byte(hour))
-		buf.WriteByte(byte(min))
-		buf.WriteByte(byte(sec))
-		_ = binary.Write(&buf, binary.LittleEndian, uint32(nanosec/1000))
-	} else if hour > 0 || min > 0 || sec > 0 {
-		buf.WriteByte(byte(7))
-		_ = binary.Write(&buf, binary.LittleEndian, uint16(year))
-		buf.WriteByte(byte(month))
-		buf.WriteByte(byte(day))
-		buf.WriteByte(byte(hour))
-		buf.WriteByte(byte(min))
-		buf.WriteByte(byte(sec))
-	} else {
-		buf.WriteByte(byte(4))
-		_ = binary.Write(&buf, binary.LittleEndian, uint16(year))
-		buf.WriteByte(byte(month))
-		buf.WriteByte(byte(day))
-	}
-
-	return buf.Bytes(), nil
+	return AppendBinaryDateTime(nil, t), nil
 }
 
 func formatBinaryValue(value interface{}) ([]byte, error) {
@@ -289,6 +257,7 @@ func BuildSimpleBinaryResultset(names []string, values [][]interface{}) (*Result
 
 		r.RowDatas = append(r.RowDatas, row)
 	}
+	r.Binary = true
 
 	return r, nil
 }
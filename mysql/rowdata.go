@@ -74,6 +74,74 @@ func (p RowData) ParseText(f []*Field, dst []FieldValue) ([]FieldValue, error) {
 	return data, nil
 }
 
+// RawValue returns the undecoded value of the column.
+// Binary values of unknown types are assumed to be length encoded strings.
+func (p RowData) RawValue(f []*Field, binary bool, column int) ([]byte, bool, error) {
+	if column >= len(f) || column < 0 {
+		return nil, false, errors.Errorf("invalid column index %d", column)
+	}
+
+	var pos int
+	if binary {
+		pos = 1 + ((len(f) + 7 + 2) >> 3)
+		if len(p) < pos || p[0] != OK_HEADER {
+			return nil, false, ErrMalformPacket
+		}
+		if p[1+(column+2)/8]&(1<<(uint(column+2)%8)) > 0 {
+			return nil, true, nil
+		}
+	}
+
+	for i := 0; i <= column; i++ {
+		if binary {
+			if p[1+(i+2)/8]&(1<<(uint(i+2)%8)) > 0 {
+				continue
+			}
+
+			if size := binaryFixedSize(f[i].Type); size >= 0 {
+				if len(p) < pos+size {
+					return nil, false, ErrMalformPacket
+				}
+				if i == column {
+					return p[pos : pos+size], f[i].Type == MYSQL_TYPE_NULL, nil
+				}
+				pos += size
+				continue
+			}
+		}
+
+		v, isNull, n, err := LengthEncodedString(p[pos:])
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if i == column {
+			return v, isNull, nil
+		}
+		pos += n
+	}
+
+	return nil, false, ErrMalformPacket
+}
+
+// binaryFixedSize returns the size of a fixed size value in the binary protocol,
+// or -1 if the value is length encoded.
+func binaryFixedSize(tp byte) int {
+	switch tp {
+	case MYSQL_TYPE_NULL:
+		return 0
+	case MYSQL_TYPE_TINY:
+		return 1
+	case MYSQL_TYPE_SHORT, MYSQL_TYPE_YEAR:
+		return 2
+	case MYSQL_TYPE_INT24, MYSQL_TYPE_LONG, MYSQL_TYPE_FLOAT:
+		return 4
+	case MYSQL_TYPE_LONGLONG, MYSQL_TYPE_DOUBLE:
+		return 8
+	default:
+		return -1
+	}
+}
+
 // ParseBinary parses the binary format of data
 // see https://dev.mysql.com/doc/internals/en/binary-protocol-value.html
 func (p RowData) ParseBinary(f []*Field, dst []FieldValue) ([]FieldValue, error) {
@@ -82,12 +150,12 @@ func (p RowData) ParseBinary(f []*Field, dst []FieldValue) ([]FieldValue, error)
 	}
 	data := dst[:len(f)]
 
-	if p[0] != OK_HEADER {
+	// the NULL bitmap of binary rows starts at bit 2
+	pos := 1 + ((len(f) + 7 + 2) >> 3)
+	if len(p) < pos || p[0] != OK_HEADER {
 		return nil, ErrMalformPacket
 	}
 
-	pos := 1 + ((len(f) + 7 + 2) >> 3)
-
 	nullBitmap := p[1:pos]
 
 	var isNull bool
@@ -176,7 +244,8 @@ func (p RowData) ParseBinary(f []*Field, dst []FieldValue) ([]FieldValue, error)
 		case MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL, MYSQL_TYPE_VARCHAR,
 			MYSQL_TYPE_BIT, MYSQL_TYPE_ENUM, MYSQL_TYPE_SET, MYSQL_TYPE_TINY_BLOB,
 			MYSQL_TYPE_MEDIUM_BLOB, MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BLOB,
-			MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING, MYSQL_TYPE_GEOMETRY, MYSQL_TYPE_JSON:
+			MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING, MYSQL_TYPE_GEOMETRY, MYSQL_TYPE_JSON,
+			MYSQL_TYPE_VECTOR:
 			v, isNull, n, err = LengthEncodedString(p[pos:])
 			pos += n
 			if err != nil {
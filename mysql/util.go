@@ -10,6 +10,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	mrand "math/rand"
 	"runtime"
 	"strings"
@@ -365,6 +366,72 @@ func FormatBinaryTime(n int, data []byte) ([]byte, error) {
 	return bytes, nil
 }
 
+// AppendBinaryDateTime appends t in the binary protocol DATETIME format to b.
+// The shortest of the 0, 4, 7 or 11 bytes encodings that keeps the microseconds of t is used,
+// a zero time is encoded as '0000-00-00 00:00:00'.
+func AppendBinaryDateTime(b []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return append(b, 0)
+	}
+
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	micro := t.Nanosecond() / 1000
+
+	var n byte
+	switch {
+	case micro > 0:
+		n = 11
+	case hour > 0 || min > 0 || sec > 0:
+		n = 7
+	default:
+		n = 4
+	}
+
+	b = append(b, n)
+	b = binary.LittleEndian.AppendUint16(b, uint16(year))
+	b = append(b, byte(month), byte(day))
+	if n >= 7 {
+		b = append(b, byte(hour), byte(min), byte(sec))
+	}
+	if n == 11 {
+		b = binary.LittleEndian.AppendUint32(b, uint32(micro))
+	}
+	return b
+}
+
+// AppendBinaryTime appends d in the binary protocol TIME format to b.
+// The shortest of the 0, 8 or 12 bytes encodings that keeps the microseconds of d is used.
+func AppendBinaryTime(b []byte, d time.Duration) []byte {
+	if d == 0 {
+		return append(b, 0)
+	}
+
+	var negative byte
+	if d < 0 {
+		negative = 1
+		d = -d
+	}
+
+	micro := (d % time.Second) / time.Microsecond
+	if micro > 0 {
+		b = append(b, 12)
+	} else {
+		b = append(b, 8)
+	}
+
+	b = append(b, negative)
+	b = binary.LittleEndian.AppendUint32(b, uint32(d/(24*time.Hour)))
+	b = append(b,
+		byte((d/time.Hour)%24),
+		byte((d/time.Minute)%60),
+		byte((d/time.Second)%60))
+	if micro > 0 {
+		b = binary.LittleEndian.AppendUint32(b, uint32(micro))
+	}
+	return b
+}
+
 var (
 	DONTESCAPE = byte(255)
 
@@ -386,6 +453,57 @@ func Escape(sql string) string {
 	return string(dest)
 }
 
+// SplitSetValue returns the members of a SET value in its text form, as returned in a resultset.
+// The empty set returns an empty, non-nil slice.
+func SplitSetValue(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// DecodeSetValue returns the members of a SET value in its bitmask form, as found in rows events,
+// members being the values the column was defined with. The empty set returns an empty, non-nil slice.
+func DecodeSetValue(bits int64, members []string) []string {
+	ret := []string{}
+	for i, m := range members {
+		if bits&(1<<uint(i)) != 0 {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+// DecodeEnumValue returns the value of an ENUM from its index, as found in rows events, members being
+// the values the column was defined with. Index 0, used by MySQL for invalid values, returns "".
+func DecodeEnumValue(index int64, members []string) string {
+	if index <= 0 || index > int64(len(members)) {
+		return ""
+	}
+	return members[index-1]
+}
+
+// DecodeVectorValue returns the floats of a VECTOR value, stored as packed little endian float32.
+func DecodeVectorValue(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, errors.Errorf("invalid VECTOR value length %d", len(data))
+	}
+
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v, nil
+}
+
+// AppendVectorValue appends the VECTOR form of v to b, e.g. to use it as a statement parameter.
+func AppendVectorValue(b []byte, v []float32) []byte {
+	for _, f := range v {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(f))
+	}
+	return b
+}
+
 func GetNetProto(addr string) string {
 	if strings.Contains(addr, "/") {
 		return "unix"
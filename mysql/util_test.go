@@ -113,3 +113,104 @@ func TestToBinaryDateTime(t *testing.T) {
 		})
 	}
 }
+
+func TestAppendBinaryDateTime(t *testing.T) {
+	tests := []struct {
+		Data   time.Time
+		Length int
+		Expect string
+	}{
+		{time.Time{}, 0, "0000-00-00 00:00:00"},
+		{time.Date(2023, 10, 10, 0, 0, 0, 0, time.UTC), 4, "2023-10-10 00:00:00"},
+		{time.Date(2023, 10, 10, 10, 10, 10, 0, time.UTC), 7, "2023-10-10 10:10:10"},
+		// sub-microsecond precision can't be stored and must not select the 11 bytes encoding
+		{time.Date(2023, 10, 10, 10, 10, 10, 999, time.UTC), 7, "2023-10-10 10:10:10"},
+		{time.Date(2023, 10, 10, 10, 10, 10, 1000, time.UTC), 11, "2023-10-10 10:10:10.000001"},
+		{time.Date(2023, 10, 10, 0, 0, 0, 123456000, time.UTC), 11, "2023-10-10 00:00:00.123456"},
+	}
+
+	for _, test := range tests {
+		got := AppendBinaryDateTime(nil, test.Data)
+		require.Equal(t, test.Length, int(got[0]), "test case %v", test.Data)
+		require.Len(t, got, test.Length+1)
+
+		formatted, err := FormatBinaryDateTime(int(got[0]), got[1:])
+		require.NoError(t, err)
+		require.Equal(t, test.Expect, string(formatted))
+	}
+}
+
+func TestAppendBinaryTime(t *testing.T) {
+	tests := []struct {
+		Data   time.Duration
+		Length int
+		Expect string
+	}{
+		{0, 0, "00:00:00"},
+		{time.Hour + 2*time.Minute + 3*time.Second, 8, "01:02:03"},
+		{-(time.Hour + 2*time.Minute + 3*time.Second), 8, "-01:02:03"},
+		{26*time.Hour + 4*time.Microsecond, 12, "26:00:00.000004"},
+		{-(838*time.Hour + 59*time.Minute + 59*time.Second + 500*time.Millisecond), 12, "-838:59:59.500000"},
+	}
+
+	for _, test := range tests {
+		got := AppendBinaryTime(nil, test.Data)
+		require.Equal(t, test.Length, int(got[0]), "test case %v", test.Data)
+		require.Len(t, got, test.Length+1)
+
+		formatted, err := FormatBinaryTime(int(got[0]), got[1:])
+		require.NoError(t, err)
+		require.Equal(t, test.Expect, string(formatted))
+	}
+}
+
+func TestSetAndEnumValues(t *testing.T) {
+	require.Equal(t, []string{}, SplitSetValue(""))
+	require.Equal(t, []string{"a", "c"}, SplitSetValue("a,c"))
+
+	members := []string{"a", "b", "c"}
+	require.Equal(t, []string{}, DecodeSetValue(0, members))
+	require.Equal(t, []string{"a", "c"}, DecodeSetValue(5, members))
+
+	require.Equal(t, "", DecodeEnumValue(0, members))
+	require.Equal(t, "b", DecodeEnumValue(2, members))
+	require.Equal(t, "", DecodeEnumValue(4, members))
+}
+
+func TestResultsetGetSet(t *testing.T) {
+	r := &Resultset{
+		Fields:     []*Field{{Name: []byte("s"), Type: MYSQL_TYPE_SET}},
+		FieldNames: map[string]int{"s": 0},
+		Values: [][]FieldValue{
+			{NewFieldValue(FieldValueTypeString, 0, []byte("a,b"))},
+			{NewFieldValue(FieldValueTypeString, 0, []byte{})},
+			{NewFieldValue(FieldValueTypeNull, 0, nil)},
+		},
+	}
+
+	s, err := r.GetSet(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, s)
+
+	s, err = r.GetSet(1, 0)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	require.Empty(t, s)
+
+	s, err = r.GetSetByName(2, "s")
+	require.NoError(t, err)
+	require.Nil(t, s)
+}
+
+func TestVectorValue(t *testing.T) {
+	v := []float32{0, 1.25, -3.5}
+	b := AppendVectorValue(nil, v)
+	require.Len(t, b, 12)
+
+	decoded, err := DecodeVectorValue(b)
+	require.NoError(t, err)
+	require.Equal(t, v, decoded)
+
+	_, err = DecodeVectorValue(b[:5])
+	require.Error(t, err)
+}
@@ -23,6 +23,8 @@ import (
 const MinCompressionLength = 50
 const DefaultBufferSize = 16 * 1024
 
+var errUnsupportedCompression = goErrors.New("unsupported compression algorithm set")
+
 // Conn is the base class to handle MySQL protocol.
 type Conn struct {
 	net.Conn
@@ -104,23 +106,6 @@ func (c *Conn) ReadPacketReuseMem(dst []byte) ([]byte, error) {
 		utils.BytesBufferPut(buf)
 	}()
 
-	if c.Compression != MYSQL_COMPRESS_NONE {
-		// it's possible that we're using compression but the server response with a compressed
-		// packet with uncompressed length of 0. In this case we leave compressedReader nil. The
-		// compressedReaderActive flag is important to track the state of the reader, allowing
-		// for the compressedReader to be reset after a packet write. Without this flag, when a
-		// compressed packet with uncompressed length of 0 is read, the compressedReader would
-		// be nil, and we'd incorrectly attempt to read the next packet as compressed.
-		if !c.compressedReaderActive {
-			var err error
-			c.compressedReader, err = c.newCompressedPacketReader()
-			if err != nil {
-				return nil, err
-			}
-			c.compressedReaderActive = true
-		}
-	}
-
 	if err := c.ReadPacketTo(buf); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -155,7 +140,7 @@ func (c *Conn) newCompressedPacketReader() (io.Reader, error) {
 		}
 	}
 	if _, err := io.ReadFull(c.reader, c.compressedHeader[:7]); err != nil {
-		return nil, errors.Wrapf(ErrBadConn, "io.ReadFull(compressedHeader) failed. err %v", err)
+		return nil, errors.Wrapf(badConnError(err), "io.ReadFull(compressedHeader) failed. err %v", err)
 	}
 
 	compressedSequence := c.compressedHeader[3]
@@ -239,6 +224,23 @@ func (c *Conn) copyN(dst io.Writer, n int64) (int64, error) {
 }
 
 func (c *Conn) ReadPacketTo(w io.Writer) error {
+	if c.Compression != MYSQL_COMPRESS_NONE {
+		// it's possible that we're using compression but the server response with a compressed
+		// packet with uncompressed length of 0. In this case we leave compressedReader nil. The
+		// compressedReaderActive flag is important to track the state of the reader, allowing
+		// for the compressedReader to be reset after a packet write. Without this flag, when a
+		// compressed packet with uncompressed length of 0 is read, the compressedReader would
+		// be nil, and we'd incorrectly attempt to read the next packet as compressed.
+		if !c.compressedReaderActive {
+			var err error
+			c.compressedReader, err = c.newCompressedPacketReader()
+			if err != nil {
+				return err
+			}
+			c.compressedReaderActive = true
+		}
+	}
+
 	b := utils.BytesBufferGet()
 	defer func() {
 		utils.BytesBufferPut(b)
@@ -249,7 +251,7 @@ func (c *Conn) ReadPacketTo(w io.Writer) error {
 	// buffer, since copyN is capable of getting the next compressed
 	// packet and updating the Conn state with a new compressedReader.
 	if _, err := c.copyN(b, 4); err != nil {
-		return errors.Wrapf(ErrBadConn, "io.ReadFull(header) failed. err %v", err)
+		return errors.Wrapf(badConnError(err), "io.ReadFull(header) failed. err %v", err)
 	} else {
 		// copy was successful so copy the 4 bytes from the buffer to the header
 		copy(c.header[:4], b.Bytes()[:4])
@@ -270,7 +272,7 @@ func (c *Conn) ReadPacketTo(w io.Writer) error {
 	}
 
 	if n, err := c.copyN(w, int64(length)); err != nil {
-		return errors.Wrapf(ErrBadConn, "io.CopyN failed. err %v, copied %v, expected %v", err, n, length)
+		return errors.Wrapf(badConnError(err), "io.CopyN failed. err %v, copied %v, expected %v", err, n, length)
 	} else if n != int64(length) {
 		return errors.Wrapf(ErrBadConn, "io.CopyN failed(n != int64(length)). %v bytes copied, while %v expected", n, length)
 	} else {
@@ -286,6 +288,27 @@ func (c *Conn) ReadPacketTo(w io.Writer) error {
 	return nil
 }
 
+// badConnError returns the error wrapped by the errors of failed reads and writes, ErrTimeout
+// if err is the timeout of a deadline, like ReadTimeout, or ErrBadConn otherwise.
+func badConnError(err error) error {
+	var netErr net.Error
+	if goErrors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	return ErrBadConn
+}
+
+// writeError returns the error of the write of a packet, what, that failed with err after n
+// bytes. The first packet of a command, of sequence 0, failing before any byte is written is
+// ErrNotSent, the command can be sent again on another connection.
+func (c *Conn) writeError(what string, err error, n int) error {
+	bad := badConnError(err)
+	if n == 0 && c.Sequence == 0 && bad != ErrTimeout {
+		bad = ErrNotSent
+	}
+	return errors.Wrapf(bad, "%s failed. err %v", what, err)
+}
+
 // WritePacket data already has 4 bytes header will modify data in-place
 func (c *Conn) WritePacket(data []byte) error {
 	length := len(data) - 4
@@ -298,7 +321,7 @@ func (c *Conn) WritePacket(data []byte) error {
 		data[3] = c.Sequence
 
 		if n, err := c.writeWithTimeout(data[:4+MaxPayloadLen]); err != nil {
-			return errors.Wrapf(ErrBadConn, "Write(payload portion) failed. err %v", err)
+			return c.writeError("Write(payload portion)", err, n)
 		} else if n != (4 + MaxPayloadLen) {
 			return errors.Wrapf(ErrBadConn, "Write(payload portion) failed. only %v bytes written, while %v expected", n, 4+MaxPayloadLen)
 		} else {
@@ -316,13 +339,13 @@ func (c *Conn) WritePacket(data []byte) error {
 	switch c.Compression {
 	case MYSQL_COMPRESS_NONE:
 		if n, err := c.writeWithTimeout(data); err != nil {
-			return errors.Wrapf(ErrBadConn, "Write failed. err %v", err)
+			return c.writeError("Write", err, n)
 		} else if n != len(data) {
 			return errors.Wrapf(ErrBadConn, "Write failed. only %v bytes written, while %v expected", n, len(data))
 		}
 	case MYSQL_COMPRESS_ZLIB, MYSQL_COMPRESS_ZSTD:
 		if n, err := c.writeCompressed(data); err != nil {
-			return errors.Wrapf(ErrBadConn, "Write failed. err %v", err)
+			return c.writeError("Write", err, n)
 		} else if n != len(data) {
 			return errors.Wrapf(ErrBadConn, "Write failed. only %v bytes written, while %v expected", n, len(data))
 		}
@@ -335,7 +358,7 @@ func (c *Conn) WritePacket(data []byte) error {
 			c.compressedReader = nil
 		}
 	default:
-		return errors.Wrapf(ErrBadConn, "Write failed. Unsuppored compression algorithm set")
+		return c.writeError("Write", errUnsupportedCompression, 0)
 	}
 
 	c.Sequence++
@@ -352,6 +375,8 @@ func (c *Conn) writeWithTimeout(b []byte) (n int, err error) {
 	return c.Write(b)
 }
 
+// writeCompressed writes data in a compressed packet and returns the length of data written.
+// It returns the bytes written to the connection on an error, none if compressing data failed.
 func (c *Conn) writeCompressed(data []byte) (n int, err error) {
 	var (
 		compressedLength, uncompressedLength int
@@ -370,7 +395,7 @@ func (c *Conn) writeCompressed(data []byte) (n int, err error) {
 		case MYSQL_COMPRESS_ZSTD:
 			w, err = zstd.NewWriter(payload)
 		default:
-			return 0, errors.Wrapf(ErrBadConn, "Write failed. Unsuppored compression algorithm set")
+			return 0, errUnsupportedCompression
 		}
 		if err != nil {
 			return 0, err
@@ -416,8 +441,8 @@ func (c *Conn) writeCompressed(data []byte) (n int, err error) {
 	if err != nil {
 		return 0, err
 	}
-	if _, err = c.writeWithTimeout(compressedPacket.Bytes()); err != nil {
-		return 0, err
+	if written, err := c.writeWithTimeout(compressedPacket.Bytes()); err != nil {
+		return written, err
 	}
 
 	return n, nil
@@ -453,6 +478,9 @@ func (c *Conn) WritePublicKeyAuthPacket(password string, cipher []byte) error {
 	}
 
 	block, _ := pem.Decode(data[1:])
+	if block == nil {
+		return errors.New("invalid public key sent by the server")
+	}
 	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
 		return errors.Wrap(err, "x509.ParsePKIXPublicKey failed")
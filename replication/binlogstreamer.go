@@ -27,10 +27,29 @@ func (s *BinlogStreamer) GetEvent(ctx context.Context) (*BinlogEvent, error) {
 		return nil, ErrNeedSyncAgain
 	}
 
+	return s.next(ctx)
+}
+
+// next returns the next event, the events received before an error are returned before it.
+func (s *BinlogStreamer) next(ctx context.Context) (*BinlogEvent, error) {
+	select {
+	case c := <-s.ch:
+		return c, nil
+	default:
+	}
+
 	select {
 	case c := <-s.ch:
 		return c, nil
-	case s.err = <-s.ech:
+	case err := <-s.ech:
+		// an event may have been added right before the error
+		select {
+		case c := <-s.ch:
+			s.AddErrorToStreamer(err)
+			return c, nil
+		default:
+		}
+		s.err = err
 		return nil, s.err
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -44,17 +63,14 @@ func (s *BinlogStreamer) GetEventWithStartTime(ctx context.Context, startTime ti
 		return nil, ErrNeedSyncAgain
 	}
 	startUnix := startTime.Unix()
-	select {
-	case c := <-s.ch:
-		if int64(c.Header.Timestamp) >= startUnix {
-			return c, nil
-		}
-		return nil, nil
-	case s.err = <-s.ech:
-		return nil, s.err
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	c, err := s.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if int64(c.Header.Timestamp) >= startUnix {
+		return c, nil
 	}
+	return nil, nil
 }
 
 // DumpEvents dumps all left events
@@ -24,6 +24,14 @@ import (
 
 var (
 	errSyncRunning = errors.New("Sync is running, must Close first")
+
+	// ErrGTIDNotAvailable is returned by CheckGTIDAvailable when the master has purged
+	// transactions that are not in the requested GTID set.
+	ErrGTIDNotAvailable = errors.New("requested GTID set needs transactions purged from the master")
+
+	// ErrGTIDModeOff is returned by Preflight when GTID based sync is requested but the
+	// master has gtid_mode=OFF.
+	ErrGTIDModeOff = errors.New("GTID based sync needs gtid_mode=ON on the master")
 )
 
 // BinlogSyncerConfig is the configuration for BinlogSyncer.
@@ -33,6 +41,10 @@ type BinlogSyncerConfig struct {
 	// Flavor is "mysql" or "mariadb", if not set, use "mysql" default.
 	Flavor string
 
+	// ParseServerVersion, if set, is the server version the events are decoded by, instead of
+	// the version announced by the server, see BinlogParser.SetServerVersion.
+	ParseServerVersion string
+
 	// Host is for MySQL server host.
 	Host string
 	// Port is for MySQL server port.
@@ -46,6 +58,15 @@ type BinlogSyncerConfig struct {
 	// If not set, use os.Hostname() instead.
 	Localhost string
 
+	// ReportPort is the port reported to the master when registering as a replica,
+	// shown in SHOW SLAVE HOSTS. If not set, Port is reported.
+	ReportPort uint16
+	// ReportUser and ReportPassword are reported to the master when registering
+	// as a replica, shown in SHOW SLAVE HOSTS with --show-replica-auth-info.
+	// If ReportUser is not set, User and Password are reported.
+	ReportUser     string
+	ReportPassword string
+
 	// Charset is for MySQL client character set
 	Charset string
 
@@ -122,10 +143,20 @@ type BinlogSyncerConfig struct {
 
 	RowsEventDecodeFunc func(*RowsEvent, []byte) error
 
+	// If not nil, the values of rows events are replaced by what it returns while they are
+	// decoded, so sensitive columns can be hashed or dropped. See RowValueTransformFunc.
+	RowValueTransformFunc RowValueTransformFunc
+
 	TableMapOptionalMetaDecodeFunc func([]byte) error
 
 	DiscardGTIDSet bool
 
+	// If not nil, only the events of the transactions in GTIDFilter are delivered, the events
+	// of other transactions are skipped but still advance the position. Events outside of
+	// transactions, like RotateEvent, are always delivered. MySQL only.
+	// See BinlogSyncer.GTIDFilterDone to know when all the transactions were delivered.
+	GTIDFilter *MysqlGTIDSet
+
 	EventCacheCount int
 
 	// SynchronousEventHandler is used for synchronous event handling.
@@ -153,11 +184,21 @@ type BinlogSyncer struct {
 
 	nextPos Position
 
+	// prevGset is the GTID set of the transactions read completely, syncing resumes from it,
+	// currGset adds the GTID of the transaction being read
 	prevGset, currGset GTIDSet
+	// guards the changes of prevGset, for ExecutedGTIDSet
+	gsetMu sync.Mutex
 
 	// instead of GTIDSet.Clone, use this to speed up calculate prevGset
 	prevMySQLGTIDEvent *GTIDEvent
 
+	// the sequence number of the last event of the transaction being received, 0 outside of
+	// a transaction, and the position of its first event
+	txnSeq int
+	txnPos Position
+	txn    txnBoundary
+
 	running bool
 
 	ctx    context.Context
@@ -166,6 +207,12 @@ type BinlogSyncer struct {
 	lastConnectionID uint32
 
 	retryCount int
+
+	// state of cfg.GTIDFilter
+	gtidFilterSkipping bool
+	gtidFilterTxn      *GTIDEvent
+	gtidFilterSeen     *MysqlGTIDSet
+	gtidFilterDone     chan struct{}
 }
 
 // NewBinlogSyncer creates the BinlogSyncer with the given configuration.
@@ -186,26 +233,33 @@ func NewBinlogSyncer(cfg BinlogSyncerConfig) *BinlogSyncer {
 	}
 
 	// Clear the Password to avoid outputting it in logs.
-	pass := cfg.Password
-	cfg.Password = ""
+	pass, reportPass := cfg.Password, cfg.ReportPassword
+	cfg.Password, cfg.ReportPassword = "", ""
 	cfg.Logger.Infof("create BinlogSyncer with config %+v", cfg)
-	cfg.Password = pass
+	cfg.Password, cfg.ReportPassword = pass, reportPass
 
 	b := new(BinlogSyncer)
 
 	b.cfg = cfg
 	b.parser = NewBinlogParser()
 	b.parser.SetFlavor(cfg.Flavor)
+	b.parser.SetServerVersion(cfg.ParseServerVersion)
 	b.parser.SetRawMode(b.cfg.RawModeEnabled)
 	b.parser.SetParseTime(b.cfg.ParseTime)
 	b.parser.SetTimestampStringLocation(b.cfg.TimestampStringLocation)
 	b.parser.SetUseDecimal(b.cfg.UseDecimal)
 	b.parser.SetVerifyChecksum(b.cfg.VerifyChecksum)
 	b.parser.SetRowsEventDecodeFunc(b.cfg.RowsEventDecodeFunc)
+	b.parser.SetRowValueTransformFunc(b.cfg.RowValueTransformFunc)
 	b.parser.SetTableMapOptionalMetaDecodeFunc(b.cfg.TableMapOptionalMetaDecodeFunc)
 	b.running = false
 	b.ctx, b.cancel = context.WithCancel(context.Background())
 
+	if cfg.GTIDFilter != nil {
+		b.gtidFilterSeen = &MysqlGTIDSet{Sets: make(map[string]*UUIDSet)}
+		b.gtidFilterDone = make(chan struct{})
+	}
+
 	return b
 }
 
@@ -463,7 +517,9 @@ func (b *BinlogSyncer) StartSyncGTID(gset GTIDSet) (*BinlogStreamer, error) {
 	b.cfg.Logger.Infof("begin to sync binlog from GTID set %s", gset)
 
 	b.prevMySQLGTIDEvent = nil
+	b.gsetMu.Lock()
 	b.prevGset = gset
+	b.gsetMu.Unlock()
 
 	b.m.Lock()
 	defer b.m.Unlock()
@@ -498,62 +554,180 @@ func (b *BinlogSyncer) StartSyncGTID(gset GTIDSet) (*BinlogStreamer, error) {
 	return b.startDumpStream(), nil
 }
 
-func (b *BinlogSyncer) writeBinlogDumpCommand(p Position) error {
-	b.c.ResetSequence()
+// CheckGTIDAvailable checks that the master can still serve all the transactions
+// missing from gset, so StartSyncGTID(gset) will not fail with error 1236.
+// It diffs gset against @@GLOBAL.gtid_purged and returns ErrGTIDNotAvailable,
+// annotated with the purged intervals that are not in gset, if there are any.
+//
+// Only the MySQL flavor is supported, for MariaDB this is a no-op.
+func (b *BinlogSyncer) CheckGTIDAvailable(gset GTIDSet) error {
+	if b.cfg.Flavor == MariaDBFlavor {
+		return nil
+	}
 
-	data := make([]byte, 4+1+4+2+4+len(p.Name))
+	requested, ok := gset.(*MysqlGTIDSet)
+	if !ok {
+		return errors.Errorf("expected MySQL GTID set, got %T", gset)
+	}
 
-	pos := 4
-	data[pos] = COM_BINLOG_DUMP
-	pos++
+	conn, err := b.newConnection(b.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer conn.Close()
 
-	binary.LittleEndian.PutUint32(data[pos:], p.Pos)
-	pos += 4
+	r, err := conn.Execute("SELECT @@GLOBAL.gtid_purged")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer r.Close()
 
-	binary.LittleEndian.PutUint16(data[pos:], b.cfg.DumpCommandFlag)
-	pos += 2
+	str, err := r.GetString(0, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	binary.LittleEndian.PutUint32(data[pos:], b.cfg.ServerID)
-	pos += 4
+	purged, err := ParseMysqlGTIDSet(str)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	copy(data[pos:], p.Name)
+	missing := unavailableGTIDs(requested, purged.(*MysqlGTIDSet))
+	if len(missing.Sets) > 0 {
+		return errors.Annotatef(ErrGTIDNotAvailable, "missing %s (gtid_purged %s)", missing, purged)
+	}
 
-	return b.c.WritePacket(data)
+	return nil
 }
 
-func (b *BinlogSyncer) writeBinlogDumpMysqlGTIDCommand(gset GTIDSet) error {
-	p := Position{Name: "", Pos: 4}
-	gtidData := gset.Encode()
+// Preflight checks that the master can serve the kind of sync that is about to be started,
+// GTID based (StartSyncGTID) if gtid is true or file position based (StartSync) otherwise,
+// so a misconfiguration fails with a clear message instead of a protocol error.
+// It reads @@GLOBAL.gtid_mode: GTID based sync fails with ErrGTIDModeOff if it is OFF, and
+// a warning is logged if the master may still write anonymous transactions, or if file
+// position based sync is requested from a master that only has GTID transactions.
+//
+// Only the MySQL flavor is supported, for MariaDB this is a no-op.
+func (b *BinlogSyncer) Preflight(gtid bool) error {
+	if b.cfg.Flavor == MariaDBFlavor {
+		return nil
+	}
 
-	b.c.ResetSequence()
+	conn, err := b.newConnection(b.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer conn.Close()
 
-	data := make([]byte, 4+1+2+4+4+len(p.Name)+8+4+len(gtidData))
-	pos := 4
-	data[pos] = COM_BINLOG_DUMP_GTID
-	pos++
+	r, err := conn.Execute("SELECT @@GLOBAL.gtid_mode")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer r.Close()
 
-	binary.LittleEndian.PutUint16(data[pos:], 0)
-	pos += 2
+	mode, err := r.GetString(0, 0)
+	if err != nil {
+		return errors.Trace(err)
+	}
 
-	binary.LittleEndian.PutUint32(data[pos:], b.cfg.ServerID)
-	pos += 4
+	warning, err := checkGTIDMode(mode, gtid)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if warning != "" {
+		b.cfg.Logger.Warnf("%s", warning)
+	}
+	return nil
+}
 
-	binary.LittleEndian.PutUint32(data[pos:], uint32(len(p.Name)))
-	pos += 4
+// BinlogFile is a binary log file of the master, as listed by ListBinlogs.
+type BinlogFile struct {
+	Name string
+	Size uint64
+	// Encrypted is only reported by MySQL 8.0 and later
+	Encrypted bool
+}
 
-	n := copy(data[pos:], p.Name)
-	pos += n
+// ListBinlogs returns the binary log files the master has, oldest first, from SHOW BINARY LOGS.
+// It uses a new connection, so it may be called while syncing.
+func (b *BinlogSyncer) ListBinlogs() ([]BinlogFile, error) {
+	conn, err := b.newConnection(b.ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer conn.Close()
 
-	binary.LittleEndian.PutUint64(data[pos:], uint64(p.Pos))
-	pos += 8
+	r, err := conn.Execute("SHOW BINARY LOGS")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Close()
 
-	binary.LittleEndian.PutUint32(data[pos:], uint32(len(gtidData)))
-	pos += 4
-	n = copy(data[pos:], gtidData)
-	pos += n
+	return parseBinlogFiles(r.Resultset)
+}
+
+// parseBinlogFiles reads the resultset of SHOW BINARY LOGS.
+func parseBinlogFiles(r *Resultset) ([]BinlogFile, error) {
+	encrypted, err := r.NameIndex("Encrypted")
+	if err != nil {
+		encrypted = -1
+	}
+
+	files := make([]BinlogFile, r.RowNumber())
+	for i := range files {
+		if files[i].Name, err = r.GetString(i, 0); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if files[i].Size, err = r.GetUint(i, 1); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if encrypted >= 0 {
+			s, err := r.GetString(i, encrypted)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			files[i].Encrypted = strings.EqualFold(s, "Yes")
+		}
+	}
+	return files, nil
+}
+
+// checkGTIDMode tells whether a master with gtid_mode set to mode can serve GTID based sync,
+// or file position based sync if gtid is false, returning a warning for setups that work but
+// may not behave as expected.
+func checkGTIDMode(mode string, gtid bool) (string, error) {
+	mode = strings.ToUpper(mode)
+	switch {
+	case gtid && mode == "OFF":
+		return "", errors.Annotatef(ErrGTIDModeOff, "gtid_mode is %s", mode)
+	case gtid && mode != "ON":
+		return fmt.Sprintf("gtid_mode is %s, GTID based sync fails on anonymous transactions", mode), nil
+	case !gtid && mode == "ON":
+		return "gtid_mode is ON, consider GTID based sync instead of file position based sync", nil
+	default:
+		return "", nil
+	}
+}
+
+// unavailableGTIDs returns the transactions of purged that are not in requested,
+// the master would need to send them but can't.
+func unavailableGTIDs(requested, purged *MysqlGTIDSet) *MysqlGTIDSet {
+	missing := purged.Clone().(*MysqlGTIDSet)
+	_ = missing.Minus(*requested)
+	return missing
+}
+
+func (b *BinlogSyncer) writeBinlogDumpCommand(p Position) error {
+	b.c.ResetSequence()
+
+	data := AppendBinlogDumpCommand(make([]byte, 4, 4+11+len(p.Name)), b.cfg.ServerID, b.cfg.DumpCommandFlag, p)
+	return b.c.WritePacket(data)
+}
 
-	data = data[0:pos]
+func (b *BinlogSyncer) writeBinlogDumpMysqlGTIDCommand(gset GTIDSet) error {
+	b.c.ResetSequence()
 
+	data := AppendBinlogDumpGTIDCommand(make([]byte, 4), b.cfg.ServerID, 0, Position{Name: "", Pos: 4}, gset)
 	return b.c.WritePacket(data)
 }
 
@@ -593,10 +767,31 @@ func (b *BinlogSyncer) localHostname() string {
 func (b *BinlogSyncer) writeRegisterSlaveCommand() error {
 	b.c.ResetSequence()
 
-	hostname := b.localHostname()
+	data, err := b.registerSlavePacket()
+	if err != nil {
+		return errors.Trace(err)
+	}
 
+	return b.c.WritePacket(data)
+}
+
+func (b *BinlogSyncer) registerSlavePacket() ([]byte, error) {
 	// This should be the name of slave host not the host we are connecting to.
-	data := make([]byte, 4+1+4+1+len(hostname)+1+len(b.cfg.User)+1+len(b.cfg.Password)+2+4+4)
+	hostname := b.localHostname()
+
+	port, user, password := b.cfg.Port, b.cfg.User, b.cfg.Password
+	if b.cfg.ReportPort != 0 {
+		port = b.cfg.ReportPort
+	}
+	if b.cfg.ReportUser != "" {
+		user, password = b.cfg.ReportUser, b.cfg.ReportPassword
+	}
+
+	if len(hostname) > 255 || len(user) > 255 || len(password) > 255 {
+		return nil, errors.New("reported host, user and password must be at most 255 bytes")
+	}
+
+	data := make([]byte, 4+1+4+1+len(hostname)+1+len(user)+1+len(password)+2+4+4)
 	pos := 4
 
 	data[pos] = COM_REGISTER_SLAVE
@@ -605,23 +800,22 @@ func (b *BinlogSyncer) writeRegisterSlaveCommand() error {
 	binary.LittleEndian.PutUint32(data[pos:], b.cfg.ServerID)
 	pos += 4
 
-	// This should be the name of slave hostname not the host we are connecting to.
 	data[pos] = uint8(len(hostname))
 	pos++
 	n := copy(data[pos:], hostname)
 	pos += n
 
-	data[pos] = uint8(len(b.cfg.User))
+	data[pos] = uint8(len(user))
 	pos++
-	n = copy(data[pos:], b.cfg.User)
+	n = copy(data[pos:], user)
 	pos += n
 
-	data[pos] = uint8(len(b.cfg.Password))
+	data[pos] = uint8(len(password))
 	pos++
-	n = copy(data[pos:], b.cfg.Password)
+	n = copy(data[pos:], password)
 	pos += n
 
-	binary.LittleEndian.PutUint16(data[pos:], b.cfg.Port)
+	binary.LittleEndian.PutUint16(data[pos:], port)
 	pos += 2
 
 	//replication rank, not used
@@ -631,7 +825,7 @@ func (b *BinlogSyncer) writeRegisterSlaveCommand() error {
 	// master ID, 0 is OK
 	binary.LittleEndian.PutUint32(data[pos:], 0)
 
-	return b.c.WritePacket(data)
+	return data, nil
 }
 
 func (b *BinlogSyncer) replySemiSyncACK(p Position) error {
@@ -868,6 +1062,9 @@ func (b *BinlogSyncer) handleEventAndACK(s *BinlogStreamer, e *BinlogEvent, need
 		b.nextPos.Name = string(event.NextLogName)
 		b.nextPos.Pos = uint32(event.Position)
 		b.cfg.Logger.Infof("rotate to %s", b.nextPos)
+		// syncing (re)started, or a new file, no transaction is in progress
+		b.txnSeq = 0
+		b.txn.reset()
 
 	case *GTIDEvent:
 		if b.prevGset == nil {
@@ -886,7 +1083,9 @@ func (b *BinlogSyncer) handleEventAndACK(s *BinlogStreamer, e *BinlogEvent, need
 			if err != nil {
 				return errors.Trace(err)
 			}
+			b.gsetMu.Lock()
 			b.prevGset.(*MysqlGTIDSet).AddGTID(u, b.prevMySQLGTIDEvent.GNO)
+			b.gsetMu.Unlock()
 		}
 		b.prevMySQLGTIDEvent = event
 
@@ -904,7 +1103,9 @@ func (b *BinlogSyncer) handleEventAndACK(s *BinlogStreamer, e *BinlogEvent, need
 		}
 		// Right after reconnect we may see the same GTID as before; update prevGset if currGset changed
 		if !b.currGset.Equal(prev) {
+			b.gsetMu.Lock()
 			b.prevGset = prev
+			b.gsetMu.Unlock()
 		}
 
 	case *XIDEvent:
@@ -918,21 +1119,37 @@ func (b *BinlogSyncer) handleEventAndACK(s *BinlogStreamer, e *BinlogEvent, need
 		}
 	}
 
-	// Use SynchronousEventHandler if it's set
-	if b.cfg.SynchronousEventHandler != nil {
-		err := b.cfg.SynchronousEventHandler.HandleEvent(e)
-		if err != nil {
-			return errors.Trace(err)
-		}
-	} else {
-		// Asynchronous mode: send the event to the streamer channel
-		select {
-		case s.ch <- e:
-		case <-b.ctx.Done():
-			return errors.New("sync is being closed...")
+	e.NextPosition = b.nextPos
+	txnEnd := b.txn.next(e)
+	b.trackTransaction(e, txnEnd)
+
+	deliver, err := b.filterGTID(e)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if deliver {
+		// Use SynchronousEventHandler if it's set
+		if b.cfg.SynchronousEventHandler != nil {
+			err := b.cfg.SynchronousEventHandler.HandleEvent(e)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		} else {
+			// Asynchronous mode: send the event to the streamer channel
+			select {
+			case s.ch <- e:
+			case <-b.ctx.Done():
+				return errors.New("sync is being closed...")
+			}
 		}
 	}
 
+	if txnEnd {
+		b.gtidTxnEnd()
+	}
+	b.gtidFilterTxnEnd(txnEnd)
+
 	if needACK {
 		err := b.replySemiSyncACK(b.nextPos)
 		if err != nil {
@@ -943,6 +1160,120 @@ func (b *BinlogSyncer) handleEventAndACK(s *BinlogStreamer, e *BinlogEvent, need
 	return nil
 }
 
+// GTIDFilterDone returns a channel closed once all the transactions of BinlogSyncerConfig.GTIDFilter
+// were delivered. It returns nil if there is no GTIDFilter.
+func (b *BinlogSyncer) GTIDFilterDone() <-chan struct{} {
+	return b.gtidFilterDone
+}
+
+// filterGTID returns whether the event passes cfg.GTIDFilter.
+func (b *BinlogSyncer) filterGTID(e *BinlogEvent) (bool, error) {
+	if b.cfg.GTIDFilter == nil {
+		return true, nil
+	}
+
+	if event, ok := e.Event.(*GTIDEvent); ok {
+		u, err := uuid.FromBytes(event.SID)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		gset := &MysqlGTIDSet{Sets: map[string]*UUIDSet{
+			u.String(): NewUUIDSet(u, Interval{Start: event.GNO, Stop: event.GNO + 1}),
+		}}
+		b.gtidFilterSkipping = !b.cfg.GTIDFilter.Contain(gset)
+		b.gtidFilterTxn = event
+	}
+
+	return !b.gtidFilterSkipping, nil
+}
+
+// gtidFilterTxnEnd records the transaction of cfg.GTIDFilter ended by the event, if any, and
+// closes gtidFilterDone once all the transactions were delivered.
+func (b *BinlogSyncer) gtidFilterTxnEnd(txnEnd bool) {
+	if b.cfg.GTIDFilter == nil || b.gtidFilterTxn == nil {
+		return
+	}
+
+	if !txnEnd {
+		return
+	}
+
+	if !b.gtidFilterSkipping {
+		u, _ := uuid.FromBytes(b.gtidFilterTxn.SID)
+		b.gtidFilterSeen.AddGTID(u, b.gtidFilterTxn.GNO)
+	}
+	b.gtidFilterTxn = nil
+	b.gtidFilterSkipping = false
+
+	select {
+	case <-b.gtidFilterDone:
+	default:
+		if b.gtidFilterSeen.Contain(b.cfg.GTIDFilter) {
+			close(b.gtidFilterDone)
+		}
+	}
+}
+
+// trackTransaction sets the TransactionSeq and TransactionPosition of the event, txnEnd
+// tells whether it ends the transaction.
+func (b *BinlogSyncer) trackTransaction(e *BinlogEvent, txnEnd bool) {
+	if b.txnSeq > 0 {
+		b.txnSeq++
+	} else if isTransactionStart(e) {
+		b.txnSeq = 1
+		b.txnPos = Position{Name: b.nextPos.Name, Pos: e.Header.LogPos - e.Header.EventSize}
+	} else {
+		// outside of a transaction, or in the one in progress when syncing started
+		return
+	}
+
+	e.TransactionSeq = b.txnSeq
+	e.TransactionPosition = b.txnPos
+	if txnEnd {
+		b.txnSeq = 0
+	}
+}
+
+// gtidTxnEnd adds the GTID of the transaction that ended to prevGset, once its events were
+// handled, so syncing resumes after it rather than from its first event.
+func (b *BinlogSyncer) gtidTxnEnd() {
+	if b.prevGset == nil || b.currGset == nil {
+		return
+	}
+
+	b.gsetMu.Lock()
+	defer b.gsetMu.Unlock()
+
+	switch gset := b.currGset.(type) {
+	case *MysqlGTIDSet:
+		if b.prevMySQLGTIDEvent != nil {
+			// the SID was parsed when the GTIDEvent was handled
+			u, _ := uuid.FromBytes(b.prevMySQLGTIDEvent.SID)
+			b.prevGset.(*MysqlGTIDSet).AddGTID(u, b.prevMySQLGTIDEvent.GNO)
+			b.prevMySQLGTIDEvent = nil
+		}
+	case *MariadbGTIDSet:
+		b.prevGset = gset.Clone()
+	}
+}
+
+// ExecutedGTIDSet returns a copy of the GTID set of the transactions read completely when
+// syncing with StartSyncGTID, the set to persist and pass to StartSyncGTID to resume syncing
+// after them, e.g. after a restart or on another server, as the GTIDs don't depend on the
+// binlog files. It returns nil when syncing from a position.
+// With a BinlogStreamer the transactions may be read ahead of the events returned by
+// GetEvent, the GSet of the XIDEvent or QueryEvent ending a transaction tracks what was
+// returned. With a SynchronousEventHandler they were handled.
+func (b *BinlogSyncer) ExecutedGTIDSet() GTIDSet {
+	b.gsetMu.Lock()
+	defer b.gsetMu.Unlock()
+
+	if b.prevGset == nil {
+		return nil
+	}
+	return b.prevGset.Clone()
+}
+
 // getCurrentGtidSet returns a clone of the current GTID set.
 func (b *BinlogSyncer) getCurrentGtidSet() GTIDSet {
 	if b.currGset != nil {
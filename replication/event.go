@@ -32,6 +32,22 @@ type BinlogEvent struct {
 
 	Header *EventHeader
 	Event  Event
+
+	// NextPosition is the binlog file and position right after this event, where syncing can be
+	// resumed from. It is only set for events received by BinlogSyncer, and has an empty file
+	// name until the first RotateEvent has been received.
+	NextPosition Position
+
+	// TransactionSeq is the sequence number of the event in its transaction, from 1 for the
+	// GTID event, or BEGIN query, starting it. A consumer which applied a transaction partly
+	// can resume from TransactionPosition and skip the events up to the last one it applied,
+	// instead of applying them again. It is 0 for the events outside of a transaction, or of
+	// the transaction in progress when syncing started. Like NextPosition, it is only set for
+	// events received by BinlogSyncer.
+	TransactionSeq int
+	// TransactionPosition is the binlog file and position of the first event of the transaction,
+	// it is set with TransactionSeq.
+	TransactionPosition Position
 }
 
 func (e *BinlogEvent) Dump(w io.Writer) {
@@ -106,6 +122,63 @@ func (h *EventHeader) Dump(w io.Writer) {
 	fmt.Fprintf(w, "Date: %s\n", time.Unix(int64(h.Timestamp), 0).Format(TimeFormat))
 	fmt.Fprintf(w, "Log position: %d\n", h.LogPos)
 	fmt.Fprintf(w, "Event size: %d\n", h.EventSize)
+	if h.Flags != 0 {
+		fmt.Fprintf(w, "Flags: %s\n", strings.Join(h.FlagNames(), "|"))
+	}
+}
+
+// HasFlag returns true if all the LOG_EVENT_*_F flags of f are set on the event.
+func (h *EventHeader) HasFlag(f uint16) bool {
+	return h.Flags&f == f
+}
+
+// IsArtificial returns true if the event was generated by the source instead of read from
+// a binlog file, like the ROTATE_EVENT sent when a replica connects. Its LogPos is 0 and it
+// doesn't move the position in the binlog file.
+func (h *EventHeader) IsArtificial() bool {
+	return h.HasFlag(LOG_EVENT_ARTIFICIAL_F)
+}
+
+// IsRelayLog returns true if the event was written by a replica to its relay log.
+func (h *EventHeader) IsRelayLog() bool {
+	return h.HasFlag(LOG_EVENT_RELAY_LOG_F)
+}
+
+// IsIgnorable returns true if the event can be skipped when its type is unknown.
+func (h *EventHeader) IsIgnorable() bool {
+	return h.HasFlag(LOG_EVENT_IGNORABLE_F)
+}
+
+var eventFlagNames = []struct {
+	flag uint16
+	name string
+}{
+	{LOG_EVENT_BINLOG_IN_USE_F, "LOG_EVENT_BINLOG_IN_USE_F"},
+	{LOG_EVENT_FORCED_ROTATE_F, "LOG_EVENT_FORCED_ROTATE_F"},
+	{LOG_EVENT_THREAD_SPECIFIC_F, "LOG_EVENT_THREAD_SPECIFIC_F"},
+	{LOG_EVENT_SUPPRESS_USE_F, "LOG_EVENT_SUPPRESS_USE_F"},
+	{LOG_EVENT_UPDATE_TABLE_MAP_VERSION_F, "LOG_EVENT_UPDATE_TABLE_MAP_VERSION_F"},
+	{LOG_EVENT_ARTIFICIAL_F, "LOG_EVENT_ARTIFICIAL_F"},
+	{LOG_EVENT_RELAY_LOG_F, "LOG_EVENT_RELAY_LOG_F"},
+	{LOG_EVENT_IGNORABLE_F, "LOG_EVENT_IGNORABLE_F"},
+	{LOG_EVENT_NO_FILTER_F, "LOG_EVENT_NO_FILTER_F"},
+	{LOG_EVENT_MTS_ISOLATE_F, "LOG_EVENT_MTS_ISOLATE_F"},
+}
+
+// FlagNames returns the names of the flags set on the event, unknown flags are given in hex.
+func (h *EventHeader) FlagNames() []string {
+	var names []string
+	flags := h.Flags
+	for _, f := range eventFlagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+			flags &^= f.flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%04x", flags))
+	}
+	return names
 }
 
 var (
@@ -154,6 +227,27 @@ type FormatDescriptionEvent struct {
 
 	// 0 is off, 1 is for CRC32, 255 is undefined
 	ChecksumAlgorithm byte
+
+	// set by the parser to decode the events by the rules of a flavor and server version,
+	// instead of guessing them from ServerVersion
+	flavor        string
+	serverVersion string
+}
+
+// decodingFlavor returns the flavor whose rules the events are decoded by, the one set by
+// the parser or else the one of the server version.
+func (e *FormatDescriptionEvent) decodingFlavor() string {
+	if e.flavor != "" {
+		return e.flavor
+	}
+	serverVersion := e.ServerVersion
+	if e.serverVersion != "" {
+		serverVersion = e.serverVersion
+	}
+	if strings.Contains(strings.ToLower(serverVersion), MariaDBFlavor) {
+		return MariaDBFlavor
+	}
+	return MySQLFlavor
 }
 
 func (e *FormatDescriptionEvent) Decode(data []byte) error {
@@ -181,12 +275,17 @@ func (e *FormatDescriptionEvent) Decode(data []byte) error {
 	} else {
 		e.ServerVersion = string(serverVersionRaw[:serverVersionLength])
 	}
+	serverVersion := e.ServerVersion
+	if e.serverVersion != "" {
+		serverVersion = e.serverVersion
+	}
+
 	checksumProduct := checksumVersionProductMysql
-	if strings.Contains(strings.ToLower(e.ServerVersion), "mariadb") {
+	if e.decodingFlavor() == MariaDBFlavor {
 		checksumProduct = checksumVersionProductMariaDB
 	}
 
-	if calcVersionProduct(e.ServerVersion) >= checksumProduct {
+	if calcVersionProduct(serverVersion) >= checksumProduct {
 		// here, the last 5 bytes is 1 byte check sum alg type and 4 byte checksum if exists
 		e.ChecksumAlgorithm = data[len(data)-5]
 		e.EventTypeHeaderLengths = data[pos : len(data)-5]
@@ -317,6 +416,12 @@ func (e *PreviousGTIDsEvent) Decode(data []byte) error {
 	return nil
 }
 
+// GTIDSet returns the set of transactions executed before the binlog file starting with this event.
+// Tagged GTIDs are not supported by MysqlGTIDSet, an error is returned if the set contains some.
+func (e *PreviousGTIDsEvent) GTIDSet() (GTIDSet, error) {
+	return ParseMysqlGTIDSet(e.GTIDSets)
+}
+
 func (e *PreviousGTIDsEvent) Dump(w io.Writer) {
 	fmt.Fprintf(w, "Previous GTID Event: %s\n", e.GTIDSets)
 	fmt.Fprintln(w)
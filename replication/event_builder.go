@@ -0,0 +1,145 @@
+package replication
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/google/uuid"
+)
+
+// eventTypeHeaderLengths are the post-header lengths of the event types of MySQL 8.0.
+var eventTypeHeaderLengths = []byte{
+	0x38, 0x0d, 0x00, 0x08, 0x00, 0x12, 0x00, 0x04, 0x04, 0x04, 0x04, 0x12, 0x00, 0x00, 0x5f, 0x00,
+	0x04, 0x1a, 0x08, 0x00, 0x00, 0x00, 0x08, 0x08, 0x08, 0x02, 0x00, 0x00, 0x00, 0x0a, 0x0a, 0x0a,
+	0x2a, 0x2a, 0x00, 0x12, 0x34, 0x00, 0x0a, 0x28, 0x00,
+}
+
+// EventBuilder builds the raw data of binlog events, e.g. to stream them from a server acting
+// as a replication source. Each event built advances LogPos.
+type EventBuilder struct {
+	ServerID  uint32
+	Timestamp uint32
+
+	// ChecksumAlgorithm is BINLOG_CHECKSUM_ALG_OFF or BINLOG_CHECKSUM_ALG_CRC32. It is announced by
+	// FormatDescription and applies to the events built after it.
+	ChecksumAlgorithm byte
+
+	// LogPos is the position of the next event in the binlog file
+	LogPos uint32
+
+	checksum bool
+}
+
+// Build returns the event of type t with the body, its RawData holding the header, the body and
+// the checksum if enabled. The Event of the returned BinlogEvent is nil.
+func (b *EventBuilder) Build(t EventType, body []byte) *BinlogEvent {
+	return b.build(t, 0, body, b.checksum)
+}
+
+func (b *EventBuilder) build(t EventType, flags uint16, body []byte, checksum bool) *BinlogEvent {
+	size := EventHeaderSize + len(body)
+	if checksum {
+		size += BinlogChecksumLength
+	}
+
+	h := &EventHeader{
+		Timestamp: b.Timestamp,
+		EventType: t,
+		ServerID:  b.ServerID,
+		EventSize: uint32(size),
+		Flags:     flags,
+	}
+	if flags&LOG_EVENT_ARTIFICIAL_F == 0 {
+		b.LogPos += uint32(size)
+		h.LogPos = b.LogPos
+	}
+
+	data := make([]byte, 0, size)
+	data = binary.LittleEndian.AppendUint32(data, h.Timestamp)
+	data = append(data, byte(h.EventType))
+	data = binary.LittleEndian.AppendUint32(data, h.ServerID)
+	data = binary.LittleEndian.AppendUint32(data, h.EventSize)
+	data = binary.LittleEndian.AppendUint32(data, h.LogPos)
+	data = binary.LittleEndian.AppendUint16(data, h.Flags)
+	data = append(data, body...)
+	if checksum {
+		data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	}
+
+	return &BinlogEvent{RawData: data, Header: h}
+}
+
+// FormatDescription builds the FORMAT_DESCRIPTION_EVENT starting every binlog file.
+func (b *EventBuilder) FormatDescription(serverVersion string) *BinlogEvent {
+	e := &FormatDescriptionEvent{
+		Version:                4,
+		ServerVersion:          serverVersion,
+		CreateTimestamp:        b.Timestamp,
+		EventHeaderLength:      byte(EventHeaderSize),
+		EventTypeHeaderLengths: eventTypeHeaderLengths,
+		ChecksumAlgorithm:      b.ChecksumAlgorithm,
+	}
+
+	body := binary.LittleEndian.AppendUint16(nil, e.Version)
+	serverVersionRaw := make([]byte, 50)
+	copy(serverVersionRaw, serverVersion)
+	body = append(body, serverVersionRaw...)
+	body = binary.LittleEndian.AppendUint32(body, e.CreateTimestamp)
+	body = append(body, e.EventHeaderLength)
+	body = append(body, e.EventTypeHeaderLengths...)
+	body = append(body, e.ChecksumAlgorithm)
+
+	// the event is always followed by a checksum, the algorithm applies to the next events
+	ev := b.build(FORMAT_DESCRIPTION_EVENT, 0, body, true)
+	ev.Event = e
+	b.checksum = b.ChecksumAlgorithm == BINLOG_CHECKSUM_ALG_CRC32
+	return ev
+}
+
+// Rotate builds the artificial ROTATE_EVENT a source sends first to tell the binlog file the
+// replica reads, and moves LogPos to pos.
+func (b *EventBuilder) Rotate(name string, pos uint64) *BinlogEvent {
+	body := binary.LittleEndian.AppendUint64(nil, pos)
+	body = append(body, name...)
+
+	ev := b.build(ROTATE_EVENT, LOG_EVENT_ARTIFICIAL_F, body, b.checksum)
+	ev.Event = &RotateEvent{Position: pos, NextLogName: []byte(name)}
+	b.LogPos = uint32(pos)
+	return ev
+}
+
+// Query builds a QUERY_EVENT, like BEGIN or a DDL statement.
+func (b *EventBuilder) Query(schema string, query string) *BinlogEvent {
+	body := make([]byte, 13, 13+len(schema)+1+len(query))
+	// slave proxy id, execution time and error code are 0, no status vars
+	body[8] = byte(len(schema))
+	body = append(body, schema...)
+	body = append(body, 0)
+	body = append(body, query...)
+
+	ev := b.Build(QUERY_EVENT, body)
+	ev.Event = &QueryEvent{Schema: []byte(schema), Query: []byte(query)}
+	return ev
+}
+
+// XID builds the XID_EVENT committing a transaction.
+func (b *EventBuilder) XID(xid uint64) *BinlogEvent {
+	ev := b.Build(XID_EVENT, binary.LittleEndian.AppendUint64(nil, xid))
+	ev.Event = &XIDEvent{XID: xid}
+	return ev
+}
+
+// GTID builds the GTID_EVENT starting the transaction sid:gno.
+func (b *EventBuilder) GTID(sid uuid.UUID, gno int64) *BinlogEvent {
+	body := []byte{1} // commit flag
+	body = append(body, sid[:]...)
+	body = binary.LittleEndian.AppendUint64(body, uint64(gno))
+	body = append(body, LogicalTimestampTypeCode)
+	// last committed and sequence number
+	body = binary.LittleEndian.AppendUint64(body, 0)
+	body = binary.LittleEndian.AppendUint64(body, 0)
+
+	ev := b.Build(GTID_EVENT, body)
+	ev.Event = &GTIDEvent{CommitFlag: 1, SID: sid[:], GNO: gno}
+	return ev
+}
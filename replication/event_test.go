@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
 )
 
 func TestMariadbGTIDListEvent(t *testing.T) {
@@ -193,4 +195,61 @@ func TestPreviousGTIDEvent(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, tc.GTIDSets, e.GTIDSets)
 	}
+
+	e := PreviousGTIDsEvent{}
+	require.NoError(t, e.Decode(testcases[1].input))
+	gset, err := e.GTIDSet()
+	require.NoError(t, err)
+	expected, err := mysql.ParseMysqlGTIDSet("896e7882-18fe-11ef-ab88-22222d34d411:1-3")
+	require.NoError(t, err)
+	require.True(t, expected.Equal(gset))
+
+	// tagged GTIDs can't be represented
+	require.NoError(t, e.Decode(testcases[2].input))
+	_, err = e.GTIDSet()
+	require.Error(t, err)
+}
+
+func TestEventBuilder(t *testing.T) {
+	b := &EventBuilder{ServerID: 1, ChecksumAlgorithm: BINLOG_CHECKSUM_ALG_CRC32}
+	p := NewBinlogParser()
+	p.SetVerifyChecksum(true)
+
+	built := []*BinlogEvent{
+		b.Rotate("mysql-bin.000002", 4),
+		b.FormatDescription("8.0.36-log"),
+		b.Query("db", "BEGIN"),
+		b.XID(9),
+	}
+	for _, ev := range built {
+		parsed, err := p.Parse(ev.RawData)
+		require.NoError(t, err)
+		require.Equal(t, ev.Header, parsed.Header)
+	}
+
+	require.Zero(t, built[0].Header.LogPos)
+	require.Equal(t, uint32(4)+built[1].Header.EventSize, built[1].Header.LogPos)
+	require.Equal(t, b.LogPos, built[3].Header.LogPos)
+}
+
+func TestEventHeaderFlags(t *testing.T) {
+	b := &EventBuilder{ServerID: 1}
+	p := NewBinlogParser()
+
+	ev, err := p.Parse(b.Rotate("mysql-bin.000002", 4).RawData)
+	require.NoError(t, err)
+	require.True(t, ev.Header.IsArtificial())
+	require.False(t, ev.Header.IsRelayLog())
+	require.Equal(t, []string{"LOG_EVENT_ARTIFICIAL_F"}, ev.Header.FlagNames())
+
+	ev, err = p.Parse(b.Query("db", "BEGIN").RawData)
+	require.NoError(t, err)
+	require.False(t, ev.Header.IsArtificial())
+	require.Empty(t, ev.Header.FlagNames())
+
+	h := &EventHeader{Flags: LOG_EVENT_SUPPRESS_USE_F | LOG_EVENT_IGNORABLE_F | 0x8000}
+	require.True(t, h.IsIgnorable())
+	require.True(t, h.HasFlag(LOG_EVENT_SUPPRESS_USE_F|LOG_EVENT_IGNORABLE_F))
+	require.False(t, h.HasFlag(LOG_EVENT_SUPPRESS_USE_F|LOG_EVENT_ARTIFICIAL_F))
+	require.Equal(t, []string{"LOG_EVENT_SUPPRESS_USE_F", "LOG_EVENT_IGNORABLE_F", "0x8000"}, h.FlagNames())
 }
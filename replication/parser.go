@@ -18,11 +18,17 @@ import (
 var (
 	// ErrChecksumMismatch indicates binlog checksum mismatch.
 	ErrChecksumMismatch = errors.New("binlog checksum mismatch, data may be corrupted")
+
+	// ErrPartialEvent indicates the binlog ended in the middle of an event, e.g. the file is
+	// still being written or the connection was cut, as opposed to ending cleanly between events.
+	ErrPartialEvent = errors.New("binlog event is truncated")
 )
 
 type BinlogParser struct {
 	// "mysql" or "mariadb", if not set, use "mysql" by default
 	flavor string
+	// if set, used instead of the version in the FORMAT_DESCRIPTION_EVENT
+	serverVersion string
 
 	format *FormatDescriptionEvent
 
@@ -42,6 +48,7 @@ type BinlogParser struct {
 	verifyChecksum      bool
 
 	rowsEventDecodeFunc func(*RowsEvent, []byte) error
+	rowValueTransform   RowValueTransformFunc
 
 	tableMapOptionalMetaDecodeFunc func([]byte) error
 }
@@ -68,6 +75,31 @@ func (p *BinlogParser) Reset() {
 
 type OnEventFunc func(*BinlogEvent) error
 
+// ParseFile parses the binlog file at path with a new BinlogParser, without any connection to
+// a server, calling onEvent for each event. Use BinlogParser.ParseFile to configure the parser.
+func ParseFile(path string, onEvent OnEventFunc) error {
+	return NewBinlogParser().ParseFile(path, 0, onEvent)
+}
+
+// ParseReader parses a whole binlog file read from r with a new BinlogParser, like ParseFile.
+// Unlike BinlogParser.ParseReader, r must start with the binlog file header.
+func ParseReader(r io.Reader, onEvent OnEventFunc) error {
+	if err := readBinlogFileHeader(r, "reader"); err != nil {
+		return errors.Trace(err)
+	}
+	return NewBinlogParser().ParseReader(r, onEvent)
+}
+
+func readBinlogFileHeader(r io.Reader, name string) error {
+	b := make([]byte, len(BinLogFileHeader))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return errors.Trace(err)
+	} else if !bytes.Equal(b, BinLogFileHeader) {
+		return errors.Errorf("%s is not a valid binlog file, head 4 bytes must fe'bin' ", name)
+	}
+	return nil
+}
+
 func (p *BinlogParser) ParseFile(name string, offset int64, onEvent OnEventFunc) error {
 	f, err := os.Open(name)
 	if err != nil {
@@ -75,11 +107,8 @@ func (p *BinlogParser) ParseFile(name string, offset int64, onEvent OnEventFunc)
 	}
 	defer f.Close()
 
-	b := make([]byte, 4)
-	if _, err = f.Read(b); err != nil {
-		return errors.Trace(err)
-	} else if !bytes.Equal(b, BinLogFileHeader) {
-		return errors.Errorf("%s is not a valid binlog file, head 4 bytes must fe'bin' ", name)
+	if err = readBinlogFileHeader(f, name); err != nil {
+		return err
 	}
 
 	if offset < 4 {
@@ -120,8 +149,10 @@ func (p *BinlogParser) parseSingleEvent(r io.Reader, onEvent OnEventFunc) (bool,
 	buf := utils.BytesBufferGet()
 	defer utils.BytesBufferPut(buf)
 
-	if n, err = io.CopyN(buf, r, EventHeaderSize); err == io.EOF {
+	if n, err = io.CopyN(buf, r, EventHeaderSize); err == io.EOF && n == 0 {
 		return true, nil
+	} else if err == io.EOF {
+		return false, errors.Annotatef(ErrPartialEvent, "need %d header bytes but got %d", EventHeaderSize, n)
 	} else if err != nil {
 		return false, errors.Errorf("get event header err %v, need %d but got %d", err, EventHeaderSize, n)
 	}
@@ -135,7 +166,9 @@ func (p *BinlogParser) parseSingleEvent(r io.Reader, onEvent OnEventFunc) (bool,
 	if h.EventSize < uint32(EventHeaderSize) {
 		return false, errors.Errorf("invalid event header, event size is %d, too small", h.EventSize)
 	}
-	if n, err = io.CopyN(buf, r, int64(h.EventSize-EventHeaderSize)); err != nil {
+	if n, err = io.CopyN(buf, r, int64(h.EventSize-EventHeaderSize)); err == io.EOF {
+		return false, errors.Annotatef(ErrPartialEvent, "event %s needs %d bytes but got %d", h.EventType, h.EventSize, n+EventHeaderSize)
+	} else if err != nil {
 		return false, errors.Errorf("get event err %v, need %d but got %d", err, h.EventSize, n)
 	}
 	if buf.Len() != int(h.EventSize) {
@@ -212,14 +245,32 @@ func (p *BinlogParser) SetVerifyChecksum(verify bool) {
 	p.verifyChecksum = verify
 }
 
+// SetFlavor sets the flavor, "mysql" or "mariadb", whose rules the events are decoded by. If not
+// set, it is the one of the server version announced by the FORMAT_DESCRIPTION_EVENT. It decides
+// from which version the events have a checksum, and whether the GEOMETRY columns of the table
+// map events are character columns, with a collation in the optional metadata, like in MariaDB.
 func (p *BinlogParser) SetFlavor(flavor string) {
 	p.flavor = flavor
 }
 
+// SetServerVersion sets the server version, like 5.5.62 or 10.5.8-MariaDB, used instead of the
+// version announced by the FORMAT_DESCRIPTION_EVENT to tell whether the events have a checksum
+// and, without SetFlavor, their flavor. It is for forks and builds with a version string the
+// parser can't make sense of.
+func (p *BinlogParser) SetServerVersion(version string) {
+	p.serverVersion = version
+}
+
 func (p *BinlogParser) SetRowsEventDecodeFunc(rowsEventDecodeFunc func(*RowsEvent, []byte) error) {
 	p.rowsEventDecodeFunc = rowsEventDecodeFunc
 }
 
+// SetRowValueTransformFunc sets a function the values of rows events go through while they
+// are decoded, see RowValueTransformFunc.
+func (p *BinlogParser) SetRowValueTransformFunc(f RowValueTransformFunc) {
+	p.rowValueTransform = f
+}
+
 func (p *BinlogParser) SetTableMapOptionalMetaDecodeFunc(tableMapOptionalMetaDecondeFunc func([]byte) error) {
 	p.tableMapOptionalMetaDecodeFunc = tableMapOptionalMetaDecondeFunc
 }
@@ -238,7 +289,7 @@ func (p *BinlogParser) parseEvent(h *EventHeader, data []byte, rawData []byte) (
 	var e Event
 
 	if h.EventType == FORMAT_DESCRIPTION_EVENT {
-		p.format = &FormatDescriptionEvent{}
+		p.format = &FormatDescriptionEvent{flavor: p.flavor, serverVersion: p.serverVersion}
 		e = p.format
 	} else {
 		if p.format != nil && p.format.ChecksumAlgorithm == BINLOG_CHECKSUM_ALG_CRC32 {
@@ -263,7 +314,7 @@ func (p *BinlogParser) parseEvent(h *EventHeader, data []byte, rawData []byte) (
 				e = &XIDEvent{}
 			case TABLE_MAP_EVENT:
 				te := &TableMapEvent{
-					flavor:                 p.flavor,
+					flavor:                 p.format.decodingFlavor(),
 					optionalMetaDecodeFunc: p.tableMapOptionalMetaDecodeFunc,
 				}
 				if p.format.EventTypeHeaderLengths[TABLE_MAP_EVENT-1] == 6 {
@@ -363,7 +414,9 @@ func (p *BinlogParser) Parse(data []byte) (*BinlogEvent, error) {
 	data = data[EventHeaderSize:]
 	eventLen := int(h.EventSize) - EventHeaderSize
 
-	if len(data) != eventLen {
+	if len(data) < eventLen {
+		return nil, errors.Annotatef(ErrPartialEvent, "event %s needs %d bytes but got %d", h.EventType, h.EventSize, len(rawData))
+	} else if len(data) != eventLen {
 		return nil, fmt.Errorf("invalid data size %d in event %s, less event length %d", len(data), h.EventType, eventLen)
 	}
 
@@ -412,6 +465,7 @@ func (p *BinlogParser) newRowsEvent(h *EventHeader) *RowsEvent {
 	e.timestampStringLocation = p.timestampStringLocation
 	e.useDecimal = p.useDecimal
 	e.ignoreJSONDecodeErr = p.ignoreJSONDecodeErr
+	e.valueTransform = p.rowValueTransform
 
 	switch h.EventType {
 	case WRITE_ROWS_EVENTv0:
@@ -455,6 +509,25 @@ func (p *BinlogParser) newRowsEvent(h *EventHeader) *RowsEvent {
 func (p *BinlogParser) newTransactionPayloadEvent() *TransactionPayloadEvent {
 	e := &TransactionPayloadEvent{}
 	e.format = *p.format
+	e.parser = p
 
 	return e
 }
+
+// newPayloadParser returns the parser of the events compressed in a TransactionPayloadEvent,
+// with the settings of p, like its RowValueTransformFunc, and format without checksums.
+// It has its own tables, the table map events are in the payload.
+func (p *BinlogParser) newPayloadParser(format *FormatDescriptionEvent) *BinlogParser {
+	parser := NewBinlogParser()
+	parser.format = format
+	parser.flavor = p.flavor
+	parser.serverVersion = p.serverVersion
+	parser.parseTime = p.parseTime
+	parser.timestampStringLocation = p.timestampStringLocation
+	parser.useDecimal = p.useDecimal
+	parser.ignoreJSONDecodeErr = p.ignoreJSONDecodeErr
+	parser.rowsEventDecodeFunc = p.rowsEventDecodeFunc
+	parser.rowValueTransform = p.rowValueTransform
+	parser.tableMapOptionalMetaDecodeFunc = p.tableMapOptionalMetaDecodeFunc
+	return parser
+}
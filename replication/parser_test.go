@@ -2,6 +2,9 @@ package replication
 
 import (
 	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -140,3 +143,113 @@ func TestRowsEventDecodeImageWithEmptyJSON(t *testing.T) {
 	require.Equal(t, []byte{}, row[4]) // empty json
 	require.Equal(t, int32(4404), row[7])
 }
+
+func TestParsePartialEvent(t *testing.T) {
+	parser := NewBinlogParser()
+	onEvent := func(*BinlogEvent) error { return nil }
+
+	// a clean end of stream between events
+	require.NoError(t, parser.ParseReader(bytes.NewReader(nil), onEvent))
+
+	// the stream ends in the middle of the event header
+	err := parser.ParseReader(bytes.NewReader(make([]byte, 10)), onEvent)
+	require.ErrorIs(t, err, ErrPartialEvent)
+
+	// the stream ends in the middle of the event body
+	header := make([]byte, EventHeaderSize)
+	header[4] = byte(QUERY_EVENT)
+	binary.LittleEndian.PutUint32(header[9:], 100)
+	err = parser.ParseReader(bytes.NewReader(append(header, make([]byte, 20)...)), onEvent)
+	require.ErrorIs(t, err, ErrPartialEvent)
+
+	_, err = parser.Parse(append(header, make([]byte, 20)...))
+	require.ErrorIs(t, err, ErrPartialEvent)
+}
+
+func TestParseFile(t *testing.T) {
+	b := &EventBuilder{ServerID: 1, ChecksumAlgorithm: BINLOG_CHECKSUM_ALG_CRC32, LogPos: 4}
+	data := append([]byte(nil), BinLogFileHeader...)
+	for _, ev := range []*BinlogEvent{
+		b.FormatDescription("8.0.36-log"),
+		b.Query("db", "BEGIN"),
+		b.XID(7),
+	} {
+		data = append(data, ev.RawData...)
+	}
+
+	path := filepath.Join(t.TempDir(), "mysql-bin.000001")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	var types []EventType
+	err := ParseFile(path, func(e *BinlogEvent) error {
+		types = append(types, e.Header.EventType)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []EventType{FORMAT_DESCRIPTION_EVENT, QUERY_EVENT, XID_EVENT}, types)
+
+	var xid *XIDEvent
+	err = ParseReader(bytes.NewReader(data), func(e *BinlogEvent) error {
+		if x, ok := e.Event.(*XIDEvent); ok {
+			xid = x
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, xid)
+	require.Equal(t, uint64(7), xid.XID)
+
+	err = ParseReader(bytes.NewReader(data[4:]), func(*BinlogEvent) error { return nil })
+	require.ErrorContains(t, err, "not a valid binlog file")
+}
+
+func TestParserServerVersion(t *testing.T) {
+	parseFDE := func(p *BinlogParser, serverVersion string) *FormatDescriptionEvent {
+		b := &EventBuilder{ServerID: 1, ChecksumAlgorithm: BINLOG_CHECKSUM_ALG_CRC32}
+		e, err := p.Parse(b.FormatDescription(serverVersion).RawData)
+		require.NoError(t, err)
+		return e.Event.(*FormatDescriptionEvent)
+	}
+
+	// the checksum can't be detected from a version string the parser doesn't know
+	require.Equal(t, BINLOG_CHECKSUM_ALG_UNDEF, parseFDE(NewBinlogParser(), "custom-build").ChecksumAlgorithm)
+
+	p := NewBinlogParser()
+	p.SetServerVersion("8.0.36")
+	fde := parseFDE(p, "custom-build")
+	require.Equal(t, BINLOG_CHECKSUM_ALG_CRC32, fde.ChecksumAlgorithm)
+	require.Equal(t, "custom-build", fde.ServerVersion)
+
+	// MariaDB has checksums since 5.3
+	require.Equal(t, BINLOG_CHECKSUM_ALG_UNDEF, parseFDE(NewBinlogParser(), "5.3.12").ChecksumAlgorithm)
+	p = NewBinlogParser()
+	p.SetFlavor("mariadb")
+	require.Equal(t, BINLOG_CHECKSUM_ALG_CRC32, parseFDE(p, "5.3.12").ChecksumAlgorithm)
+}
+
+func TestParserFlavor(t *testing.T) {
+	parseTableMap := func(p *BinlogParser, serverVersion string) *TableMapEvent {
+		b := &EventBuilder{ServerID: 1}
+		_, err := p.Parse(b.FormatDescription(serverVersion).RawData)
+		require.NoError(t, err)
+		e, err := p.Parse(b.Build(TABLE_MAP_EVENT, mariadb105TableMapEvent).RawData)
+		require.NoError(t, err)
+		return e.Event.(*TableMapEvent)
+	}
+
+	// the GEOMETRY columns of MariaDB have a collation, column 40 is g_geometry
+	e := parseTableMap(NewBinlogParser(), "10.5.8-MariaDB-log")
+	require.True(t, e.IsCharacterColumn(40))
+	require.Equal(t, uint64(63), e.CollationMap()[40])
+
+	// the flavor is detected from the version set on the parser too
+	p := NewBinlogParser()
+	p.SetServerVersion("10.5.8-MariaDB")
+	require.True(t, parseTableMap(p, "custom-build").IsCharacterColumn(40))
+
+	// the flavor set wins over the server version
+	p = NewBinlogParser()
+	p.SetFlavor("mysql")
+	require.False(t, parseTableMap(p, "10.5.8-MariaDB-log").IsCharacterColumn(40))
+	require.False(t, parseTableMap(NewBinlogParser(), "8.0.36").IsCharacterColumn(40))
+}
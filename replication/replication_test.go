@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -347,6 +348,19 @@ func (t *testSyncerSuite) TestMysqlPositionSync() {
 	t.testPositionSync()
 }
 
+func (t *testSyncerSuite) TestListBinlogs() {
+	t.setupTest(mysql.MySQLFlavor)
+
+	files, err := t.b.ListBinlogs()
+	require.NoError(t.T(), err)
+	require.NotEmpty(t.T(), files)
+
+	r, err := t.c.Execute("SHOW MASTER STATUS")
+	require.NoError(t.T(), err)
+	current, _ := r.GetString(0, 0)
+	require.Equal(t.T(), current, files[len(files)-1].Name)
+}
+
 func (t *testSyncerSuite) TestMysqlGTIDSync() {
 	t.setupTest(mysql.MySQLFlavor)
 
@@ -464,3 +478,409 @@ func (t *testSyncerSuite) TestMysqlBinlogCodec() {
 		require.NoError(t.T(), err)
 	}
 }
+
+func TestUnavailableGTIDs(t *testing.T) {
+	const sid = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+	purged, err := mysql.ParseMysqlGTIDSet(sid + ":1-100")
+	require.NoError(t, err)
+
+	requested, err := mysql.ParseMysqlGTIDSet(sid + ":1-100")
+	require.NoError(t, err)
+	missing := unavailableGTIDs(requested.(*mysql.MysqlGTIDSet), purged.(*mysql.MysqlGTIDSet))
+	require.Empty(t, missing.Sets)
+
+	requested, err = mysql.ParseMysqlGTIDSet(sid + ":1-10:20-200")
+	require.NoError(t, err)
+	missing = unavailableGTIDs(requested.(*mysql.MysqlGTIDSet), purged.(*mysql.MysqlGTIDSet))
+	require.Equal(t, sid+":11-19", missing.String())
+
+	// purged set must be left untouched
+	require.Equal(t, sid+":1-100", purged.String())
+}
+
+func TestCheckGTIDMode(t *testing.T) {
+	_, err := checkGTIDMode("OFF", true)
+	require.ErrorIs(t, err, ErrGTIDModeOff)
+
+	for _, mode := range []string{"OFF_PERMISSIVE", "ON_PERMISSIVE"} {
+		warning, err := checkGTIDMode(mode, true)
+		require.NoError(t, err)
+		require.Contains(t, warning, mode)
+	}
+
+	warning, err := checkGTIDMode("ON", true)
+	require.NoError(t, err)
+	require.Empty(t, warning)
+
+	warning, err = checkGTIDMode("on", false)
+	require.NoError(t, err)
+	require.NotEmpty(t, warning)
+
+	for _, mode := range []string{"OFF", "OFF_PERMISSIVE", "ON_PERMISSIVE"} {
+		warning, err = checkGTIDMode(mode, false)
+		require.NoError(t, err)
+		require.Empty(t, warning)
+	}
+}
+
+func TestRegisterSlavePacket(t *testing.T) {
+	b := &BinlogSyncer{cfg: BinlogSyncerConfig{
+		ServerID:  101,
+		Port:      3306,
+		User:      "repl",
+		Password:  "secret",
+		Localhost: "cdc-1",
+	}}
+
+	data, err := b.registerSlavePacket()
+	require.NoError(t, err)
+	expected := []byte{mysql.COM_REGISTER_SLAVE, 101, 0, 0, 0, 5, 'c', 'd', 'c', '-', '1', 4, 'r', 'e', 'p', 'l', 6, 's', 'e', 'c', 'r', 'e', 't', 0xea, 0x0c, 0, 0, 0, 0, 0, 0, 0, 0}
+	require.Equal(t, expected, data[4:])
+
+	b.cfg.ReportPort = 4000
+	b.cfg.ReportUser = "cdc"
+	data, err = b.registerSlavePacket()
+	require.NoError(t, err)
+	expected = []byte{mysql.COM_REGISTER_SLAVE, 101, 0, 0, 0, 5, 'c', 'd', 'c', '-', '1', 3, 'c', 'd', 'c', 0, 0xa0, 0x0f, 0, 0, 0, 0, 0, 0, 0, 0}
+	require.Equal(t, expected, data[4:])
+
+	b.cfg.Localhost = strings.Repeat("h", 256)
+	_, err = b.registerSlavePacket()
+	require.Error(t, err)
+}
+
+func TestParseBinlogFiles(t *testing.T) {
+	files, err := parseBinlogFiles(buildTextResultset(t, []string{"Log_name", "File_size", "Encrypted"}, [][]interface{}{
+		{"mysql-bin.000001", uint64(177), "No"},
+		{"mysql-bin.000002", uint64(1073741824), "Yes"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []BinlogFile{
+		{Name: "mysql-bin.000001", Size: 177},
+		{Name: "mysql-bin.000002", Size: 1073741824, Encrypted: true},
+	}, files)
+
+	// before MySQL 8.0
+	files, err = parseBinlogFiles(buildTextResultset(t, []string{"Log_name", "File_size"}, [][]interface{}{
+		{"mysql-bin.000003", uint64(4)},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []BinlogFile{{Name: "mysql-bin.000003", Size: 4}}, files)
+}
+
+// buildTextResultset returns a resultset like the ones read by the client.
+func buildTextResultset(t *testing.T, names []string, values [][]interface{}) *mysql.Resultset {
+	r, err := mysql.BuildSimpleTextResultset(names, values)
+	require.NoError(t, err)
+
+	r.FieldNames = make(map[string]int)
+	for i, f := range r.Fields {
+		r.FieldNames[string(f.Name)] = i
+	}
+	for _, data := range r.RowDatas {
+		row, err := data.Parse(r.Fields, false, nil)
+		require.NoError(t, err)
+		r.Values = append(r.Values, row)
+	}
+	return r
+}
+
+func TestEventNextPosition(t *testing.T) {
+	b := NewBinlogSyncer(BinlogSyncerConfig{ServerID: 100, DiscardGTIDSet: true})
+	defer b.Close()
+	s := NewBinlogStreamer()
+
+	rotate := &BinlogEvent{
+		Header: &EventHeader{EventType: ROTATE_EVENT},
+		Event:  &RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000002")},
+	}
+	require.NoError(t, b.handleEventAndACK(s, rotate, false))
+	require.Equal(t, mysql.Position{Name: "mysql-bin.000002", Pos: 4}, rotate.NextPosition)
+
+	query := &BinlogEvent{
+		Header: &EventHeader{EventType: QUERY_EVENT, LogPos: 120},
+		Event:  &QueryEvent{Query: []byte("BEGIN")},
+	}
+	require.NoError(t, b.handleEventAndACK(s, query, false))
+	require.Equal(t, mysql.Position{Name: "mysql-bin.000002", Pos: 120}, query.NextPosition)
+}
+
+func TestEventTransactionSeq(t *testing.T) {
+	b := NewBinlogSyncer(BinlogSyncerConfig{ServerID: 100, DiscardGTIDSet: true})
+	defer b.Close()
+	s := NewBinlogStreamer()
+
+	handle := func(ev Event, logPos uint32, size uint32) *BinlogEvent {
+		e := &BinlogEvent{Header: &EventHeader{LogPos: logPos, EventSize: size}, Event: ev}
+		require.NoError(t, b.handleEventAndACK(s, e, false))
+		return e
+	}
+	rotate := func() *BinlogEvent {
+		return handle(&RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")}, 0, 0)
+	}
+	u := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+
+	require.Zero(t, rotate().TransactionSeq)
+	events := []*BinlogEvent{
+		handle(&GTIDEvent{SID: u[:], GNO: 1}, 200, 96),
+		handle(&QueryEvent{Query: []byte("BEGIN")}, 300, 100),
+		handle(&TableMapEvent{}, 350, 50),
+		handle(&RowsEvent{}, 400, 50),
+		handle(&RowsEvent{}, 450, 50),
+		handle(&XIDEvent{XID: 1}, 480, 30),
+	}
+	for i, e := range events {
+		require.Equal(t, i+1, e.TransactionSeq)
+		require.Equal(t, mysql.Position{Name: "mysql-bin.000001", Pos: 104}, e.TransactionPosition)
+	}
+
+	// a DDL is a transaction on its own
+	require.Equal(t, 1, handle(&GTIDEvent{SID: u[:], GNO: 2}, 576, 96).TransactionSeq)
+	ddl := handle(&QueryEvent{Query: []byte("CREATE TABLE t (id int)")}, 700, 124)
+	require.Equal(t, 2, ddl.TransactionSeq)
+	require.Equal(t, mysql.Position{Name: "mysql-bin.000001", Pos: 480}, ddl.TransactionPosition)
+
+	// syncing restarted in the middle of a transaction
+	require.Equal(t, 1, handle(&GTIDEvent{SID: u[:], GNO: 3}, 796, 96).TransactionSeq)
+	require.Zero(t, rotate().TransactionSeq)
+	require.Zero(t, handle(&RowsEvent{}, 900, 50).TransactionSeq)
+	require.Zero(t, handle(&XIDEvent{XID: 3}, 930, 30).TransactionSeq)
+	require.Equal(t, 1, handle(&QueryEvent{Query: []byte("BEGIN")}, 1030, 100).TransactionSeq)
+}
+
+func TestExecutedGTIDSet(t *testing.T) {
+	u1 := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	u2 := uuid.MustParse("5a3e5e7c-9f3b-11ee-8c90-0242ac120002")
+	gset, err := mysql.ParseMysqlGTIDSet(u1.String() + ":1-5")
+	require.NoError(t, err)
+
+	b := NewBinlogSyncer(BinlogSyncerConfig{ServerID: 100})
+	defer b.Close()
+	require.Nil(t, b.ExecutedGTIDSet())
+	// as StartSyncGTID(gset) does before connecting
+	b.prevGset = gset
+	s := NewBinlogStreamer()
+
+	handle := func(ev Event) *BinlogEvent {
+		e := &BinlogEvent{Header: &EventHeader{}, Event: ev}
+		require.NoError(t, b.handleEventAndACK(s, e, false))
+		return e
+	}
+	executed := func() string {
+		return b.ExecutedGTIDSet().String()
+	}
+
+	handle(&RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000012")})
+	handle(&GTIDEvent{SID: u1[:], GNO: 6})
+	handle(&QueryEvent{Query: []byte("BEGIN")})
+	// the transaction is not read completely yet
+	require.Equal(t, u1.String()+":1-5", executed())
+	xid := handle(&XIDEvent{XID: 6})
+	require.Equal(t, u1.String()+":1-6", executed())
+	require.Equal(t, xid.Event.(*XIDEvent).GSet.String(), executed())
+
+	handle(&GTIDEvent{SID: u1[:], GNO: 7})
+	handle(&QueryEvent{Query: []byte("CREATE TABLE t (id int)")})
+	require.Equal(t, u1.String()+":1-7", executed())
+
+	// the copy returned is not changed by the next transactions
+	copied := b.ExecutedGTIDSet()
+
+	// resyncing on a new primary, as retrySync does, whose binlog files start again
+	b.prevMySQLGTIDEvent = nil
+	b.currGset = nil
+	handle(&RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")})
+	handle(&GTIDEvent{SID: u2[:], GNO: 1})
+	handle(&QueryEvent{Query: []byte("BEGIN")})
+	handle(&XIDEvent{XID: 1})
+	want, err := mysql.ParseMysqlGTIDSet(u1.String() + ":1-7," + u2.String() + ":1")
+	require.NoError(t, err)
+	require.True(t, want.Equal(b.ExecutedGTIDSet()))
+	require.Equal(t, u1.String()+":1-7", copied.String())
+}
+
+func TestTransactionEndStatements(t *testing.T) {
+	u := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	query := func(q string) Event {
+		return &QueryEvent{Query: []byte(q)}
+	}
+	mariadbGTID := func(seq uint64, flags byte) Event {
+		return &MariadbGTIDEvent{GTID: mysql.MariadbGTID{DomainID: 0, ServerID: 1, SequenceNumber: seq}, Flags: flags}
+	}
+
+	tests := []struct {
+		name   string
+		gset   string
+		events []Event
+		// the GTID set executed after each event
+		executed []string
+	}{
+		{
+			name: "statement-based DML",
+			gset: u.String() + ":1-5",
+			events: []Event{
+				&GTIDEvent{SID: u[:], GNO: 6}, query("BEGIN"), query("INSERT INTO t VALUES (1)"),
+				query("UPDATE t SET id = 2"), &XIDEvent{XID: 6},
+			},
+			executed: []string{":1-5", ":1-5", ":1-5", ":1-5", ":1-6"},
+		},
+		{
+			name: "savepoint",
+			gset: u.String() + ":1-5",
+			events: []Event{
+				&GTIDEvent{SID: u[:], GNO: 6}, query("BEGIN"), query("SAVEPOINT a"),
+				&TableMapEvent{}, &RowsEvent{}, query("ROLLBACK TO a"), query("COMMIT"),
+			},
+			executed: []string{":1-5", ":1-5", ":1-5", ":1-5", ":1-5", ":1-5", ":1-6"},
+		},
+		{
+			name: "MariaDB DML",
+			gset: "0-1-5",
+			events: []Event{
+				mariadbGTID(6, 0), query("INSERT INTO t VALUES (1)"), query("UPDATE t SET id = 2"), query("COMMIT"),
+				mariadbGTID(7, BINLOG_MARIADB_FL_STANDALONE|BINLOG_MARIADB_FL_DDL), query("CREATE TABLE u (id int)"),
+			},
+			executed: []string{"0-1-5", "0-1-5", "0-1-5", "0-1-6", "0-1-6", "0-1-7"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flavor := mysql.MySQLFlavor
+			if strings.HasPrefix(test.gset, "0-") {
+				flavor = mysql.MariaDBFlavor
+			}
+			gset, err := mysql.ParseGTIDSet(flavor, test.gset)
+			require.NoError(t, err)
+
+			b := NewBinlogSyncer(BinlogSyncerConfig{ServerID: 100, Flavor: flavor})
+			defer b.Close()
+			b.prevGset = gset
+			s := NewBinlogStreamer()
+
+			require.NoError(t, b.handleEventAndACK(s, &BinlogEvent{Header: &EventHeader{}, Event: &RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")}}, false))
+			for i, ev := range test.events {
+				e := &BinlogEvent{Header: &EventHeader{}, Event: ev}
+				require.NoError(t, b.handleEventAndACK(s, e, false))
+				want := test.executed[i]
+				if flavor == mysql.MySQLFlavor {
+					want = u.String() + want
+				}
+				require.Equal(t, want, b.ExecutedGTIDSet().String(), "event %d", i)
+				if i < 4 {
+					require.Equal(t, i+1, e.TransactionSeq, "event %d", i)
+				}
+			}
+
+			// the transaction streamer ends the transactions at the same events
+			ts := NewTransactionStreamer(s, 0)
+			txn, err := ts.GetTransaction(context.Background())
+			require.NoError(t, err)
+			require.IsType(t, &RotateEvent{}, txn.Events[0].Event)
+			txn, err = ts.GetTransaction(context.Background())
+			require.NoError(t, err)
+			n := len(test.events)
+			if flavor == mysql.MariaDBFlavor {
+				n = 4
+			}
+			require.Len(t, txn.Events, n)
+		})
+	}
+}
+
+func TestGTIDFilter(t *testing.T) {
+	u := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	filter, err := mysql.ParseMysqlGTIDSet(u.String() + ":2-3")
+	require.NoError(t, err)
+
+	b := NewBinlogSyncer(BinlogSyncerConfig{ServerID: 100, DiscardGTIDSet: true, GTIDFilter: filter.(*mysql.MysqlGTIDSet)})
+	defer b.Close()
+	s := NewBinlogStreamer()
+
+	txn := func(gno int64) {
+		events := []Event{
+			&GTIDEvent{SID: u[:], GNO: gno},
+			&QueryEvent{Query: []byte("BEGIN")},
+			&XIDEvent{XID: uint64(gno)},
+		}
+		for _, ev := range events {
+			require.NoError(t, b.handleEventAndACK(s, &BinlogEvent{Header: &EventHeader{}, Event: ev}, false))
+		}
+	}
+
+	require.NoError(t, b.handleEventAndACK(s, &BinlogEvent{Header: &EventHeader{}, Event: &RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")}}, false))
+	txn(1)
+	txn(2)
+	require.Len(t, s.ch, 4)
+	select {
+	case <-b.GTIDFilterDone():
+		t.Fatal("filter is not done yet")
+	default:
+	}
+
+	txn(3)
+	txn(4)
+	require.Len(t, s.ch, 7)
+	<-b.GTIDFilterDone()
+
+	require.IsType(t, &RotateEvent{}, (<-s.ch).Event)
+	gtid := (<-s.ch).Event.(*GTIDEvent)
+	require.Equal(t, int64(2), gtid.GNO)
+}
+
+func TestTransactionStreamer(t *testing.T) {
+	u := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	s := NewBinlogStreamer()
+	add := func(events ...Event) {
+		for _, ev := range events {
+			require.NoError(t, s.AddEventToStreamer(&BinlogEvent{Header: &EventHeader{}, Event: ev}))
+		}
+	}
+
+	add(&RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")})
+	add(&GTIDEvent{SID: u[:], GNO: 1}, &QueryEvent{Query: []byte("BEGIN")},
+		&TableMapEvent{}, &RowsEvent{}, &RowsEvent{}, &XIDEvent{XID: 1})
+	add(&GTIDEvent{SID: u[:], GNO: 2}, &QueryEvent{Query: []byte("CREATE TABLE t (id int)")})
+	add(&GTIDEvent{SID: u[:], GNO: 3}, &QueryEvent{Query: []byte("BEGIN")},
+		&TableMapEvent{}, &RowsEvent{}, &TableMapEvent{}, &RowsEvent{}, &XIDEvent{XID: 3})
+
+	ts := NewTransactionStreamer(s, 6)
+	ctx := context.Background()
+
+	txn, err := ts.GetTransaction(ctx)
+	require.NoError(t, err)
+	require.Nil(t, txn.GTID)
+	require.Len(t, txn.Events, 1)
+	require.IsType(t, &RotateEvent{}, txn.Events[0].Event)
+
+	txn, err = ts.GetTransaction(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), txn.GTID.Event.(*GTIDEvent).GNO)
+	require.False(t, txn.Partial)
+	require.Len(t, txn.Events, 6)
+	require.Len(t, txn.RowsEvents(), 2)
+
+	txn, err = ts.GetTransaction(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), txn.GTID.Event.(*GTIDEvent).GNO)
+	require.Len(t, txn.Events, 2)
+
+	// the last transaction is split in batches of 6 events
+	txn, err = ts.GetTransaction(ctx)
+	require.NoError(t, err)
+	require.True(t, txn.Partial)
+	require.Len(t, txn.Events, 6)
+	require.Len(t, txn.RowsEvents(), 2)
+
+	txn, err = ts.GetTransaction(ctx)
+	require.NoError(t, err)
+	require.False(t, txn.Partial)
+	require.Equal(t, int64(3), txn.GTID.Event.(*GTIDEvent).GNO)
+	require.Len(t, txn.Events, 1)
+	require.IsType(t, &XIDEvent{}, txn.Events[0].Event)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = ts.GetTransaction(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
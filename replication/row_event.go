@@ -1,10 +1,13 @@
 package replication
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -180,6 +183,7 @@ func bitmapByteSize(columnCount int) int {
 	MYSQL_TYPE_DOUBLE
 	MYSQL_TYPE_BLOB
 	MYSQL_TYPE_GEOMETRY
+	MYSQL_TYPE_VECTOR
 
 	//maybe
 	MYSQL_TYPE_TIME2
@@ -225,7 +229,8 @@ func (e *TableMapEvent) decodeMeta(data []byte) error {
 			MYSQL_TYPE_DOUBLE,
 			MYSQL_TYPE_FLOAT,
 			MYSQL_TYPE_GEOMETRY,
-			MYSQL_TYPE_JSON:
+			MYSQL_TYPE_JSON,
+			MYSQL_TYPE_VECTOR:
 			e.ColumnMeta[i] = uint16(data[pos])
 			pos++
 		case MYSQL_TYPE_TIME2,
@@ -727,6 +732,42 @@ func (e *TableMapEvent) strValueMap(includeType func(int) bool, strValue [][]str
 	return ret
 }
 
+// SetValue decodes the value v of the SET column at index column in a rows event of this table:
+// nil is returned for NULL and an empty slice for the empty set.
+// It requires the SET_STR_VALUE metadata, see binlog_row_metadata.
+func (e *TableMapEvent) SetValue(column int, v interface{}) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	members, ok := e.SetStrValueMap()[column]
+	if !ok {
+		return nil, errors.Errorf("no set values for column %d", column)
+	}
+	bits, ok := v.(int64)
+	if !ok {
+		return nil, errors.Errorf("invalid set value %T for column %d", v, column)
+	}
+	return DecodeSetValue(bits, members), nil
+}
+
+// EnumValue decodes the value v of the ENUM column at index column in a rows event of this table:
+// "" is returned for NULL and for index 0, which MySQL uses for invalid values.
+// It requires the ENUM_STR_VALUE metadata, see binlog_row_metadata.
+func (e *TableMapEvent) EnumValue(column int, v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	members, ok := e.EnumStrValueMap()[column]
+	if !ok {
+		return "", errors.Errorf("no enum values for column %d", column)
+	}
+	index, ok := v.(int64)
+	if !ok {
+		return "", errors.Errorf("invalid enum value %T for column %d", v, column)
+	}
+	return DecodeEnumValue(index, members), nil
+}
+
 // GeometryTypeMap returns a map: column index -> geometry type.
 // Note that only geometry columns will be returned.
 // nil is returned if not available or no geometry columns at all.
@@ -890,8 +931,11 @@ const RowsEventStmtEndFlag = 0x01
 // - MYSQL_TYPE_STRING: string
 // - MYSQL_TYPE_JSON: []byte / *replication.JsonDiff
 // - MYSQL_TYPE_GEOMETRY: []byte
+// - MYSQL_TYPE_VECTOR: []float32
 type RowsEvent struct {
-	// 0, 1, 2
+	// 0, 1, 2, from the event type. v0 events are written by MySQL 5.1 before GA, v1 ones by
+	// MySQL 5.1 to 5.5 and MariaDB, v2 ones by MySQL 5.6+ with extra data after the flags.
+	// Update events have a second columns bitmap for the after images since v1.
 	Version int
 
 	tableIDSize int
@@ -944,8 +988,16 @@ type RowsEvent struct {
 	timestampStringLocation *time.Location
 	useDecimal              bool
 	ignoreJSONDecodeErr     bool
+	valueTransform          RowValueTransformFunc
 }
 
+// RowValueTransformFunc is called with every non-NULL value decoded in a rows event and
+// returns the value stored in the row instead, or nil to drop it. It is meant for hashing
+// or removing sensitive columns before the event is delivered. column is the index of the
+// column in the table, name is empty unless binlog_row_metadata=FULL.
+// Note that BinlogEvent.RawData still holds the undecoded event.
+type RowValueTransformFunc func(schema, table string, column int, name string, value interface{}) interface{}
+
 // EnumRowImageType is allowed types for every row in mysql binlog.
 // See https://github.com/mysql/mysql-server/blob/1bfe02bdad6604d54913c62614bde57a055c8332/sql/rpl_record.h#L39
 // enum class enum_row_image_type { WRITE_AI, UPDATE_BI, UPDATE_AI, DELETE_BI };
@@ -989,8 +1041,12 @@ func (e *RowsEvent) DecodeHeader(data []byte) (int, error) {
 	e.Flags = binary.LittleEndian.Uint16(data[pos:])
 	pos += 2
 
+	// only v2 has extra data, its length includes the 2 bytes of the length itself
 	if e.Version == 2 {
 		dataLen := binary.LittleEndian.Uint16(data[pos:])
+		if dataLen < 2 {
+			return 0, errors.Errorf("invalid rows event extra data length %d", dataLen)
+		}
 		pos += 2
 		if dataLen > 2 {
 			err := e.decodeExtraData(data[pos:])
@@ -1071,9 +1127,16 @@ func (e *RowsEvent) DecodeData(pos int, data []byte) (err2 error) {
 		}
 	}()
 
+	// UPDATE_ROWS_EVENTv0 has no second bitmap, its after images use the first one
+	hasAfterImage := e.needBitmap2 || e.eventType == UPDATE_ROWS_EVENTv0
+	afterImageBitmap := e.ColumnBitmap2
+	if !e.needBitmap2 {
+		afterImageBitmap = e.ColumnBitmap1
+	}
+
 	// Pre-allocate memory for rows: before image + (optional) after image
 	rowsLen := 1
-	if e.needBitmap2 {
+	if hasAfterImage {
 		rowsLen++
 	}
 	e.SkippedColumns = make([][]int, 0, rowsLen)
@@ -1097,8 +1160,8 @@ func (e *RowsEvent) DecodeData(pos int, data []byte) (err2 error) {
 		pos += n
 
 		// Parse the second image (for UPDATE only)
-		if e.needBitmap2 {
-			if n, err = e.decodeImage(data[pos:], e.ColumnBitmap2, EnumRowImageTypeUpdateAI); err != nil {
+		if hasAfterImage {
+			if n, err = e.decodeImage(data[pos:], afterImageBitmap, EnumRowImageTypeUpdateAI); err != nil {
 				return errors.Trace(err)
 			}
 			pos += n
@@ -1192,6 +1255,14 @@ func (e *RowsEvent) decodeImage(data []byte, bitmap []byte, rowImageType EnumRow
 			return 0, err
 		}
 		pos += n
+
+		if e.valueTransform != nil {
+			var name string
+			if i < len(e.Table.ColumnName) {
+				name = string(e.Table.ColumnName[i])
+			}
+			row[i] = e.valueTransform(string(e.Table.Schema), string(e.Table.Table), i, name, row[i])
+		}
 	}
 
 	e.Rows = append(e.Rows, row)
@@ -1405,6 +1476,12 @@ func (e *RowsEvent) decodeValue(data []byte, tp byte, meta uint16, isPartial boo
 		// I also find some go libs to handle WKB if possible
 		// see https://github.com/twpayne/go-geom or https://github.com/paulmach/go.geo
 		v, n, err = decodeBlob(data, meta)
+	case MYSQL_TYPE_VECTOR:
+		// VECTOR is saved as a blob of packed float32
+		var b []byte
+		if b, n, err = decodeBlob(data, meta); err == nil {
+			v, err = DecodeVectorValue(b)
+		}
 	default:
 		err = fmt.Errorf("unsupport type %d in binlog and don't know how to handle", tp)
 	}
@@ -1803,6 +1880,69 @@ func decodeBlob(data []byte, meta uint16) (v []byte, n int, err error) {
 	return
 }
 
+// ChangedColumns returns the indexes of the columns whose value differs between before and
+// after, the images of a row of the update event e, like Rows[0] and Rows[1].
+// The values are compared by type, decimals by value so 1.0 equals 1.00 and times by instant.
+// With a partial row image, a column missing from the after image is unchanged and a column
+// only in the after image has changed. A partial JSON update always counts as a change.
+func (e *RowsEvent) ChangedColumns(before, after []interface{}) []int {
+	var changed []int
+	for i := 0; i < len(before) && i < len(after); i++ {
+		inBefore := e.ColumnBitmap1 == nil || isBitSet(e.ColumnBitmap1, i)
+		inAfter := e.ColumnBitmap2 == nil || isBitSet(e.ColumnBitmap2, i)
+		if !inAfter {
+			continue
+		}
+		var colType byte
+		if e.Table != nil && i < len(e.Table.ColumnType) {
+			colType = e.Table.ColumnType[i]
+		}
+		if !inBefore || !rowValuesEqual(colType, before[i], after[i]) {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// rowValuesEqual compares two values decoded by decodeValue for a column of type colType.
+func rowValuesEqual(colType byte, a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch av := a.(type) {
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && bytes.Equal(av, bv)
+	case []float32:
+		bv, ok := b.([]float32)
+		return ok && slices.Equal(av, bv)
+	case time.Time:
+		bv, ok := b.(time.Time)
+		return ok && av.Equal(bv)
+	case decimal.Decimal:
+		bv, ok := b.(decimal.Decimal)
+		return ok && av.Equal(bv)
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return false
+		}
+		if colType == MYSQL_TYPE_NEWDECIMAL {
+			ad, err1 := decimal.NewFromString(av)
+			bd, err2 := decimal.NewFromString(bv)
+			if err1 == nil && err2 == nil {
+				return ad.Equal(bd)
+			}
+		}
+		return av == bv
+	case *JsonDiff:
+		return false
+	}
+	// == panics on the values that aren't comparable, like slices
+	return reflect.DeepEqual(a, b)
+}
+
 func (e *RowsEvent) Dump(w io.Writer) {
 	fmt.Fprintf(w, "TableID: %d\n", e.TableID)
 	fmt.Fprintf(w, "Flags: %d\n", e.Flags)
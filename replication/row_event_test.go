@@ -1,8 +1,12 @@
 package replication
 
 import (
+	"encoding/binary"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 
@@ -1176,6 +1180,99 @@ func TestRowsDataExtraData(t *testing.T) {
 	}
 }
 
+func TestRowsEventVersions(t *testing.T) {
+	// post-header lengths of MySQL 5.1 pre-GA v0 events, with 4 bytes table ids, v1 and v2 events
+	lengths := make([]uint8, PARTIAL_UPDATE_ROWS_EVENT)
+	for _, et := range []EventType{WRITE_ROWS_EVENTv0, UPDATE_ROWS_EVENTv0, DELETE_ROWS_EVENTv0} {
+		lengths[et-1] = 6
+	}
+	for _, et := range []EventType{WRITE_ROWS_EVENTv1, UPDATE_ROWS_EVENTv1, DELETE_ROWS_EVENTv1} {
+		lengths[et-1] = 8
+	}
+	for _, et := range []EventType{WRITE_ROWS_EVENTv2, UPDATE_ROWS_EVENTv2, DELETE_ROWS_EVENTv2} {
+		lengths[et-1] = 10
+	}
+
+	// the table of TestRowsDataExtraData, with a nullable INT column
+	tableMapEvent := new(TableMapEvent)
+	tableMapEvent.tableIDSize = 6
+	err := tableMapEvent.Decode([]byte("m\x00\x00\x00\x00\x00\x01\x00\x04test\x00\x04test\x00\x01\x03\x00\x01"))
+	require.NoError(t, err)
+
+	parser := NewBinlogParser()
+	parser.format = &FormatDescriptionEvent{EventTypeHeaderLengths: lengths}
+	parser.tables[tableMapEvent.TableID] = tableMapEvent
+
+	// insert into test values (3); update test set id = 1;
+	inserted := [][]interface{}{{int32(3)}}
+	updated := [][]interface{}{{int32(3)}, {int32(1)}}
+	testcases := []struct {
+		eventType EventType
+		version   int
+		data      string
+		rows      [][]interface{}
+		bitmap2   []byte
+	}{
+		{WRITE_ROWS_EVENTv0, 0, "m\x00\x00\x00\x01\x00\x01\xff\xfe\x03\x00\x00\x00", inserted, nil},
+		// a single bitmap for both images
+		{UPDATE_ROWS_EVENTv0, 0, "m\x00\x00\x00\x01\x00\x01\xff\xfe\x03\x00\x00\x00\xfe\x01\x00\x00\x00", updated, nil},
+		{DELETE_ROWS_EVENTv0, 0, "m\x00\x00\x00\x01\x00\x01\xff\xfe\x01\x00\x00\x00", [][]interface{}{{int32(1)}}, nil},
+		{WRITE_ROWS_EVENTv1, 1, "m\x00\x00\x00\x00\x00\x01\x00\x01\xff\xfe\x03\x00\x00\x00", inserted, nil},
+		{UPDATE_ROWS_EVENTv1, 1, "m\x00\x00\x00\x00\x00\x01\x00\x01\xff\xff\xfe\x03\x00\x00\x00\xfe\x01\x00\x00\x00", updated, []byte{0xff}},
+		{DELETE_ROWS_EVENTv1, 1, "m\x00\x00\x00\x00\x00\x01\x00\x01\xff\xfe\x01\x00\x00\x00", [][]interface{}{{int32(1)}}, nil},
+		{WRITE_ROWS_EVENTv2, 2, "m\x00\x00\x00\x00\x00\x01\x00\x02\x00\x01\xff\xfe\x03\x00\x00\x00", inserted, nil},
+		{UPDATE_ROWS_EVENTv2, 2, "m\x00\x00\x00\x00\x00\x01\x00\x02\x00\x01\xff\xff\xfe\x03\x00\x00\x00\xfe\x01\x00\x00\x00", updated, []byte{0xff}},
+		{DELETE_ROWS_EVENTv2, 2, "m\x00\x00\x00\x00\x00\x01\x00\x02\x00\x01\xff\xfe\x01\x00\x00\x00", [][]interface{}{{int32(1)}}, nil},
+	}
+	for _, tc := range testcases {
+		e := parser.newRowsEvent(&EventHeader{EventType: tc.eventType})
+		require.Equal(t, tc.version, e.Version, tc.eventType)
+		require.NoError(t, e.Decode([]byte(tc.data)), tc.eventType)
+		require.Equal(t, uint64(0x6d), e.TableID, tc.eventType)
+		require.Equal(t, tc.rows, e.Rows, tc.eventType)
+		require.Equal(t, tc.bitmap2, e.ColumnBitmap2, tc.eventType)
+	}
+
+	// the v2 extra data length counts its own 2 bytes
+	e := parser.newRowsEvent(&EventHeader{EventType: WRITE_ROWS_EVENTv2})
+	err = e.Decode([]byte("m\x00\x00\x00\x00\x00\x01\x00\x01\x00\x01\xff\xfe\x03\x00\x00\x00"))
+	require.ErrorContains(t, err, "invalid rows event extra data length 1")
+}
+
+func TestRowsEventChangedColumns(t *testing.T) {
+	e := &RowsEvent{
+		Table: &TableMapEvent{ColumnType: []byte{
+			mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_DATETIME2,
+			mysql.MYSQL_TYPE_BLOB, mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_LONG,
+		}},
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := []interface{}{int32(1), "1.0", "1.0", ts, []byte("a"), decimal.RequireFromString("2.5"), nil}
+	after := []interface{}{int32(1), "1.00", "1.00", ts.In(time.FixedZone("", 3600)), []byte("a"), decimal.RequireFromString("2.50"), nil}
+	// the varchar changed, not the decimals nor the time at another location
+	require.Equal(t, []int{2}, e.ChangedColumns(before, after))
+
+	after = []interface{}{int32(2), "1.0", "1.0", ts.Add(time.Second), []byte("b"), decimal.RequireFromString("2.5"), int32(0)}
+	require.Equal(t, []int{0, 3, 4, 6}, e.ChangedColumns(before, after))
+
+	// partial row image: the before image has the first column only, the after image the
+	// first and the last ones
+	e.ColumnBitmap1 = []byte{0x01}
+	e.ColumnBitmap2 = []byte{0x41}
+	before = []interface{}{int32(1), nil, nil, nil, nil, nil, nil}
+	after = []interface{}{int32(1), nil, nil, nil, nil, nil, nil}
+	require.Equal(t, []int{6}, e.ChangedColumns(before, after))
+
+	// the values that aren't comparable with == don't panic
+	e.ColumnBitmap1, e.ColumnBitmap2 = nil, nil
+	before = []interface{}{[]int64{1}, nil, nil, nil, nil, nil, map[string]interface{}{"a": 1}}
+	after = []interface{}{[]int64{1}, nil, nil, nil, nil, nil, map[string]interface{}{"a": 2}}
+	require.Equal(t, []int{6}, e.ChangedColumns(before, after))
+}
+
+// a table map event of MariaDB 10.5 with the full row metadata
+var mariadb105TableMapEvent = []byte("\x1e\x00\x00\x00\x00\x00\x01\x00\x04test\x00\x06_types\x003\x10\x01\x01\x02\t\x03\b\xf6\x04\x05\x01\x02\t\x03\b\xf6\x04\x05\r\n\x13\x13\x12\x12\x11\x11\xfe\x0f\xfe\x0f\xfc\xfc\xfc\xfc\xfc\xfc\xfc\xfc\xfe\xfe\xff\xfc\xfe\xfe\xff\xff\xff\xff\xff\xff\xff1\x00\bA\x1e\x04\bA\x1e\x04\b\x00\x06\x00\x06\x00\x06\xee\xfe\xfc\x03\xfe@@\x00\x01\x02\x03\x04\x01\x02\x03\x04\xf7\x01\xf8\x01\x04\x04\xf8\x01\xf7\x01\x04\x04\x04\x04\x04\x04\x04\x00\x00\xfc\xc0\xff\xff\a\x01\x03\x00\u007f\xc0\x02\x0f?\x00\x1c\x01\xe0\b\xe0\t\xe0\n\xe0\v\xe0\r.\a\b\x00\a\x06\x05\x04\x03\x02\x01\x04\xfc\x05\x02\x05b_bit\tn_boolean\tn_tinyint\nn_smallint\vn_mediumint\x05n_int\bn_bigint\tn_decimal\an_float\bn_double\nnu_tinyint\vnu_smallint\fnu_mediumint\x06nu_int\tnu_bigint\nnu_decimal\bnu_float\tnu_double\x06t_year\x06t_date\x06t_time\at_ftime\nt_datetime\vt_fdatetime\vt_timestamp\ft_ftimestamp\x06c_char\tc_varchar\bc_binary\vc_varbinary\nc_tinyblob\x06c_blob\fc_mediumblob\nc_longblob\nc_tinytext\x06c_text\fc_mediumtext\nc_longtext\x06e_enum\x05s_set\ng_geometry\x06j_json\x06s_set2\ae_enum2\x14g_geometrycollection\x0eg_multipolygon\x11g_multilinestring\fg_multipoint\tg_polygon\fg_linestring\ag_point\v\x04\xe0\xe0\x1c\x1c\x05\n\x02\x011\x012\x02\x013\x014\x06\n\x02\x01a\x01b\x02\x01c\x01d")
+
 func TestTableMapHelperMaps(t *testing.T) {
 	/*
 		CREATE TABLE `_types` (
@@ -1308,7 +1405,7 @@ func TestTableMapHelperMaps(t *testing.T) {
 		},
 		{
 			flavor:              "mariadb", // mariadb 10.5
-			data:                []byte("\x1e\x00\x00\x00\x00\x00\x01\x00\x04test\x00\x06_types\x003\x10\x01\x01\x02\t\x03\b\xf6\x04\x05\x01\x02\t\x03\b\xf6\x04\x05\r\n\x13\x13\x12\x12\x11\x11\xfe\x0f\xfe\x0f\xfc\xfc\xfc\xfc\xfc\xfc\xfc\xfc\xfe\xfe\xff\xfc\xfe\xfe\xff\xff\xff\xff\xff\xff\xff1\x00\bA\x1e\x04\bA\x1e\x04\b\x00\x06\x00\x06\x00\x06\xee\xfe\xfc\x03\xfe@@\x00\x01\x02\x03\x04\x01\x02\x03\x04\xf7\x01\xf8\x01\x04\x04\xf8\x01\xf7\x01\x04\x04\x04\x04\x04\x04\x04\x00\x00\xfc\xc0\xff\xff\a\x01\x03\x00\u007f\xc0\x02\x0f?\x00\x1c\x01\xe0\b\xe0\t\xe0\n\xe0\v\xe0\r.\a\b\x00\a\x06\x05\x04\x03\x02\x01\x04\xfc\x05\x02\x05b_bit\tn_boolean\tn_tinyint\nn_smallint\vn_mediumint\x05n_int\bn_bigint\tn_decimal\an_float\bn_double\nnu_tinyint\vnu_smallint\fnu_mediumint\x06nu_int\tnu_bigint\nnu_decimal\bnu_float\tnu_double\x06t_year\x06t_date\x06t_time\at_ftime\nt_datetime\vt_fdatetime\vt_timestamp\ft_ftimestamp\x06c_char\tc_varchar\bc_binary\vc_varbinary\nc_tinyblob\x06c_blob\fc_mediumblob\nc_longblob\nc_tinytext\x06c_text\fc_mediumtext\nc_longtext\x06e_enum\x05s_set\ng_geometry\x06j_json\x06s_set2\ae_enum2\x14g_geometrycollection\x0eg_multipolygon\x11g_multilinestring\fg_multipoint\tg_polygon\fg_linestring\ag_point\v\x04\xe0\xe0\x1c\x1c\x05\n\x02\x011\x012\x02\x013\x014\x06\n\x02\x01a\x01b\x02\x01c\x01d"),
+			data:                mariadb105TableMapEvent,
 			unsignedMap:         unsignedMap,
 			collationMap:        mariadbCollationMap,
 			enumSetCollationMap: enumSetCollationMap,
@@ -1539,3 +1636,134 @@ func BenchmarkInt(b *testing.B) {
 		}
 	}
 }
+
+func TestTableMapSetAndEnumValue(t *testing.T) {
+	tableMapEvent := &TableMapEvent{
+		ColumnCount: 3,
+		ColumnType:  []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_STRING, mysql.MYSQL_TYPE_STRING},
+		ColumnMeta:  []uint16{0, uint16(mysql.MYSQL_TYPE_ENUM) << 8, uint16(mysql.MYSQL_TYPE_SET) << 8},
+		EnumStrValue: [][][]byte{
+			{[]byte("x"), []byte("y")},
+		},
+		SetStrValue: [][][]byte{
+			{[]byte("a"), []byte("b"), []byte("c")},
+		},
+	}
+
+	e, err := tableMapEvent.EnumValue(1, int64(2))
+	require.NoError(t, err)
+	require.Equal(t, "y", e)
+	e, err = tableMapEvent.EnumValue(1, int64(0))
+	require.NoError(t, err)
+	require.Equal(t, "", e)
+
+	s, err := tableMapEvent.SetValue(2, int64(6))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, s)
+	s, err = tableMapEvent.SetValue(2, int64(0))
+	require.NoError(t, err)
+	require.Equal(t, []string{}, s)
+	s, err = tableMapEvent.SetValue(2, nil)
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	_, err = tableMapEvent.SetValue(0, int64(1))
+	require.Error(t, err)
+}
+
+func TestDecodeVector(t *testing.T) {
+	vec := mysql.AppendVectorValue(nil, []float32{1.5, -2, 0})
+	data := append([]byte{byte(len(vec)), 0, 0, 0}, vec...)
+
+	e := &RowsEvent{}
+	v, n, err := e.decodeValue(data, mysql.MYSQL_TYPE_VECTOR, 4, false)
+	require.NoError(t, err)
+	require.Equal(t, len(data), n)
+	require.Equal(t, []float32{1.5, -2, 0}, v)
+
+	_, _, err = e.decodeValue([]byte{3, 0, 0, 0, 1, 2, 3}, mysql.MYSQL_TYPE_VECTOR, 4, false)
+	require.Error(t, err)
+}
+
+func TestDecodeMediumInt(t *testing.T) {
+	e := &RowsEvent{}
+	for _, tt := range []struct {
+		data  []byte
+		value int32
+	}{
+		{[]byte{0x00, 0x00, 0x80}, -8388608},
+		{[]byte{0xff, 0xff, 0xff}, -1},
+		{[]byte{0xff, 0xff, 0x7f}, 8388607},
+		{[]byte{0x00, 0x00, 0x00}, 0},
+	} {
+		v, n, err := e.decodeValue(tt.data, mysql.MYSQL_TYPE_INT24, 0, false)
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Equal(t, tt.value, v)
+	}
+}
+
+func TestRowValueTransform(t *testing.T) {
+	// the funnytable of TestLastNull
+	tableMapEvent := new(TableMapEvent)
+	tableMapEvent.tableIDSize = 6
+	err := tableMapEvent.Decode([]byte("\xd3\x01\x00\x00\x00\x00\x01\x00\x04test\x00\nfunnytable\x00\x01\x01\x00\x01"))
+	require.NoError(t, err)
+
+	var columns []string
+	rows := &RowsEvent{
+		tableIDSize: 6,
+		tables:      map[uint64]*TableMapEvent{tableMapEvent.TableID: tableMapEvent},
+		Version:     2,
+		valueTransform: func(schema, table string, column int, name string, value interface{}) interface{} {
+			columns = append(columns, fmt.Sprintf("%s.%s.%d%s", schema, table, column, name))
+			if value == int8(2) {
+				return nil
+			}
+			return fmt.Sprintf("hashed %v", value)
+		},
+	}
+
+	// insert into funnytable values (1), (2), (null);
+	err = rows.Decode([]byte("\xd3\x01\x00\x00\x00\x00\x01\x00\x02\x00\x01\xff\xfe\x01\xfe\x02\xff"))
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{"hashed 1"}, {nil}, {nil}}, rows.Rows)
+	// not called for NULL
+	require.Equal(t, []string{"test.funnytable.0", "test.funnytable.0"}, columns)
+}
+
+func TestRowValueTransformCompressed(t *testing.T) {
+	b := &EventBuilder{ServerID: 1}
+	p := NewBinlogParser()
+	p.SetRowValueTransformFunc(func(schema, table string, column int, name string, value interface{}) interface{} {
+		return fmt.Sprintf("hashed %v", value)
+	})
+	_, err := p.Parse(b.FormatDescription("8.0.36").RawData)
+	require.NoError(t, err)
+
+	// the funnytable of TestRowValueTransform, in a compressed transaction
+	var events []byte
+	events = append(events, b.Build(TABLE_MAP_EVENT, []byte("\xd3\x01\x00\x00\x00\x00\x01\x00\x04test\x00\nfunnytable\x00\x01\x01\x00\x01")).RawData...)
+	events = append(events, b.Build(WRITE_ROWS_EVENTv2, []byte("\xd3\x01\x00\x00\x00\x00\x01\x00\x02\x00\x01\xff\xfe\x01\xfe\x02\xff")).RawData...)
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	compressed := encoder.EncodeAll(events, nil)
+	require.NoError(t, encoder.Close())
+
+	field := func(fieldType byte, value uint64) []byte {
+		return binary.LittleEndian.AppendUint64([]byte{fieldType, 8}, value)
+	}
+	var body []byte
+	body = append(body, field(OTW_PAYLOAD_COMPRESSION_TYPE_FIELD, ZSTD)...)
+	body = append(body, field(OTW_PAYLOAD_UNCOMPRESSED_SIZE_FIELD, uint64(len(events)))...)
+	body = append(body, field(OTW_PAYLOAD_SIZE_FIELD, uint64(len(compressed)))...)
+	body = append(body, OTW_PAYLOAD_HEADER_END_MARK)
+	body = append(body, compressed...)
+
+	e, err := p.Parse(b.Build(TRANSACTION_PAYLOAD_EVENT, body).RawData)
+	require.NoError(t, err)
+	payload := e.Event.(*TransactionPayloadEvent)
+	require.Len(t, payload.Events, 2)
+	rows := payload.Events[1].Event.(*RowsEvent)
+	require.Equal(t, [][]interface{}{{"hashed 1"}, {"hashed 2"}, {nil}}, rows.Rows)
+}
@@ -0,0 +1,169 @@
+package replication
+
+import (
+	"context"
+	"strings"
+)
+
+// Transaction is a batch of events read by a TransactionStreamer.
+type Transaction struct {
+	// GTID is the GTID_EVENT or MARIADB_GTID_EVENT starting the transaction, nil if GTIDs
+	// are disabled or for an event outside of a transaction.
+	GTID *BinlogEvent
+
+	// Events are the events of the transaction, from the GTID event to the XID event or the
+	// COMMIT query, or the single event outside of a transaction, like a ROTATE_EVENT.
+	Events []*BinlogEvent
+
+	// Partial is true if the transaction was split in batches, for all the batches but the
+	// last one.
+	Partial bool
+}
+
+// RowsEvents returns the rows events of the transaction.
+func (t *Transaction) RowsEvents() []*RowsEvent {
+	var rows []*RowsEvent
+	for _, e := range t.Events {
+		if r, ok := e.Event.(*RowsEvent); ok {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// TransactionStreamer groups the events of a BinlogStreamer by transaction, so they can be
+// applied atomically. The events must be parsed, the boundaries of transactions aren't
+// detected in raw mode.
+type TransactionStreamer struct {
+	s         *BinlogStreamer
+	maxEvents int
+
+	cur      *Transaction
+	boundary txnBoundary
+}
+
+// NewTransactionStreamer returns a TransactionStreamer reading the events of s. If maxEvents
+// is greater than 0, the transactions with more events are returned in partial batches of
+// maxEvents events to bound the memory used.
+func NewTransactionStreamer(s *BinlogStreamer, maxEvents int) *TransactionStreamer {
+	return &TransactionStreamer{s: s, maxEvents: maxEvents}
+}
+
+// GetTransaction returns the next transaction, or batch of a transaction. It blocks like
+// BinlogStreamer.GetEvent until the transaction ends, the events read before an error are
+// kept for the next call.
+func (t *TransactionStreamer) GetTransaction(ctx context.Context) (*Transaction, error) {
+	for {
+		e, err := t.s.GetEvent(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		end := t.boundary.next(e)
+		if t.cur == nil {
+			if !isTransactionStart(e) {
+				return &Transaction{Events: []*BinlogEvent{e}}, nil
+			}
+			t.cur = &Transaction{}
+		}
+
+		switch e.Event.(type) {
+		case *GTIDEvent, *MariadbGTIDEvent:
+			t.cur.GTID = e
+		}
+		t.cur.Events = append(t.cur.Events, e)
+
+		if end {
+			txn := t.cur
+			t.cur = nil
+			return txn, nil
+		}
+
+		if t.maxEvents > 0 && len(t.cur.Events) >= t.maxEvents {
+			txn := t.cur
+			txn.Partial = true
+			t.cur = &Transaction{GTID: txn.GTID}
+			return txn, nil
+		}
+	}
+}
+
+// isTransactionStart returns true if the event starts a transaction.
+func isTransactionStart(e *BinlogEvent) bool {
+	switch event := e.Event.(type) {
+	case *GTIDEvent, *MariadbGTIDEvent:
+		return true
+	case *QueryEvent:
+		return string(event.Query) == "BEGIN"
+	}
+	return false
+}
+
+// isTransactionBegin returns true if the event begins a transaction ended by a COMMIT, a
+// ROLLBACK or an XID event rather than by its first statement: a BEGIN or XA START query, or
+// the GTID event of MariaDB, which replaces the BEGIN, of a transaction that isn't standalone.
+func isTransactionBegin(e *BinlogEvent) bool {
+	switch event := e.Event.(type) {
+	case *MariadbGTIDEvent:
+		return !event.IsStandalone()
+	case *QueryEvent:
+		q := strings.TrimSpace(string(event.Query))
+		return strings.EqualFold(q, "BEGIN") || hasPrefixFold(q, "XA START") || hasPrefixFold(q, "XA BEGIN")
+	}
+	return false
+}
+
+// isTransactionEnd returns true if the event ends the transaction it is part of, begun tells
+// whether the transaction had an event for which isTransactionBegin is true. The statements
+// of a transaction, like the DML of statement-based logging or a SAVEPOINT, don't end it.
+func isTransactionEnd(e *BinlogEvent, begun bool) bool {
+	if e.Header != nil && e.Header.EventType == XA_PREPARE_LOG_EVENT {
+		return true
+	}
+
+	switch event := e.Event.(type) {
+	case *XIDEvent, *TransactionPayloadEvent:
+		return true
+	case *QueryEvent:
+		q := strings.TrimSpace(string(event.Query))
+		switch {
+		case strings.EqualFold(q, "COMMIT"), strings.EqualFold(q, "ROLLBACK"),
+			hasPrefixFold(q, "XA COMMIT"), hasPrefixFold(q, "XA ROLLBACK"):
+			return true
+		case isTransactionBegin(e):
+			return false
+		}
+		// DDL is a transaction on its own, right after its GTID event
+		return !begun
+	}
+	return false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// txnBoundary tracks whether the transaction being read had a BEGIN, to tell its end.
+type txnBoundary struct {
+	begun bool
+}
+
+// next returns true if e ends the transaction being read.
+func (t *txnBoundary) next(e *BinlogEvent) bool {
+	end := isTransactionEnd(e, t.begun)
+	switch {
+	case end:
+		t.begun = false
+	case isTransactionBegin(e):
+		t.begun = true
+	case isTransactionStart(e):
+		// the GTID event of MySQL, a BEGIN may follow
+		t.begun = false
+	}
+	return end
+}
+
+// reset forgets the transaction being read, when syncing restarts.
+func (t *txnBoundary) reset() {
+	t.begun = false
+}
@@ -34,6 +34,9 @@ type TransactionPayloadEvent struct {
 	CompressionType  uint64
 	Payload          []byte
 	Events           []*BinlogEvent
+
+	// the parser of the event, whose settings the events of the payload are parsed with
+	parser *BinlogParser
 }
 
 func (e *TransactionPayloadEvent) compressionType() string {
@@ -119,8 +122,7 @@ func (e *TransactionPayloadEvent) decodePayload() error {
 	// to work on them. We can't use e.parser directly as we need to disable checksums
 	// but we still need the initialization from the FormatDescriptionEvent. We can't
 	// modify e.parser as it is used elsewhere.
-	parser := NewBinlogParser()
-	parser.format = &FormatDescriptionEvent{
+	format := &FormatDescriptionEvent{
 		Version:                e.format.Version,
 		ServerVersion:          e.format.ServerVersion,
 		CreateTimestamp:        e.format.CreateTimestamp,
@@ -128,6 +130,11 @@ func (e *TransactionPayloadEvent) decodePayload() error {
 		EventTypeHeaderLengths: e.format.EventTypeHeaderLengths,
 		ChecksumAlgorithm:      BINLOG_CHECKSUM_ALG_OFF,
 	}
+	outer := e.parser
+	if outer == nil {
+		outer = NewBinlogParser()
+	}
+	parser := outer.newPayloadParser(format)
 
 	offset := uint32(0)
 	for {
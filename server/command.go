@@ -2,6 +2,8 @@ package server
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
 
@@ -11,7 +13,9 @@ import (
 	"github.com/go-mysql-org/go-mysql/utils"
 )
 
-// Handler is what a server needs to implement the client-server protocol
+// Handler is what a server needs to implement the client-server protocol.
+// Errors returned as a *MyError, possibly wrapped, are sent to the client with their
+// code and SQLSTATE, like NewDefaultError(ER_DUP_ENTRY, ...); other errors are sent as ER_UNKNOWN_ERROR.
 type Handler interface {
 	//handle COM_INIT_DB command, you can check whether the dbName is valid, or other.
 	UseDB(dbName string) error
@@ -42,6 +46,19 @@ type ReplicationHandler interface {
 	HandleBinlogDumpGTID(gtidSet *MysqlGTIDSet) (*replication.BinlogStreamer, error)
 }
 
+// ConnectionIDHandler is for handlers that want to know the id of the connection they serve,
+// e.g. to match the id in a KILL statement. SetConnectionID is called once the handshake succeeded.
+type ConnectionIDHandler interface {
+	SetConnectionID(id uint32)
+}
+
+// SetOptionHandler is for handlers that want to know when the client turns multi statements
+// on or off with COM_SET_OPTION, which the server applies for every handler. HandleSetOption
+// is called before the option is applied, returning an error rejects it.
+type SetOptionHandler interface {
+	HandleSetOption(multiStatements bool) error
+}
+
 // HandleCommand is handling commands received by the server
 // https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_command_phase.html
 func (c *Conn) HandleCommand() error {
@@ -49,15 +66,19 @@ func (c *Conn) HandleCommand() error {
 		return fmt.Errorf("connection closed")
 	}
 
-	data, err := c.ReadPacket()
-	if err != nil {
+	data, err := c.readCommand()
+	var v interface{}
+	if err == errPacketTooLarge {
+		// the whole packet was read, the connection can go on
+		v = NewDefaultError(ER_NET_PACKET_TOO_LARGE)
+	} else if err != nil {
 		c.Close()
 		c.Conn = nil
 		return err
+	} else {
+		v = c.dispatch(data)
 	}
 
-	v := c.dispatch(data)
-
 	err = c.WriteValue(v)
 
 	if c.Conn != nil {
@@ -71,6 +92,43 @@ func (c *Conn) HandleCommand() error {
 	return err
 }
 
+var errPacketTooLarge = errors.New("packet too large")
+
+// readCommand reads a command packet, a packet larger than the max packet size of the server
+// is discarded and errPacketTooLarge returned.
+func (c *Conn) readCommand() ([]byte, error) {
+	if c.serverConf == nil || c.serverConf.maxPacketSize <= 0 {
+		return c.ReadPacket()
+	}
+
+	b := &limitedBuffer{max: c.serverConf.maxPacketSize}
+	if err := c.ReadPacketTo(b); err != nil {
+		return nil, err
+	}
+	if b.overflow {
+		return nil, errPacketTooLarge
+	}
+	return b.Bytes(), nil
+}
+
+// limitedBuffer keeps up to max bytes, once more are written it drops them all.
+type limitedBuffer struct {
+	bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if !b.overflow && b.Len()+len(p) > b.max {
+		b.overflow = true
+		b.Reset()
+	}
+	if b.overflow {
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
 func (c *Conn) dispatch(data []byte) interface{} {
 	cmd := data[0]
 	data = data[1:]
@@ -81,9 +139,29 @@ func (c *Conn) dispatch(data []byte) interface{} {
 		c.Conn = nil
 		return noResponse{}
 	case COM_QUERY:
-		if r, err := c.h.HandleQuery(utils.ByteSliceToString(data)); err != nil {
+		query := utils.ByteSliceToString(data)
+		cs, collation, setNames := parseSetNames(query)
+		if h, ok := c.h.(SetNamesHandler); ok && setNames {
+			return c.handleSetNames(h, cs, collation)
+		}
+		if h, ok := c.h.(LocalInFileHandler); ok {
+			if filename, ok := h.LocalInFileName(query); ok {
+				if r, err := c.handleLocalInFile(h, query, filename); err != nil {
+					return err
+				} else {
+					return r
+				}
+			}
+		}
+		if r, err := c.h.HandleQuery(query); err != nil {
 			return err
 		} else {
+			if setNames {
+				// the handler ran it, the character set of the session follows if it is known
+				if co, err := c.setNamesCollation(cs, collation); err == nil {
+					c.setCollation(co)
+				}
+			}
 			return r
 		}
 	case COM_PING:
@@ -140,7 +218,7 @@ func (c *Conn) dispatch(data []byte) interface{} {
 			return r
 		}
 	case COM_SET_OPTION:
-		if err := c.h.HandleOtherCommand(cmd, data); err != nil {
+		if err := c.handleSetOption(data); err != nil {
 			return err
 		}
 
@@ -284,3 +362,34 @@ func (h EmptyHandler) HandleOtherCommand(cmd byte, data []byte) error {
 		fmt.Sprintf("command %d is not supported now", cmd),
 	)
 }
+
+// handleSetOption toggles CLIENT_MULTI_STATEMENTS, so Conn.HasCapability tells the handler
+// whether the following queries may hold multiple statements.
+func (c *Conn) handleSetOption(data []byte) error {
+	if len(data) < 2 {
+		return ErrMalformPacket
+	}
+
+	var multiStatements bool
+	switch binary.LittleEndian.Uint16(data) {
+	case MYSQL_OPTION_MULTI_STATEMENTS_ON:
+		multiStatements = true
+	case MYSQL_OPTION_MULTI_STATEMENTS_OFF:
+		multiStatements = false
+	default:
+		return NewDefaultError(ER_UNKNOWN_COM_ERROR)
+	}
+
+	if h, ok := c.h.(SetOptionHandler); ok {
+		if err := h.HandleSetOption(multiStatements); err != nil {
+			return err
+		}
+	}
+
+	if multiStatements {
+		c.SetCapability(CLIENT_MULTI_STATEMENTS)
+	} else {
+		c.UnsetCapability(CLIENT_MULTI_STATEMENTS)
+	}
+	return nil
+}
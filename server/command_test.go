@@ -1,5 +1,195 @@
 package server
 
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/packet"
+)
+
 // Ensure EmptyHandler implements Handler interface or cause compile time error
 var _ Handler = EmptyHandler{}
 var _ ReplicationHandler = EmptyReplicationHandler{}
+
+type setOptionHandler struct {
+	EmptyHandler
+	multiStatements []bool
+	err             error
+}
+
+func (h *setOptionHandler) HandleSetOption(multiStatements bool) error {
+	if h.err != nil {
+		return h.err
+	}
+	h.multiStatements = append(h.multiStatements, multiStatements)
+	return nil
+}
+
+func TestDispatchSetOption(t *testing.T) {
+	h := &setOptionHandler{}
+	c := &Conn{h: h}
+
+	v := c.dispatch([]byte{mysql.COM_SET_OPTION, mysql.MYSQL_OPTION_MULTI_STATEMENTS_ON, 0})
+	require.Equal(t, eofResponse{}, v)
+	require.True(t, c.HasCapability(mysql.CLIENT_MULTI_STATEMENTS))
+
+	v = c.dispatch([]byte{mysql.COM_SET_OPTION, mysql.MYSQL_OPTION_MULTI_STATEMENTS_OFF, 0})
+	require.Equal(t, eofResponse{}, v)
+	require.False(t, c.HasCapability(mysql.CLIENT_MULTI_STATEMENTS))
+	require.Equal(t, []bool{true, false}, h.multiStatements)
+
+	v = c.dispatch([]byte{mysql.COM_SET_OPTION, 5, 0})
+	require.IsType(t, &mysql.MyError{}, v)
+
+	// the handler can reject the change
+	h.err = mysql.NewDefaultError(mysql.ER_UNKNOWN_COM_ERROR)
+	v = c.dispatch([]byte{mysql.COM_SET_OPTION, mysql.MYSQL_OPTION_MULTI_STATEMENTS_ON, 0})
+	require.Equal(t, h.err, v)
+	require.False(t, c.HasCapability(mysql.CLIENT_MULTI_STATEMENTS))
+
+	// the other handlers don't need to handle it
+	c = &Conn{h: EmptyHandler{}}
+	v = c.dispatch([]byte{mysql.COM_SET_OPTION, mysql.MYSQL_OPTION_MULTI_STATEMENTS_ON, 0})
+	require.Equal(t, eofResponse{}, v)
+	require.True(t, c.HasCapability(mysql.CLIENT_MULTI_STATEMENTS))
+}
+
+type setNamesHandler struct {
+	EmptyHandler
+	names []string
+}
+
+func (h *setNamesHandler) HandleSetNames(charset string, collation string) error {
+	h.names = append(h.names, charset+" "+collation)
+	return nil
+}
+
+type queryHandler struct {
+	EmptyHandler
+	queries []string
+}
+
+func (h *queryHandler) HandleQuery(query string) (*mysql.Result, error) {
+	h.queries = append(h.queries, query)
+	return nil, nil
+}
+
+func TestDispatchSetNames(t *testing.T) {
+	query := func(c *Conn, q string) interface{} {
+		return c.dispatch(append([]byte{mysql.COM_QUERY}, q...))
+	}
+
+	// utf8mb4_general_ci in the handshake
+	h := &setNamesHandler{}
+	c := &Conn{h: h, charset: 45}
+	require.Equal(t, "utf8mb4", c.CharsetName())
+	require.Equal(t, "utf8mb4_general_ci", c.CollationName())
+
+	require.Nil(t, query(c, "SET NAMES latin1"))
+	require.Equal(t, "latin1", c.CharsetName())
+	// the default collation of the character set
+	require.Equal(t, "latin1_swedish_ci", c.CollationName())
+	require.Equal(t, uint8(8), c.Charset())
+
+	require.Nil(t, query(c, "set names 'utf8mb4' collate 'utf8mb4_bin';"))
+	require.Equal(t, "utf8mb4_bin", c.CollationName())
+
+	v := query(c, "SET NAMES nope")
+	require.IsType(t, &mysql.MyError{}, v)
+	require.Equal(t, uint16(mysql.ER_UNKNOWN_CHARACTER_SET), v.(*mysql.MyError).Code)
+
+	v = query(c, "SET NAMES utf8mb4 COLLATE latin1_bin")
+	require.Equal(t, uint16(mysql.ER_COLLATION_CHARSET_MISMATCH), v.(*mysql.MyError).Code)
+	require.Equal(t, "utf8mb4_bin", c.CollationName())
+
+	require.Nil(t, query(c, "SET NAMES cp1251"))
+	require.Nil(t, query(c, "SET NAMES utf8mb4"))
+	require.Nil(t, query(c, "SET NAMES DEFAULT"))
+	require.Equal(t, []string{
+		"latin1 latin1_swedish_ci",
+		"utf8mb4 utf8mb4_bin",
+		"cp1251 cp1251_general_ci",
+		"utf8mb4 utf8mb4_0900_ai_ci",
+		"utf8 utf8_general_ci",
+	}, h.names)
+
+	// the other handlers run SET NAMES as a query
+	qh := &queryHandler{}
+	c = &Conn{h: qh, charset: 45}
+	require.Nil(t, query(c, "SET NAMES latin1"))
+	require.Nil(t, query(c, "SET NAMES nope"))
+	require.Equal(t, []string{"SET NAMES latin1", "SET NAMES nope"}, qh.queries)
+	require.Equal(t, "latin1_swedish_ci", c.CollationName())
+}
+
+type stmtExecuteHandler struct {
+	EmptyHandler
+	args [][]interface{}
+}
+
+func (h *stmtExecuteHandler) HandleStmtExecute(context interface{}, query string, args []interface{}) (*mysql.Result, error) {
+	h.args = append(h.args, append([]interface{}(nil), args...))
+	return &mysql.Result{}, nil
+}
+
+func TestHandleStmtExecuteBoundTypes(t *testing.T) {
+	h := &stmtExecuteHandler{}
+	c := &Conn{h: h, stmts: map[uint32]*Stmt{1: {ID: 1, Params: 1, Args: make([]interface{}, 1)}}}
+
+	// id, flags, iteration count, NULL bitmap
+	header := []byte{1, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+	// no types were bound yet
+	_, err := c.handleStmtExecute(append(header, 0, 7, 0, 0, 0, 0, 0, 0, 0))
+	require.ErrorIs(t, err, mysql.ErrMalformPacket)
+
+	_, err = c.handleStmtExecute(append(header, 1, mysql.MYSQL_TYPE_LONGLONG, 0, 1, 0, 0, 0, 0, 0, 0, 0))
+	require.NoError(t, err)
+	// the types of the previous execution
+	_, err = c.handleStmtExecute(append(header, 0, 2, 0, 0, 0, 0, 0, 0, 0))
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{int64(1)}, {int64(2)}}, h.args)
+}
+
+func TestHandleCommandPacketTooLarge(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	c := &Conn{
+		Conn:       packet.NewConn(serverConn),
+		serverConf: &Server{maxPacketSize: 16},
+		h:          EmptyHandler{},
+		capability: mysql.CLIENT_PROTOCOL_41,
+	}
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 2; i++ {
+			if err := c.HandleCommand(); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	cc := packet.NewConn(clientConn)
+	command := func(data []byte) []byte {
+		cc.ResetSequence()
+		require.NoError(t, cc.WritePacket(append(make([]byte, 4), data...)))
+		resp, err := cc.ReadPacket()
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := command(append([]byte{mysql.COM_QUERY}, "select 'a long query'"...))
+	require.Equal(t, byte(mysql.ERR_HEADER), resp[0])
+	require.Equal(t, uint16(mysql.ER_NET_PACKET_TOO_LARGE), binary.LittleEndian.Uint16(resp[1:]))
+
+	// the connection is still usable
+	resp = command([]byte{mysql.COM_PING})
+	require.Equal(t, byte(mysql.OK_HEADER), resp[0])
+	require.NoError(t, <-done)
+}
@@ -5,6 +5,8 @@ import (
 	"net"
 	"sync/atomic"
 
+	"github.com/pingcap/tidb/pkg/parser/charset"
+
 	. "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/packet"
 )
@@ -13,9 +15,11 @@ import (
 type Conn struct {
 	*packet.Conn
 
-	serverConf     *Server
-	capability     uint32
-	charset        uint8
+	serverConf *Server
+	capability uint32
+	charset    uint8
+	// set by SET NAMES, charset is the id of the collation if it fits
+	collation      *charset.Collation
 	authPluginName string
 	attributes     map[string]string
 	connectionID   uint32
@@ -55,7 +59,7 @@ func NewConn(conn net.Conn, user string, password string, h Handler) (*Conn, err
 		serverConf:         defaultServer,
 		credentialProvider: p,
 		h:                  h,
-		connectionID:       atomic.AddUint32(&baseConnID, 1),
+		connectionID:       defaultServer.nextConnectionID(),
 		stmts:              make(map[uint32]*Stmt),
 		salt:               RandomBuf(20),
 	}
@@ -83,7 +87,7 @@ func NewCustomizedConn(conn net.Conn, serverConf *Server, p CredentialProvider,
 		serverConf:         serverConf,
 		credentialProvider: p,
 		h:                  h,
-		connectionID:       atomic.AddUint32(&baseConnID, 1),
+		connectionID:       serverConf.nextConnectionID(),
 		stmts:              make(map[uint32]*Stmt),
 		salt:               RandomBuf(20),
 	}
@@ -120,6 +124,10 @@ func (c *Conn) handshake() error {
 
 	c.ResetSequence()
 
+	if h, ok := c.h.(ConnectionIDHandler); ok {
+		h.SetConnectionID(c.connectionID)
+	}
+
 	return nil
 }
 
@@ -91,3 +91,19 @@ func TestCapability(t *testing.T) {
 		require.False(t, conn.HasCapability(capI))
 	}
 }
+
+func TestConnectionIDAllocator(t *testing.T) {
+	s := NewServer("8.0.12", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_NATIVE_PASSWORD, nil, nil)
+
+	// the default allocator hands out increasing ids
+	id := s.nextConnectionID()
+	require.Greater(t, s.nextConnectionID(), id)
+
+	next := uint32(0)
+	s.SetConnectionIDAllocator(func() uint32 {
+		next++
+		return next
+	})
+	require.Equal(t, uint32(1), s.nextConnectionID())
+	require.Equal(t, uint32(2), s.nextConnectionID())
+}
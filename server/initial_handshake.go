@@ -1,5 +1,9 @@
 package server
 
+import (
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
 // see: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_handshake_v10.html
 func (c *Conn) writeInitialHandshake() error {
 	data := make([]byte, 4)
@@ -21,6 +25,10 @@ func (c *Conn) writeInitialHandshake() error {
 	data = append(data, 0x00)
 
 	defaultFlag := c.serverConf.capability
+	// LOAD DATA LOCAL INFILE is only served by a LocalInFileHandler
+	if _, ok := c.h.(LocalInFileHandler); ok {
+		defaultFlag |= CLIENT_LOCAL_FILES
+	}
 	//capability flag lower 2 bytes, using default capability here
 	data = append(data, byte(defaultFlag), byte(defaultFlag>>8))
 
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"io"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// LocalInFileHandler is for handlers that want to serve LOAD DATA LOCAL INFILE statements,
+// where the server asks the client to send the content of one of its local files.
+// CLIENT_LOCAL_FILES is only advertised in the handshake when the handler implements it.
+type LocalInFileHandler interface {
+	// LocalInFileName is called for every COM_QUERY before HandleQuery. It returns the name of
+	// the file to request from the client and true if the query is a LOAD DATA LOCAL INFILE.
+	LocalInFileName(query string) (filename string, ok bool)
+	// HandleLocalInFile is called with the file content streamed by the client. Whatever is not
+	// read from r when it returns is discarded.
+	HandleLocalInFile(query string, filename string, r io.Reader) (*Result, error)
+}
+
+func (c *Conn) handleLocalInFile(h LocalInFileHandler, query string, filename string) (*Result, error) {
+	if c.capability&CLIENT_LOCAL_FILES == 0 {
+		return nil, NewDefaultError(ER_NOT_ALLOWED_COMMAND)
+	}
+
+	data := make([]byte, 4, 5+len(filename))
+	data = append(data, LocalInFile_HEADER)
+	data = append(data, filename...)
+	if err := c.WritePacket(data); err != nil {
+		return nil, err
+	}
+
+	r := &localInFileReader{c: c}
+	result, err := h.HandleLocalInFile(query, filename, r)
+
+	// the client must have sent the whole file before we can respond
+	if _, derr := io.Copy(io.Discard, r); derr != nil {
+		return nil, derr
+	}
+
+	return result, err
+}
+
+// localInFileReader reads the file content sent by the client as a sequence of packets,
+// terminated by an empty packet.
+type localInFileReader struct {
+	c    *Conn
+	buf  []byte
+	done bool
+}
+
+func (r *localInFileReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		data, err := r.c.ReadPacket()
+		if err != nil {
+			return 0, err
+		}
+		if len(data) == 0 {
+			r.done = true
+		}
+		r.buf = data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/packet"
+)
+
+type localInFileTestHandler struct {
+	EmptyHandler
+
+	content string
+}
+
+func (h *localInFileTestHandler) LocalInFileName(query string) (string, bool) {
+	return "/tmp/data.csv", strings.HasPrefix(query, "LOAD DATA LOCAL INFILE")
+}
+
+func (h *localInFileTestHandler) HandleLocalInFile(query string, filename string, r io.Reader) (*mysql.Result, error) {
+	// only read the first byte, the rest must be discarded by the server
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	h.content = string(b)
+	return &mysql.Result{AffectedRows: 3}, nil
+}
+
+func TestHandleLocalInFile(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer clientSide.Close()
+
+	h := &localInFileTestHandler{}
+	c := &Conn{
+		Conn:       packet.NewConn(serverSide),
+		h:          h,
+		capability: mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_LOCAL_FILES,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.HandleCommand()
+	}()
+
+	cli := packet.NewConn(clientSide)
+	query := "LOAD DATA LOCAL INFILE '/tmp/data.csv' INTO TABLE t"
+	require.NoError(t, cli.WritePacket(append([]byte{0, 0, 0, 0, mysql.COM_QUERY}, query...)))
+
+	data, err := cli.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{mysql.LocalInFile_HEADER}, "/tmp/data.csv"...), data)
+
+	require.NoError(t, cli.WritePacket(append([]byte{0, 0, 0, 0}, "1,a\n"...)))
+	require.NoError(t, cli.WritePacket(append([]byte{0, 0, 0, 0}, "2,b\n3,c\n"...)))
+	require.NoError(t, cli.WritePacket([]byte{0, 0, 0, 0}))
+
+	data, err = cli.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, mysql.OK_HEADER, data[0])
+	require.Equal(t, byte(3), data[1])
+
+	require.NoError(t, <-done)
+	require.Equal(t, "1", h.content)
+}
+
+func TestLocalInFileCapability(t *testing.T) {
+	for _, h := range []Handler{EmptyHandler{}, &localInFileTestHandler{}} {
+		serverSide, clientSide := net.Pipe()
+		c := &Conn{
+			Conn:       packet.NewConn(serverSide),
+			h:          h,
+			serverConf: NewServer("8.0.11", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_NATIVE_PASSWORD, nil, nil),
+			salt:       make([]byte, 20),
+		}
+		go func() {
+			_ = c.writeInitialHandshake()
+		}()
+
+		data, err := packet.NewConn(clientSide).ReadPacket()
+		require.NoError(t, err)
+		// the version, connection id, first part of the salt and filler come first
+		pos := 1 + len("8.0.11") + 1 + 4 + 8 + 1
+		capability := uint32(binary.LittleEndian.Uint16(data[pos:]))
+		_, ok := h.(LocalInFileHandler)
+		require.Equal(t, ok, capability&mysql.CLIENT_LOCAL_FILES > 0)
+
+		serverSide.Close()
+		clientSide.Close()
+	}
+}
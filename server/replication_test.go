@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siddontang/go-log/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestParseBinlogDump(t *testing.T) {
+	p := mysql.Position{Name: "mysql-bin.000003", Pos: 1234}
+	data := mysql.AppendBinlogDumpCommand(nil, 100, 0x01, p)
+	require.Equal(t, mysql.COM_BINLOG_DUMP, data[0])
+
+	parsed, err := parseBinlogDump(data[1:])
+	require.NoError(t, err)
+	require.Equal(t, p, parsed)
+}
+
+func TestParseBinlogDumpGTID(t *testing.T) {
+	gset, err := mysql.ParseMysqlGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-23")
+	require.NoError(t, err)
+
+	data := mysql.AppendBinlogDumpGTIDCommand(nil, 100, 0x01, mysql.Position{Name: "", Pos: 4}, gset)
+	require.Equal(t, mysql.COM_BINLOG_DUMP_GTID, data[0])
+
+	parsed, err := parseBinlogDumpGTID(data[1:])
+	require.NoError(t, err)
+	require.True(t, gset.Equal(parsed))
+}
+
+type fakeSourceHandler struct {
+	EmptyReplicationHandler
+	events []*replication.BinlogEvent
+	pos    chan mysql.Position
+}
+
+func (h *fakeSourceHandler) HandleQuery(query string) (*mysql.Result, error) {
+	if query == "SHOW GLOBAL VARIABLES LIKE 'BINLOG_CHECKSUM'" {
+		r, err := mysql.BuildSimpleResultset([]string{"Variable_name", "Value"}, [][]interface{}{{"binlog_checksum", "CRC32"}}, false)
+		return &mysql.Result{Resultset: r}, err
+	}
+	return nil, nil
+}
+
+func (h *fakeSourceHandler) HandleBinlogDump(pos mysql.Position) (*replication.BinlogStreamer, error) {
+	h.pos <- pos
+	s := replication.NewBinlogStreamer()
+	for _, ev := range h.events {
+		if err := s.AddEventToStreamer(ev); err != nil {
+			return nil, err
+		}
+	}
+	s.AddErrorToStreamer(errors.New("end of binlog"))
+	return s, nil
+}
+
+func TestFakeReplicationSource(t *testing.T) {
+	sid := uuid.MustParse("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	b := &replication.EventBuilder{ServerID: 1, ChecksumAlgorithm: replication.BINLOG_CHECKSUM_ALG_CRC32}
+	h := &fakeSourceHandler{
+		events: []*replication.BinlogEvent{
+			b.Rotate("mysql-bin.000001", 4),
+			b.FormatDescription("8.0.36"),
+			b.GTID(sid, 1),
+			b.Query("test", "BEGIN"),
+			b.XID(7),
+			b.Query("test", "CREATE TABLE t (id INT)"),
+		},
+		pos: make(chan mysql.Position, 1),
+	}
+
+	serverSide, clientSide := net.Pipe()
+	go func() {
+		c, err := NewConn(serverSide, "root", "", h)
+		if err != nil {
+			return
+		}
+		for c.HandleCommand() == nil {
+		}
+	}()
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: 100,
+		Flavor:   mysql.MySQLFlavor,
+		User:     "root",
+		Dialer: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return clientSide, nil
+		},
+		Logger: log.NewDefault(&log.NullHandler{}),
+	})
+	defer syncer.Close()
+
+	s, err := syncer.StartSync(mysql.Position{Name: "mysql-bin.000001", Pos: 4})
+	require.NoError(t, err)
+	require.Equal(t, mysql.Position{Name: "mysql-bin.000001", Pos: 4}, <-h.pos)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var events []replication.Event
+	for range h.events {
+		ev, err := s.GetEvent(ctx)
+		require.NoError(t, err)
+		events = append(events, ev.Event)
+	}
+
+	require.Equal(t, []byte("mysql-bin.000001"), events[0].(*replication.RotateEvent).NextLogName)
+	require.Equal(t, "8.0.36", events[1].(*replication.FormatDescriptionEvent).ServerVersion)
+	require.Equal(t, int64(1), events[2].(*replication.GTIDEvent).GNO)
+	require.Equal(t, []byte("BEGIN"), events[3].(*replication.QueryEvent).Query)
+	require.Equal(t, uint64(7), events[4].(*replication.XIDEvent).XID)
+	require.Equal(t, []byte("CREATE TABLE t (id INT)"), events[5].(*replication.QueryEvent).Query)
+	require.Equal(t, mysql.Position{Name: "mysql-bin.000001", Pos: b.LogPos}, syncer.GetNextPosition())
+
+	_, err = s.GetEvent(ctx)
+	require.ErrorContains(t, err, "end of binlog")
+}
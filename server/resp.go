@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	. "github.com/go-mysql-org/go-mysql/mysql"
@@ -30,13 +31,20 @@ func (c *Conn) writeOK(r *Result) error {
 	return c.WritePacket(data)
 }
 
+// writeError sends e as an ERR packet. A *MyError, even wrapped like by errors.Trace,
+// is sent with its code, SQLSTATE and message, other errors as ER_UNKNOWN_ERROR.
 func (c *Conn) writeError(e error) error {
 	var m *MyError
-	var ok bool
-	if m, ok = e.(*MyError); !ok {
+	if !errors.As(e, &m) {
 		m = NewError(ER_UNKNOWN_ERROR, e.Error())
 	}
 
+	// a MyError built by hand may have no SQLSTATE
+	state := m.State
+	if len(state) != 5 {
+		state = NewError(m.Code, "").State
+	}
+
 	data := make([]byte, 4, 16+len(m.Message))
 
 	data = append(data, ERR_HEADER)
@@ -44,7 +52,7 @@ func (c *Conn) writeError(e error) error {
 
 	if c.capability&CLIENT_PROTOCOL_41 > 0 {
 		data = append(data, '#')
-		data = append(data, m.State...)
+		data = append(data, state...)
 	}
 
 	data = append(data, m.Message...)
@@ -200,11 +208,16 @@ func (c *Conn) writeFieldValues(fv []FieldValue) error {
 }
 
 // see: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_replication.html
+// writeBinlogEvents streams the events of s until it is closed, the error it is closed with is
+// sent to the replica.
 func (c *Conn) writeBinlogEvents(s *replication.BinlogStreamer) error {
 	for {
 		ev, err := s.GetEvent(context.Background())
 		if err != nil {
-			return err
+			return c.writeError(err)
+		}
+		if len(ev.RawData) == 0 {
+			return c.writeError(fmt.Errorf("binlog event has no raw data"))
 		}
 		data := make([]byte, 4, 4+len(ev.RawData))
 		data = append(data, OK_HEADER)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/go-mysql-org/go-mysql/mysql"
@@ -76,6 +77,18 @@ func TestConnWriteError(t *testing.T) {
 	require.NoError(t, err)
 	expected = []byte{13, 0, 0, 2, mysql.ERR_HEADER, 81, 4, 35, 72, 89, 48, 48, 48, 116, 101, 115, 116}
 	require.Equal(t, expected, clientConn.WriteBuffered)
+
+	// wrapped error of a handler
+	err = conn.writeError(fmt.Errorf("insert: %w", mysql.NewError(mysql.ER_DUP_ENTRY, "dup")))
+	require.NoError(t, err)
+	expected = []byte{12, 0, 0, 3, mysql.ERR_HEADER, 0x26, 0x04, '#', '2', '3', '0', '0', '0', 'd', 'u', 'p'}
+	require.Equal(t, expected, clientConn.WriteBuffered)
+
+	// no SQLSTATE
+	err = conn.writeError(&mysql.MyError{Code: mysql.ER_DUP_ENTRY, Message: "dup"})
+	require.NoError(t, err)
+	expected = []byte{12, 0, 0, 4, mysql.ERR_HEADER, 0x26, 0x04, '#', '2', '3', '0', '0', '0', 'd', 'u', 'p'}
+	require.Equal(t, expected, clientConn.WriteBuffered)
 }
 
 func TestConnWriteAuthSwitchRequest(t *testing.T) {
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	. "github.com/go-mysql-org/go-mysql/mysql"
 )
@@ -34,8 +35,13 @@ type Server struct {
 	pubKey            []byte
 	tlsConfig         *tls.Config
 	cacheShaPassword  *sync.Map // 'user@host' -> SHA256(SHA256(PASSWORD))
+	connIDAllocator   func() uint32
+	maxPacketSize     int // the largest command accepted, 0 for no limit
 }
 
+// defaultMaxPacketSize is the default max_allowed_packet of MySQL 8.0.
+const defaultMaxPacketSize = 64 << 20
+
 // NewDefaultServer: New mysql server with default settings.
 //
 // NOTES:
@@ -57,6 +63,7 @@ func NewDefaultServer() *Server {
 		pubKey:            getPublicKeyFromCert(certPem),
 		tlsConfig:         tlsConf,
 		cacheShaPassword:  new(sync.Map),
+		maxPacketSize:     defaultMaxPacketSize,
 	}
 }
 
@@ -92,6 +99,7 @@ func NewServer(serverVersion string, collationId uint8, defaultAuthMethod string
 		pubKey:            pubKey,
 		tlsConfig:         tlsConfig,
 		cacheShaPassword:  new(sync.Map),
+		maxPacketSize:     defaultMaxPacketSize,
 	}
 }
 
@@ -99,6 +107,26 @@ func isAuthMethodSupported(authMethod string) bool {
 	return authMethod == AUTH_NATIVE_PASSWORD || authMethod == AUTH_CACHING_SHA2_PASSWORD || authMethod == AUTH_SHA256_PASSWORD
 }
 
+// SetConnectionIDAllocator sets the function called to assign the id of every new connection,
+// instead of the default process-wide counter. It must be safe for concurrent use.
+func (s *Server) SetConnectionIDAllocator(f func() uint32) {
+	s.connIDAllocator = f
+}
+
+// SetMaxPacketSize sets the size of the largest command a client may send, 64MB by default
+// like max_allowed_packet. A larger command is read and discarded, and answered with the
+// ER_NET_PACKET_TOO_LARGE error. 0 removes the limit.
+func (s *Server) SetMaxPacketSize(n int) {
+	s.maxPacketSize = n
+}
+
+func (s *Server) nextConnectionID() uint32 {
+	if s.connIDAllocator != nil {
+		return s.connIDAllocator()
+	}
+	return atomic.AddUint32(&baseConnID, 1)
+}
+
 func (s *Server) InvalidateCache(username string, host string) {
 	s.cacheShaPassword.Delete(fmt.Sprintf("%s@%s", username, host))
 }
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/charset"
+
+	. "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// SetNamesHandler is for handlers that want to know when the client changes the character set
+// of the session with SET NAMES, e.g. to encode the strings of the results they return.
+// HandleSetNames is called instead of HandleQuery, after the character set is validated and
+// before it is applied, returning an error rejects it. SET NAMES is passed to HandleQuery for
+// the other handlers, the character set of the session is tracked if it succeeds.
+type SetNamesHandler interface {
+	HandleSetNames(charset string, collation string) error
+}
+
+var setNamesRegexp = regexp.MustCompile(`(?is)^\s*SET\s+NAMES\s+('[^']*'|"[^"]*"|\w+)(?:\s+COLLATE\s+('[^']*'|"[^"]*"|\w+))?\s*;?\s*$`)
+
+// parseSetNames returns the character set and collation of a SET NAMES statement, the
+// collation is empty if not given.
+func parseSetNames(query string) (cs string, collation string, ok bool) {
+	m := setNamesRegexp.FindStringSubmatch(query)
+	if m == nil {
+		return "", "", false
+	}
+	unquote := func(s string) string {
+		return strings.ToLower(strings.Trim(s, `'"`))
+	}
+	return unquote(m[1]), unquote(m[2]), true
+}
+
+// handleSetNames validates and applies the character set of a SET NAMES statement, so
+// CharsetName and CollationName tell the handler how the client expects strings to be encoded.
+func (c *Conn) handleSetNames(h SetNamesHandler, cs string, collation string) error {
+	co, err := c.setNamesCollation(cs, collation)
+	if err != nil {
+		return err
+	}
+	if err := h.HandleSetNames(co.CharsetName, co.Name); err != nil {
+		return err
+	}
+	c.setCollation(co)
+	return nil
+}
+
+// setNamesCollation returns the collation set by SET NAMES cs COLLATE collation, the default
+// collation of cs if collation is empty.
+func (c *Conn) setNamesCollation(cs string, collation string) (*charset.Collation, error) {
+	var co *charset.Collation
+	var err error
+	if cs == "default" {
+		co, err = charset.GetCollationByID(int(c.defaultCollationID()))
+		if err != nil {
+			return nil, NewDefaultError(ER_UNKNOWN_CHARACTER_SET, cs)
+		}
+		cs = co.CharsetName
+	} else {
+		if cs == "utf8mb3" {
+			cs = "utf8"
+		}
+		if co = defaultCollation(cs); co == nil {
+			return nil, NewDefaultError(ER_UNKNOWN_CHARACTER_SET, cs)
+		}
+	}
+
+	if collation != "" {
+		if co, err = charset.GetCollationByName(collation); err != nil {
+			return nil, NewDefaultError(ER_UNKNOWN_COLLATION, collation)
+		}
+		if co.CharsetName != cs {
+			return nil, NewDefaultError(ER_COLLATION_CHARSET_MISMATCH, collation, cs)
+		}
+	}
+	return co, nil
+}
+
+func (c *Conn) setCollation(co *charset.Collation) {
+	c.collation = co
+	if co.ID <= 0xff {
+		c.charset = uint8(co.ID)
+	}
+}
+
+// mysqlDefaultCollations are the default collations of MySQL for the character sets whose
+// default collation differs in the charset package, which follows TiDB.
+var mysqlDefaultCollations = map[string]string{
+	"ascii":   "ascii_general_ci",
+	"gb18030": "gb18030_chinese_ci",
+	"gbk":     "gbk_chinese_ci",
+	"latin1":  "latin1_swedish_ci",
+	"utf8":    "utf8_general_ci",
+	"utf8mb4": "utf8mb4_0900_ai_ci",
+}
+
+// defaultCollation returns the default collation of the character set cs, nil if cs has
+// no collation.
+func defaultCollation(cs string) *charset.Collation {
+	if name, ok := mysqlDefaultCollations[cs]; ok {
+		if co, err := charset.GetCollationByName(name); err == nil {
+			return co
+		}
+	}
+
+	// the ids of the collations of MySQL are below 2048
+	var found *charset.Collation
+	for id := 1; id < 2048; id++ {
+		co, err := charset.GetCollationByID(id)
+		if err != nil || co.CharsetName != cs {
+			continue
+		}
+		if co.IsDefault {
+			return co
+		}
+		if found == nil {
+			found = co
+		}
+	}
+	return found
+}
+
+func (c *Conn) defaultCollationID() uint8 {
+	if c.serverConf != nil {
+		return c.serverConf.collationId
+	}
+	return DEFAULT_COLLATION_ID
+}
+
+// sessionCollation returns the collation set by SET NAMES, or the one of the handshake.
+func (c *Conn) sessionCollation() *charset.Collation {
+	if c.collation != nil {
+		return c.collation
+	}
+	co, err := charset.GetCollationByID(int(c.charset))
+	if err != nil {
+		return nil
+	}
+	return co
+}
+
+// CharsetName returns the character set of the session, sent by the client in the handshake
+// or set by SET NAMES. It is empty if the client sent an unknown collation id.
+func (c *Conn) CharsetName() string {
+	if co := c.sessionCollation(); co != nil {
+		return co.CharsetName
+	}
+	return ""
+}
+
+// CollationName returns the collation of the session, see CharsetName.
+func (c *Conn) CollationName() string {
+	if co := c.sessionCollation(); co != nil {
+		return co.Name
+	}
+	return ""
+}
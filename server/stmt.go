@@ -31,12 +31,16 @@ type Stmt struct {
 	Args []interface{}
 
 	Context interface{}
+
+	// types bound by the last execution that sent them, reused until new ones are bound
+	paramTypes []byte
 }
 
 func (s *Stmt) Rest(params int, columns int, context interface{}) {
 	s.Params = params
 	s.Columns = columns
 	s.Context = context
+	s.paramTypes = nil
 	s.ResetParams()
 }
 
@@ -142,11 +146,13 @@ func (c *Conn) handleStmtExecute(data []byte) (*Result, error) {
 				return nil, ErrMalformPacket
 			}
 
-			paramTypes = data[pos : pos+(paramNum<<1)]
+			s.paramTypes = append(s.paramTypes[:0], data[pos:pos+(paramNum<<1)]...)
 			pos += paramNum << 1
-
-			paramValues = data[pos:]
+		} else {
+			pos++
 		}
+		paramTypes = s.paramTypes
+		paramValues = data[pos:]
 
 		if err := c.bindStmtArgs(s, nullBitmaps, paramTypes, paramValues); err != nil {
 			return nil, errors.Trace(err)
@@ -180,6 +186,11 @@ func (c *Conn) bindStmtArgs(s *Stmt, nullBitmap, paramTypes, paramValues []byte)
 			continue
 		}
 
+		// no types were ever bound
+		if len(paramTypes) < (i+1)<<1 {
+			return ErrMalformPacket
+		}
+
 		tp := paramTypes[i<<1]
 		isUnsigned := (paramTypes[(i<<1)+1] & 0x80) > 0
 